@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/track"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// focusOptions are the lengths of a focus session. A long break follows every LongEvery-th
+// work interval instead of a short one.
+type focusOptions struct {
+	Work      time.Duration
+	Break     time.Duration
+	LongBreak time.Duration
+	Cycles    int
+	LongEvery int
+}
+
+var defaultFocus = focusOptions{
+	Work:      25 * time.Minute,
+	Break:     5 * time.Minute,
+	LongBreak: 15 * time.Minute,
+	Cycles:    4,
+	LongEvery: 4,
+}
+
+func (o focusOptions) check() error {
+	if o.Work <= 0 {
+		return fmt.Errorf("the work interval has to be longer than 0")
+	}
+	if o.Break < 0 || o.LongBreak < 0 {
+		return fmt.Errorf("breaks can't be negative")
+	}
+	if o.Cycles < 1 || o.LongEvery < 1 {
+		return fmt.Errorf("there has to be at least one work interval")
+	}
+	return nil
+}
+
+// interval is a work interval or a break of a focus session, Cycle counts the work
+// intervals from 1
+type interval struct {
+	Work   bool
+	Cycle  int
+	Length time.Duration
+}
+
+// intervals lists the work intervals of the session and the breaks between them, the
+// session ends with the last work interval
+func (o focusOptions) intervals() []interval {
+	var intervals []interval
+	for cycle := 1; cycle <= o.Cycles; cycle++ {
+		intervals = append(intervals, interval{Work: true, Cycle: cycle, Length: o.Work})
+		if cycle == o.Cycles {
+			break
+		}
+
+		length := o.Break
+		if cycle%o.LongEvery == 0 {
+			length = o.LongBreak
+		}
+		if length > 0 {
+			intervals = append(intervals, interval{Cycle: cycle, Length: length})
+		}
+	}
+	return intervals
+}
+
+// formatCountdown formats the time left of an interval as minutes and seconds
+func formatCountdown(left time.Duration) string {
+	seconds := int(left.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// countdown shows the time left of an interval on a single line until it is over. It
+// returns false if ctx ends first.
+func countdown(ctx context.Context, out io.Writer, label string, length time.Duration) bool {
+	end := time.Now().Add(length)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		left := time.Until(end)
+		if left <= 0 {
+			// The bell tells that the interval is over when the terminal isn't watched
+			fmt.Fprintf(out, "\r%s %s left\a\n", label, formatCountdown(0))
+			return true
+		}
+		fmt.Fprintf(out, "\r%s %s left ", label, formatCountdown(left))
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(out)
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// focus runs the intervals of a session for the named work and records every completed
+// work interval in entries. A session that is interrupted keeps the intervals completed so
+// far, it returns how many there are.
+func focus(ctx context.Context, out io.Writer, name string, opts focusOptions, entries *[]track.Entry) int {
+	completed := 0
+	for _, iv := range opts.intervals() {
+		label := fmt.Sprintf("Break after %d/%d:", iv.Cycle, opts.Cycles)
+		if iv.Work {
+			label = fmt.Sprintf("Focus on %s, %d/%d:", name, iv.Cycle, opts.Cycles)
+		}
+
+		start := time.Now()
+		if !countdown(ctx, out, label, iv.Length) {
+			fmt.Fprintln(out, "The focus session was stopped")
+			break
+		}
+		if iv.Work {
+			*entries = append(*entries, track.Entry{Start: start, Stop: time.Now(), Note: fmt.Sprintf("Focus %d/%d", iv.Cycle, opts.Cycles)})
+			completed++
+		}
+	}
+
+	fmt.Fprintf(out, "Recorded %d of %d work intervals, %s on %s\n", completed, opts.Cycles,
+		formatDuration(time.Duration(completed)*opts.Work), name)
+	return completed
+}
+
+// getMinutes asks for a length in minutes, an empty answer keeps the default
+func getMinutes(reader *bufio.Reader, question string, def time.Duration, min int) (time.Duration, error) {
+	for {
+		fmt.Printf("%s(Just press enter for %d): ", question, int(def.Minutes()))
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return 0, fmt.Errorf("error in reading from stdin")
+		}
+
+		response = strings.TrimSpace(response)
+		if response == "" {
+			return def, nil
+		}
+		minutes, err := strconv.Atoi(response)
+		if err != nil || minutes < min {
+			fmt.Printf("Invalid input! Please enter a number of at least %d.\n", min)
+			continue
+		}
+		return time.Duration(minutes) * time.Minute, nil
+	}
+}
+
+// getFocusOptions asks for the lengths of a focus session
+func getFocusOptions(reader *bufio.Reader) (focusOptions, error) {
+	opts := defaultFocus
+	var err error
+
+	opts.Work, err = getMinutes(reader, "Please enter the length of a work interval in minutes", opts.Work, 1)
+	if err != nil {
+		return opts, err
+	}
+	opts.Break, err = getMinutes(reader, "Please enter the length of a break in minutes", opts.Break, 0)
+	if err != nil {
+		return opts, err
+	}
+	opts.LongBreak, err = getMinutes(reader, "Please enter the length of every fourth break in minutes", opts.LongBreak, 0)
+	if err != nil {
+		return opts, err
+	}
+
+	for {
+		fmt.Printf("Please enter the number of work intervals(Just press enter for %d): ", opts.Cycles)
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return opts, fmt.Errorf("error in reading from stdin")
+		}
+
+		response = strings.TrimSpace(response)
+		if response == "" {
+			return opts, nil
+		}
+		opts.Cycles, err = strconv.Atoi(response)
+		if err != nil || opts.Cycles < 1 {
+			fmt.Println("Invalid input! Please enter a positive number.")
+			continue
+		}
+		return opts, nil
+	}
+}
+
+// focusSession runs a focus session on an event or one of its todos. The completed work
+// intervals are recorded as its time entries, afterwards the open todos can be marked done.
+func focusSession(days []*Day, reader *bufio.Reader, layout string) error {
+	day, err := getDayByIndex(days, reader, layout, "focus on")
+	if err != nil {
+		return err
+	}
+	event, err := getEventByIndex(day, reader)
+	if err != nil {
+		return err
+	}
+
+	todoIndex := -1
+	if len(event.TodoList) > 0 {
+		todoIndex, err = getTodoIndex(event, reader)
+		if err != nil {
+			return err
+		}
+	}
+	name, entries := event.Name, &event.Entries
+	if todoIndex >= 0 {
+		name, entries = event.TodoList[todoIndex].Name, &event.TodoList[todoIndex].Entries
+	}
+
+	opts, err := getFocusOptions(reader)
+	if err != nil {
+		return err
+	}
+
+	// The session tracks the time itself
+	if running := runningEvent(days); running != nil {
+		if err = stopEvent(running, "", time.Now()); err != nil {
+			return err
+		}
+	}
+
+	// Ctrl+C ends the session instead of the program
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	focus(ctx, os.Stdout, name, opts, entries)
+	stop()
+
+	// Only the todo of the session is offered, or all todos of the event
+	open := event.TodoList
+	if todoIndex >= 0 {
+		open = event.TodoList[todoIndex : todoIndex+1]
+	}
+	for i := range open {
+		if open[i].Done {
+			continue
+		}
+		done, err := askYesNo(reader, fmt.Sprintf("Mark %s as done?", open[i].Name))
+		if err != nil {
+			return err
+		}
+		open[i].Done = done
+	}
+
+	return nil
+}
+
+// selectWork picks the event or the todo of a session by their indexes as the menu lists
+// them, counting from 1. A todo index of 0 picks the event itself.
+func selectWork(days []*Day, dayIndex, eventIndex, todoIndex int) (*Event, *Todo, error) {
+	if dayIndex < 1 || dayIndex > len(days) {
+		return nil, nil, fmt.Errorf("there is no day %d", dayIndex)
+	}
+	day := days[dayIndex-1]
+	if eventIndex < 1 || eventIndex > len(day.Events) {
+		return nil, nil, fmt.Errorf("day %d has no event %d", dayIndex, eventIndex)
+	}
+	event := &day.Events[eventIndex-1]
+	if todoIndex == 0 {
+		return event, nil, nil
+	}
+	if todoIndex < 1 || todoIndex > len(event.TodoList) {
+		return nil, nil, fmt.Errorf("%s has no todo %d", event.Name, todoIndex)
+	}
+	return event, &event.TodoList[todoIndex-1], nil
+}
+
+// runFocus runs a focus session that is configured by flags, so that it can be scripted.
+// With -day and -event the completed work intervals are recorded as time entries of that
+// event or of one of its todos in the data file. It returns the exit code of the program.
+func runFocus(args []string) int {
+	opts := defaultFocus
+	flags := flag.NewFlagSet("focus", flag.ContinueOnError)
+	name := flags.String("name", "", "what the session is spent on, the name of the event or todo by default")
+	dataFile := flags.String("data", defaultDataFile, "file the days are kept in")
+	dayIndex := flags.Int("day", 0, "index of the day of the event, as the menu lists it")
+	eventIndex := flags.Int("event", 0, "index of the event on its day")
+	todoIndex := flags.Int("todo", 0, "index of a todo of the event, 0 for the event itself")
+	flags.DurationVar(&opts.Work, "work", opts.Work, "length of a work interval")
+	flags.DurationVar(&opts.Break, "break", opts.Break, "length of a break, 0 for none")
+	flags.DurationVar(&opts.LongBreak, "long-break", opts.LongBreak, "length of a long break")
+	flags.IntVar(&opts.LongEvery, "long-every", opts.LongEvery, "number of work intervals before a long break")
+	flags.IntVar(&opts.Cycles, "cycles", opts.Cycles, "number of work intervals")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if err := opts.check(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error in starting the focus session:", err)
+		return 2
+	}
+
+	var days []*Day
+	var layout string
+	var entries []track.Entry
+	record := &entries
+	switch {
+	case *dayIndex != 0 || *eventIndex != 0:
+		var err error
+		days, layout, err = loadDays(*dataFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in loading the days:", err)
+			return 1
+		}
+		event, todo, err := selectWork(days, *dayIndex, *eventIndex, *todoIndex)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in starting the focus session:", err)
+			return 2
+		}
+
+		label := event.Name
+		record = &event.Entries
+		if todo != nil {
+			label, record = todo.Name, &todo.Entries
+		}
+		if *name == "" {
+			*name = label
+		}
+
+		// The session tracks the time itself
+		if running := runningEvent(days); running != nil {
+			if err = stopEvent(running, "", time.Now()); err != nil {
+				fmt.Fprintln(os.Stderr, "Error in stopping the timer:", err)
+				return 1
+			}
+		}
+	case *todoIndex != 0:
+		fmt.Fprintln(os.Stderr, "Error in starting the focus session: -todo needs -day and -event")
+		return 2
+	}
+	if *name == "" {
+		*name = "Focus"
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	before := len(*record)
+	completed := focus(ctx, os.Stdout, *name, opts, record)
+	for _, entry := range (*record)[before:] {
+		fmt.Printf("%s - %s %s\n", entry.Start.Format("15:04"), entry.Stop.Format("15:04"), entry.Note)
+	}
+	if days != nil {
+		// Interrupted sessions keep the intervals completed so far as well
+		if err := saveDays(*dataFile, days, layout); err != nil {
+			fmt.Fprintln(os.Stderr, "Error in saving the days:", err)
+			return 1
+		}
+	}
+	if completed < opts.Cycles {
+		return 1
+	}
+	return 0
+}
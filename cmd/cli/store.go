@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/recur"
+	"io/fs"
+	"os"
+)
+
+// defaultDataFile is where the days are kept unless -data names another file
+const defaultDataFile = "taskweave.json"
+
+// savedDays is the content of the data file. The occurrences of a repeating event share
+// their recur.Set, it is saved once in Series and Repeats tells which events use it.
+type savedDays struct {
+	Layout  string
+	Days    []*Day
+	Series  []recur.Set
+	Repeats []savedRepeat
+}
+
+// savedRepeat links the event at the given indexes to a set of savedDays.Series
+type savedRepeat struct {
+	Day    int
+	Event  int
+	Series int
+}
+
+// loadDays reads the days and their layout from the data file. A file that doesn't
+// exist yet is no error, there are no days then.
+func loadDays(path string) ([]*Day, string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	var saved savedDays
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, "", fmt.Errorf("%s can't be read: %w", path, err)
+	}
+	for _, repeat := range saved.Repeats {
+		if repeat.Day < 0 || repeat.Day >= len(saved.Days) || repeat.Series < 0 || repeat.Series >= len(saved.Series) ||
+			repeat.Event < 0 || repeat.Event >= len(saved.Days[repeat.Day].Events) {
+			return nil, "", fmt.Errorf("%s refers to a repeating event that doesn't exist", path)
+		}
+		saved.Days[repeat.Day].Events[repeat.Event].Repeat = &saved.Series[repeat.Series]
+	}
+	return sortDays(saved.Days), saved.Layout, nil
+}
+
+// saveDays writes the days and their layout to the data file. The file is replaced at
+// once, so that an interrupted save doesn't lose the days.
+func saveDays(path string, days []*Day, layout string) error {
+	saved := savedDays{Layout: layout, Days: days}
+	series := map[*recur.Set]int{}
+	for d, day := range days {
+		for e, event := range day.Events {
+			if event.Repeat == nil {
+				continue
+			}
+			i, ok := series[event.Repeat]
+			if !ok {
+				i = len(saved.Series)
+				series[event.Repeat] = i
+				saved.Series = append(saved.Series, *event.Repeat)
+			}
+			saved.Repeats = append(saved.Repeats, savedRepeat{Day: d, Event: e, Series: i})
+		}
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
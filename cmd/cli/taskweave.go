@@ -1,8 +1,14 @@
-package cli
+package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"github.com/Shu-AFK/TaskWeave/conflict"
+	"github.com/Shu-AFK/TaskWeave/priority"
+	"github.com/Shu-AFK/TaskWeave/recur"
+	"github.com/Shu-AFK/TaskWeave/schedule"
+	"github.com/Shu-AFK/TaskWeave/track"
 	"os"
 	"sort"
 	"strconv"
@@ -15,6 +21,9 @@ type Todo struct {
 	Description string
 	Deadline    time.Time
 	Done        bool
+	Priority    priority.Priority
+	// Entries is the time tracked for the todo in focus sessions
+	Entries []track.Entry
 }
 
 type Event struct {
@@ -23,6 +32,23 @@ type Event struct {
 	Deadline time.Time
 	Start    time.Time
 	End      time.Time
+	Priority priority.Priority
+	// Scheduled events got their start and end from scheduleEvents, which may move them
+	Scheduled bool
+	// AllowOverlap lets the event be booked at the same time as other events
+	AllowOverlap bool
+	// Repeat is the recurrence of a repeating event, all of its occurrences share it. Its
+	// dates are real calendar dates, see calendarDate. saveDays keeps it apart from the
+	// event, so that the occurrences still share it when they are loaded again.
+	Repeat *recur.Set `json:"-"`
+	// Entries is the time tracked for the event, the last one runs while its timer does
+	Entries []track.Entry
+	// Rollovers counts how often the event moved on because its day ended before it was
+	// finished. Missed is set for events with a fixed time that ended unfinished.
+	Rollovers int
+	Missed    bool
+	// Done is set when the event was marked as done, see finished
+	Done     bool
 	TodoList []Todo
 }
 
@@ -35,11 +61,27 @@ type Day struct {
 
 var parseTemplates = []string{"Jan 2 15:04", "Jan 2 3pm"}
 
+// postponedRollovers is how often an event may roll over before it is pointed out
+const postponedRollovers = 3
+
+// changingOptions are the menu options that change the plan, the deadlines are forecast
+// again after them
+var changingOptions = map[string]bool{"1": true, "2": true, "3": true, "5": true, "6": true, "10": true, "11": true, "13": true}
+
 func printMenu() {
 	fmt.Println("1. Create a new day")
 	fmt.Println("2. Add a new event to a day")
 	fmt.Println("3. Add a new todo to an event")
 	fmt.Println("4. Print all days")
+	fmt.Println("5. Schedule events without a fixed time")
+	fmt.Println("6. Rebalance next week")
+	fmt.Println("7. Start a timer for an event")
+	fmt.Println("8. Stop the timer")
+	fmt.Println("9. Compare planned and tracked time")
+	fmt.Println("10. Start a focus session")
+	fmt.Println("11. Roll over unfinished work")
+	fmt.Println("12. Show deadlines at risk")
+	fmt.Println("13. Mark an event as done or not done")
 	fmt.Println("0. Exit")
 }
 
@@ -142,7 +184,9 @@ func getEndOf(startOf time.Time, layout string, reader *bufio.Reader, itemType s
 	}
 }
 
-func createDay(days []*Day, _layout string) ([]*Day, string, error) {
+// createDay asks for a new day and adds the occurrences of the repeating events that fall
+// on it
+func createDay(days []*Day, _layout string, now time.Time) ([]*Day, string, error) {
 	reader := bufio.NewReader(os.Stdin)
 	newDay := new(Day)
 
@@ -160,10 +204,70 @@ func createDay(days []*Day, _layout string) ([]*Day, string, error) {
 	newDay.EndOfDay = endTime
 	newDay.Duration = endTime.Sub(startTime)
 
+	// Every repeating event is taken once, its occurrences are all the same
+	seen := map[*recur.Set]bool{}
+	for _, day := range days {
+		for _, event := range day.Events {
+			if event.Repeat == nil || seen[event.Repeat] {
+				continue
+			}
+			seen[event.Repeat] = true
+			if _, ok := event.Repeat.On(calendarDate(newDay.StartOfDay, now)); ok {
+				newDay.Events = append(newDay.Events, occurrenceOn(event, newDay))
+			}
+		}
+	}
+
 	days = append(days, newDay)
 	return days, layout, nil
 }
 
+// occurrenceOn copies a repeating event onto another day. Fixed times and the deadline
+// keep their time of day, times from scheduleEvents are dropped.
+func occurrenceOn(event Event, day *Day) Event {
+	onDay := func(t time.Time) time.Time {
+		date := day.StartOfDay
+		return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, date.Location())
+	}
+
+	event.TodoList = nil
+	event.Entries = nil
+	event.Rollovers, event.Missed, event.Done = 0, false, false
+	if event.Scheduled {
+		event.Start, event.End, event.Scheduled = time.Time{}, time.Time{}, false
+	}
+	if !event.Start.IsZero() {
+		event.Start = onDay(event.Start)
+		event.End = event.Start.Add(event.Duration)
+	}
+	if !event.Deadline.IsZero() {
+		event.Deadline = onDay(event.Deadline)
+	}
+	return event
+}
+
+// getRepeat asks for an optional recurrence rule
+func getRepeat(reader *bufio.Reader) (*recur.Rule, error) {
+	for {
+		fmt.Print("Please enter a recurrence rule like FREQ=WEEKLY;BYDAY=MO(If the event doesn't repeat just press enter): ")
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("error in reading from stdin")
+		}
+
+		response = strings.TrimSpace(response)
+		if response == "" {
+			return nil, nil
+		}
+		rule, err := recur.Parse(response, time.Local)
+		if err != nil {
+			fmt.Println("Invalid rule:", err)
+			continue
+		}
+		return &rule, nil
+	}
+}
+
 func formatDuration(duration time.Duration) string {
 	hours := duration / time.Hour
 	minutes := (duration % time.Hour) / time.Minute
@@ -175,12 +279,115 @@ func formatDuration(duration time.Duration) string {
 	}
 }
 
-func printDays(days []*Day, layout string) error {
+// formatOptional formats t or tells that it isn't set
+func formatOptional(t time.Time, layout string) string {
+	if t.IsZero() {
+		return "not scheduled"
+	}
+	return t.Format(layout)
+}
+
+// printOptions choose which events and todos printDays shows and in which order
+type printOptions struct {
+	// quadrant only shows work of this quadrant, 0 shows everything
+	quadrant   priority.Quadrant
+	byPriority bool
+}
+
+func (o printOptions) matches(p priority.Priority) bool {
+	return o.quadrant == 0 || p.Quadrant() == o.quadrant
+}
+
+// arrange filters and sorts events and their todos. Events that aren't in the quadrant
+// are kept if one of their todos is.
+func (o printOptions) arrange(events []Event) []Event {
+	var arranged []Event
+	for _, event := range events {
+		var todos []Todo
+		for _, todo := range event.TodoList {
+			if o.matches(todo.Priority) {
+				todos = append(todos, todo)
+			}
+		}
+		if !o.matches(event.Priority) && len(todos) == 0 {
+			continue
+		}
+
+		if o.byPriority {
+			sort.SliceStable(todos, func(i, j int) bool {
+				return todos[i].Priority.Quadrant() > todos[j].Priority.Quadrant()
+			})
+		}
+		event.TodoList = todos
+		arranged = append(arranged, event)
+	}
+
+	if o.byPriority {
+		sort.SliceStable(arranged, func(i, j int) bool {
+			return arranged[i].Priority.Quadrant() > arranged[j].Priority.Quadrant()
+		})
+	}
+	return arranged
+}
+
+// askYesNo asks a question until it is answered with y or n
+func askYesNo(reader *bufio.Reader, question string) (bool, error) {
+	for {
+		fmt.Printf("%s (y/n) ", question)
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			return false, fmt.Errorf("error in reading from stdin")
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Println("Please answer with y or n.")
+	}
+}
+
+// readPrintOptions asks which priority to show and whether to sort by it
+func readPrintOptions(reader *bufio.Reader) (printOptions, error) {
+	var opts printOptions
+	for {
+		fmt.Print("Only show a priority (do/schedule/delegate/eliminate, press enter for all): ")
+		code, err := reader.ReadString('\n')
+		if err != nil {
+			return opts, fmt.Errorf("error in reading from stdin")
+		}
+
+		code = strings.ToLower(strings.TrimSpace(code))
+		if code == "" {
+			break
+		}
+		q, ok := priority.Parse(code)
+		if !ok {
+			fmt.Println("Unknown priority! Please try again.")
+			continue
+		}
+		opts.quadrant = q
+		break
+	}
+
+	var err error
+	opts.byPriority, err = askYesNo(reader, "Sort by priority?")
+	return opts, err
+}
+
+func printDays(days []*Day, layout string, opts printOptions) error {
 	if len(days) == 0 || layout == "" {
 		return fmt.Errorf("there were no days created so far")
 	}
 
 	for index, day := range days {
+		events := opts.arrange(day.Events)
+		if opts.quadrant != 0 && len(events) == 0 {
+			continue
+		}
+
 		fmt.Printf("%d. { "+
 			"Start of day: %s\n"+
 			"End of day: %s\n"+
@@ -188,15 +395,29 @@ func printDays(days []*Day, layout string) error {
 			"Events: [",
 			index+1, day.StartOfDay.Format(layout), day.EndOfDay.Format(layout), formatDuration(day.Duration))
 
-		if len(day.Events) == 0 {
+		if len(events) == 0 {
 			fmt.Println("]\n}")
 			continue
 		}
 		fmt.Println()
 
-		for eventIndex, event := range day.Events {
-			fmt.Printf("\t\t%d. { Title: %s, Duration: %s, Start: %s, End %s, Todo's: [",
-				eventIndex+1, event.Name, formatDuration(event.Duration), event.Start.Format(layout), event.End.Format(layout))
+		for eventIndex, event := range events {
+			name := event.Name
+			if event.Repeat != nil {
+				name += " (repeats " + event.Repeat.Rule.String() + ")"
+			}
+			if event.Rollovers > 0 {
+				name += fmt.Sprintf(" (rolled over %d×)", event.Rollovers)
+			}
+			if event.Missed {
+				name += " (missed)"
+			}
+			if event.Done {
+				name += " (done)"
+			}
+			fmt.Printf("\t\t%d. { Title: %s, Priority: %s, Duration: %s, Start: %s, End %s, Todo's: [",
+				eventIndex+1, name, event.Priority.Quadrant(), formatDuration(event.Duration),
+				formatOptional(event.Start, layout), formatOptional(event.End, layout))
 			if len(event.TodoList) == 0 {
 				fmt.Println("]}")
 				continue
@@ -211,8 +432,13 @@ func printDays(days []*Day, layout string) error {
 					done = "Not Done"
 				}
 
-				fmt.Printf("\t\t\t%d. {Name: %s, Description: %s, Deadline: %s, %s}\n",
-					todoIndex+1, todo.Name, todo.Description, todo.Deadline.Format(layout), done)
+				deadline := "none"
+				if !todo.Deadline.IsZero() {
+					deadline = todo.Deadline.Format(layout)
+				}
+
+				fmt.Printf("\t\t\t%d. {Name: %s, Description: %s, Priority: %s, Deadline: %s, %s}\n",
+					todoIndex+1, todo.Name, todo.Description, todo.Priority.Quadrant(), deadline, done)
 			}
 			fmt.Println("\t\t]")
 		}
@@ -231,15 +457,15 @@ func sortDays(days []*Day) []*Day {
 	return days
 }
 
-func getDayByIndex(days []*Day, reader *bufio.Reader, layout string) (*Day, error) {
+func getDayByIndex(days []*Day, reader *bufio.Reader, layout string, purpose string) (*Day, error) {
 	if len(days) == 0 || layout == "" {
 		return nil, fmt.Errorf("there were no days created so far")
 	}
 
-	_ = printDays(days, layout)
+	_ = printDays(days, layout, printOptions{})
 	var numIn int
 	for {
-		fmt.Print("\nPlease enter the index of the day you want to add an event to: ")
+		fmt.Printf("\nPlease enter the index of the day you want to %s: ", purpose)
 		for {
 			response, err := reader.ReadString('\n')
 			if err != nil {
@@ -264,15 +490,14 @@ func getDayByIndex(days []*Day, reader *bufio.Reader, layout string) (*Day, erro
 	}
 }
 
-// TODO: Add a feature to let the user specify the start and end of the event if he wants
 // TODO: Add a feature to let the user specify the todos if he wants
-func addNewEvent(days []*Day, reader *bufio.Reader, layout string) ([]*Day, error) {
+func addNewEvent(days []*Day, reader *bufio.Reader, layout string, now time.Time) ([]*Day, error) {
 	if len(days) == 0 || layout == "" {
 		return days, fmt.Errorf("there were no days created so far")
 	}
 	newEvent := new(Event)
 
-	day, err := getDayByIndex(days, reader, layout)
+	day, err := getDayByIndex(days, reader, layout, "add an event to")
 	if err != nil {
 		return nil, err
 	}
@@ -286,58 +511,861 @@ func addNewEvent(days []*Day, reader *bufio.Reader, layout string) ([]*Day, erro
 	title = strings.TrimSpace(title)
 	newEvent.Name = title
 
+	newEvent.Deadline, err = getDeadline(reader, layout, day, "event")
+	if err != nil {
+		return days, err
+	}
+
+	newEvent.Priority.Urgent, err = askYesNo(reader, "Is the event urgent?")
+	if err != nil {
+		return days, err
+	}
+	newEvent.Priority.Important, err = askYesNo(reader, "Is the event important?")
+	if err != nil {
+		return days, err
+	}
+
+	fixed, err := askYesNo(reader, "Do you have a specific start and end time?")
+	if err != nil {
+		return days, err
+	}
+
+	for fixed {
+		// Events may start on any planned day, only new days have to be unique
+		newEvent.Start, layout, err = getStartOf(layout, reader, nil, "event")
+		if err != nil {
+			return days, err
+		}
+
+		newEvent.End, err = getEndOf(newEvent.Start, layout, reader, "event")
+		if err != nil {
+			return days, err
+		}
+
+		newEvent.Duration = newEvent.End.Sub(newEvent.Start)
+
+		conflicts := findConflicts(day, *newEvent)
+		if len(conflicts) == 0 {
+			break
+		}
+		printConflicts(conflicts, layout)
+		if len(conflict.Without(conflicts, conflict.Overlap)) > 0 {
+			fmt.Println("The event has to lie inside of the working window of its day! Please try again.")
+			continue
+		}
+
+		newEvent.AllowOverlap, err = askYesNo(reader, "Double-book the event anyway?")
+		if err != nil {
+			return days, err
+		}
+		if newEvent.AllowOverlap {
+			break
+		}
+	}
+	if !fixed {
+		// Without a start and end the event is placed by scheduleEvents
+		newEvent.Duration, err = getDuration(reader)
+		if err != nil {
+			return days, err
+		}
+	}
+
+	rule, err := getRepeat(reader)
+	if err != nil {
+		return days, err
+	}
+	if rule != nil {
+		// The rule is expanded on real dates, the times of day come from the event
+		start := calendarDate(day.StartOfDay, now)
+		if fixed {
+			start = time.Date(start.Year(), start.Month(), start.Day(), newEvent.Start.Hour(), newEvent.Start.Minute(), 0, 0, start.Location())
+		}
+		newEvent.Repeat = &recur.Set{Start: start, Rule: *rule}
+	}
+
+	day.Events = append(day.Events, *newEvent)
+
+	// The other days that exist get their occurrence right away, later ones when they are created
+	added := 0
+	for _, other := range days {
+		if newEvent.Repeat == nil || other == day {
+			continue
+		}
+		if _, ok := newEvent.Repeat.On(calendarDate(other.StartOfDay, now)); ok {
+			other.Events = append(other.Events, occurrenceOn(*newEvent, other))
+			added++
+		}
+	}
+	if added > 0 {
+		fmt.Printf("Added the event to %d more days\n", added)
+	}
+
+	return days, nil
+}
+
+// getDeadline asks for an optional deadline, a time of day on the given day
+func getDeadline(reader *bufio.Reader, layout string, day *Day, itemType string) (time.Time, error) {
 	for {
-		fmt.Print("Please enter a deadline for the event(If there is no deadline just press enter): ")
+		fmt.Printf("Please enter a deadline for the %s(If there is no deadline just press enter): ", itemType)
 		deadlineStr, err := reader.ReadString('\n')
 		if err != nil {
-			return days, fmt.Errorf("error in reading from stdin")
+			return time.Time{}, fmt.Errorf("error in reading from stdin")
 		}
 		deadlineStr = strings.TrimSpace(deadlineStr)
-		if deadlineStr != "" {
-			var newLayout string
-			if len(layout) > 6 {
-				newLayout = layout[6:]
+		if deadlineStr == "" {
+			return time.Time{}, nil
+		}
+
+		var newLayout string
+		if len(layout) > 6 {
+			newLayout = layout[6:]
+		}
+		deadline, err := time.Parse(newLayout, deadlineStr)
+		if err != nil {
+			fmt.Println("Wrong format of input")
+			continue
+		}
+		// The deadline is a time on the day of the event
+		date := day.StartOfDay
+		return time.Date(date.Year(), date.Month(), date.Day(), deadline.Hour(), deadline.Minute(), 0, 0, date.Location()), nil
+	}
+}
+
+// addNewTodo asks for a todo and adds it to an event of a day. Occurrences of a repeating
+// event have todos of their own.
+func addNewTodo(days []*Day, reader *bufio.Reader, layout string) error {
+	day, err := getDayByIndex(days, reader, layout, "add a todo to")
+	if err != nil {
+		return err
+	}
+	event, err := getEventByIndex(day, reader)
+	if err != nil {
+		return err
+	}
+
+	var todo Todo
+	for todo.Name == "" {
+		fmt.Print("Please enter the name of the todo: ")
+		name, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("error in reading from stdin")
+		}
+		todo.Name = strings.TrimSpace(name)
+		if todo.Name == "" {
+			fmt.Println("The name can't be empty!")
+		}
+	}
+
+	fmt.Print("Please enter a description of the todo(If there is none just press enter): ")
+	description, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("error in reading from stdin")
+	}
+	todo.Description = strings.TrimSpace(description)
+
+	todo.Deadline, err = getDeadline(reader, layout, day, "todo")
+	if err != nil {
+		return err
+	}
+	todo.Priority.Urgent, err = askYesNo(reader, "Is the todo urgent?")
+	if err != nil {
+		return err
+	}
+	todo.Priority.Important, err = askYesNo(reader, "Is the todo important?")
+	if err != nil {
+		return err
+	}
+
+	event.TodoList = append(event.TodoList, todo)
+	return nil
+}
+
+// findConflicts checks a new event against the working window and the events of its day
+// that have a time
+func findConflicts(day *Day, event Event) []conflict.Conflict {
+	var others []conflict.Event
+	for i, other := range day.Events {
+		if !other.Start.IsZero() {
+			others = append(others, conflict.Event{ID: int64(i), Name: other.Name, Start: other.Start, End: other.End})
+		}
+	}
+
+	// The new event isn't part of the day yet, so it gets an id no other event has
+	checked := conflict.Event{ID: -1, Name: event.Name, Start: event.Start, End: event.End}
+	return conflict.Check(conflict.Window{Start: day.StartOfDay, End: day.EndOfDay}, others, checked)
+}
+
+func printConflicts(conflicts []conflict.Conflict, layout string) {
+	for _, c := range conflicts {
+		if c.With != nil {
+			fmt.Printf("Overlaps with %s from %s to %s\n", c.With.Name, c.Start.Format(layout), c.End.Format(layout))
+		} else {
+			fmt.Printf("Outside of the working window from %s to %s\n", c.Start.Format(layout), c.End.Format(layout))
+		}
+	}
+}
+
+// getDuration asks for the length of an event in minutes
+func getDuration(reader *bufio.Reader) (time.Duration, error) {
+	for {
+		fmt.Print("Please enter the duration of the event in minutes: ")
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return 0, fmt.Errorf("error in reading from stdin")
+		}
+
+		minutes, err := strconv.Atoi(strings.TrimSpace(response))
+		if err != nil || minutes <= 0 {
+			fmt.Println("Invalid input! Please enter a positive number of minutes.")
+			continue
+		}
+		return time.Duration(minutes) * time.Minute, nil
+	}
+}
+
+// scheduleEvents places the events without a fixed time into the free time of their day
+// and reports the ones that don't fit
+func scheduleEvents(days []*Day, layout string) error {
+	if len(days) == 0 || layout == "" {
+		return fmt.Errorf("there were no days created so far")
+	}
+
+	var scheduleDays []schedule.Day
+	var items []schedule.Item
+	// events holds the event of each item, the id of an item is its index
+	var events []*Event
+	for dayIndex, day := range days {
+		window := schedule.Day{Start: day.StartOfDay, End: day.EndOfDay}
+		for i := range day.Events {
+			event := &day.Events[i]
+			if !event.Start.IsZero() && !event.Scheduled {
+				window.Busy = append(window.Busy, schedule.Span{Start: event.Start, End: event.End})
+				continue
 			}
 
-			newEvent.Deadline, err = time.Parse(newLayout, deadlineStr)
-			if err != nil {
-				fmt.Println("Wrong format of input")
+			items = append(items, schedule.Item{
+				ID:       int64(len(events)),
+				Name:     event.Name,
+				Duration: event.Duration,
+				Deadline: event.Deadline,
+				Day:      dayIndex,
+				Priority: int(event.Priority.Quadrant()),
+			})
+			events = append(events, event)
+		}
+		scheduleDays = append(scheduleDays, window)
+	}
+
+	if len(items) == 0 {
+		return fmt.Errorf("there are no events without a fixed time")
+	}
+
+	plan := schedule.Schedule(scheduleDays, items)
+	for _, placement := range plan.Placed {
+		event := events[placement.Item.ID]
+		event.Start, event.End, event.Scheduled = placement.Start, placement.End, true
+		fmt.Printf("Placed %s: %s - %s\n", event.Name, placement.Start.Format(layout), placement.End.Format(layout))
+	}
+	for _, unplaced := range plan.Unplaced {
+		event := events[unplaced.Item.ID]
+		event.Start, event.End, event.Scheduled = time.Time{}, time.Time{}, false
+		fmt.Printf("Could not place %s: %s\n", event.Name, unplaced.Reason)
+	}
+
+	return nil
+}
+
+// calendarDate places a day, which is entered without a year, into the year around now
+func calendarDate(t time.Time, now time.Time) time.Time {
+	date := time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+	// Early January is next year when looking at it from December
+	if date.Before(now.AddDate(0, -6, 0)) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return date
+}
+
+// getMaxLoad asks for the share of a working window that may be booked
+func getMaxLoad(reader *bufio.Reader) (float64, error) {
+	for {
+		fmt.Printf("Please enter the maximum load per day in percent(Just press enter for %.0f): ", schedule.DefaultMaxLoad*100)
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return 0, fmt.Errorf("error in reading from stdin")
+		}
+
+		response = strings.TrimSpace(response)
+		if response == "" {
+			return schedule.DefaultMaxLoad, nil
+		}
+		percent, err := strconv.Atoi(response)
+		if err != nil || percent < 1 || percent > 100 {
+			fmt.Println("Invalid input! Please enter a number between 1 and 100.")
+			continue
+		}
+		return float64(percent) / 100, nil
+	}
+}
+
+// rebalanceEvents spreads the events without a fixed time over the days of next week so
+// that no day is booked beyond the maximum load. The moves are shown before they are made.
+func rebalanceEvents(days []*Day, reader *bufio.Reader, now time.Time) error {
+	if len(days) == 0 {
+		return fmt.Errorf("there were no days created so far")
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday := today.AddDate(0, 0, 7-(int(today.Weekday())+6)%7)
+	sunday := monday.AddDate(0, 0, 6)
+
+	var week []*Day
+	var balanceDays []schedule.Day
+	var items []schedule.Item
+	// events holds the event of each item, the id of an item is its index
+	var events []*Event
+	for _, day := range days {
+		date := calendarDate(day.StartOfDay, now)
+		if date.Before(monday) || date.After(sunday) {
+			continue
+		}
+
+		window := schedule.Day{Start: day.StartOfDay, End: day.EndOfDay}
+		for i := range day.Events {
+			event := &day.Events[i]
+			if !event.Start.IsZero() && !event.Scheduled {
+				window.Busy = append(window.Busy, schedule.Span{Start: event.Start, End: event.End})
 				continue
 			}
+
+			items = append(items, schedule.Item{
+				ID:       int64(len(events)),
+				Name:     event.Name,
+				Duration: event.Duration,
+				Deadline: event.Deadline,
+				Day:      len(week),
+				Priority: int(event.Priority.Quadrant()),
+			})
+			events = append(events, event)
 		}
-		break
+		week = append(week, day)
+		balanceDays = append(balanceDays, window)
+	}
+
+	if len(week) == 0 {
+		return fmt.Errorf("there are no days from %s to %s", monday.Format("Jan 2"), sunday.Format("Jan 2"))
 	}
 
-	// TODO: finish
-	fmt.Print("Do you have a specific start and end time? ")
-	deadlineStr, err := reader.ReadString('\n')
+	maxLoad, err := getMaxLoad(reader)
 	if err != nil {
-		return days, fmt.Errorf("error in reading from stdin")
+		return err
 	}
-	deadlineStr = strings.TrimSpace(deadlineStr)
 
-	newEvent.Start, layout, err = getStartOf(layout, reader, days, "event")
+	balanced := schedule.Balance(balanceDays, items, maxLoad)
+	for _, move := range balanced.Moves {
+		fmt.Printf("Move %s (%s) from %s to %s\n", move.Item.Name, formatDuration(move.Item.Duration),
+			week[move.From].StartOfDay.Format("Jan 2"), week[move.To].StartOfDay.Format("Jan 2"))
+	}
+	for _, unplaced := range balanced.Unplaced {
+		fmt.Printf("Could not place %s: %s\n", unplaced.Item.Name, unplaced.Reason)
+	}
+	for i, load := range balanced.Loads {
+		fmt.Printf("%s: %.0f%% booked\n", week[i].StartOfDay.Format("Jan 2"), load.Share()*100)
+	}
+
+	if len(balanced.Moves) == 0 {
+		fmt.Println("No event has to move.")
+		return nil
+	}
+	apply, err := askYesNo(reader, "Apply these moves?")
+	if err != nil || !apply {
+		return err
+	}
+
+	moved := map[*Event]*Day{}
+	for _, move := range balanced.Moves {
+		moved[events[move.Item.ID]] = week[move.To]
+	}
+	moveEvents(week, moved)
+
+	fmt.Println("Moved the events, schedule them to give them a time on their new day.")
+	return nil
+}
+
+// moveEvents takes events to their new days, they go to the end of the day. Times from
+// scheduleEvents belong to the old day and are dropped.
+func moveEvents(days []*Day, moved map[*Event]*Day) {
+	// The events are copied before the days are changed, as the pointers point into them
+	arrivals := map[*Day][]Event{}
+	for _, day := range days {
+		var stay []Event
+		for i := range day.Events {
+			to, ok := moved[&day.Events[i]]
+			if !ok {
+				stay = append(stay, day.Events[i])
+				continue
+			}
+
+			event := day.Events[i]
+			event.Start, event.End, event.Scheduled = time.Time{}, time.Time{}, false
+			arrivals[to] = append(arrivals[to], event)
+		}
+		day.Events = stay
+	}
+	for _, day := range days {
+		day.Events = append(day.Events, arrivals[day]...)
+	}
+}
+
+// onCalendar places a time of a day, which is entered without a year, into the year
+// around now
+func onCalendar(t time.Time, now time.Time) time.Time {
+	date := calendarDate(t, now)
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, date.Location())
+}
+
+// finished reports whether the work of an event is done: it was marked as done, all of its
+// todos are, or it has no todos and has ended
+func (e Event) finished(now time.Time) bool {
+	if e.Done {
+		return true
+	}
+	if len(e.TodoList) > 0 {
+		for _, todo := range e.TodoList {
+			if !todo.Done {
+				return false
+			}
+		}
+		return true
+	}
+	return !e.End.IsZero() && !onCalendar(e.End, now).After(now)
+}
+
+// rollOver moves the unfinished events without a fixed time of days that are over to the
+// first later day with room. Unfinished events with a fixed time stay and are marked as
+// missed. Events that rolled over again and again are pointed out.
+func rollOver(days []*Day, reader *bufio.Reader, now time.Time) error {
+	if len(days) == 0 {
+		return fmt.Errorf("there were no days created so far")
+	}
+
+	var rollDays []schedule.Day
+	var items []schedule.Item
+	// events holds the event of each item, the id of an item is its index
+	var events []*Event
+	open := 0
+	for dayIndex, day := range days {
+		over := !onCalendar(day.EndOfDay, now).After(now)
+		if over {
+			open = dayIndex + 1
+		}
+
+		window := schedule.Day{Start: day.StartOfDay, End: day.EndOfDay}
+		for i := range day.Events {
+			event := &day.Events[i]
+			fixed := !event.Start.IsZero() && !event.Scheduled
+			switch {
+			case fixed && !over:
+				window.Busy = append(window.Busy, schedule.Span{Start: event.Start, End: event.End})
+			case fixed:
+				if !event.Missed && !event.finished(now) {
+					event.Missed = true
+					fmt.Printf("Missed %s on %s\n", event.Name, day.StartOfDay.Format("Jan 2"))
+				}
+			case !over || !event.finished(now):
+				items = append(items, schedule.Item{
+					ID:       int64(len(events)),
+					Name:     event.Name,
+					Duration: event.Duration,
+					Deadline: event.Deadline,
+					Day:      dayIndex,
+					Priority: int(event.Priority.Quadrant()),
+				})
+				events = append(events, event)
+			}
+		}
+		rollDays = append(rollDays, window)
+	}
+
+	if open == 0 {
+		return fmt.Errorf("no day is over yet")
+	}
+
+	maxLoad, err := getMaxLoad(reader)
 	if err != nil {
-		return days, err
+		return err
+	}
+
+	rolled := schedule.Rollover(rollDays, items, maxLoad, open)
+	moved := map[*Event]*Day{}
+	for _, move := range rolled.Moves {
+		event := events[move.Item.ID]
+		event.Rollovers++
+		moved[event] = days[move.To]
+		fmt.Printf("Rolled %s over from %s to %s\n", event.Name,
+			days[move.From].StartOfDay.Format("Jan 2"), days[move.To].StartOfDay.Format("Jan 2"))
+	}
+	for _, unplaced := range rolled.Unplaced {
+		fmt.Printf("Could not roll %s over: %s\n", unplaced.Item.Name, unplaced.Reason)
+	}
+	moveEvents(days, moved)
+
+	for _, day := range days {
+		for _, event := range day.Events {
+			if event.Rollovers >= postponedRollovers {
+				fmt.Printf("Warning: %s rolled over %d×\n", event.Name, event.Rollovers)
+			}
+		}
+	}
+	if len(rolled.Moves) > 0 {
+		fmt.Println("Schedule the moved events to give them a time on their new day.")
+	}
+	return nil
+}
+
+// risk is an event that is forecast to miss its deadline. finish is zero if the days
+// have no room for all of its work.
+type risk struct {
+	event  *Event
+	finish time.Time
+	late   time.Duration
+	short  time.Duration
+}
+
+// forecastRisks finds the events that won't be done before their deadline if the
+// remaining work is done in the free time of the days that aren't over, without booking
+// a day beyond maxLoad of its window. Events with a fixed time are done when they end.
+func forecastRisks(days []*Day, maxLoad float64, now time.Time) []risk {
+	var forecastDays []schedule.Day
+	var items []schedule.Item
+	// events holds the unfinished events, the id of an item is the index of its event
+	var events []*Event
+	var progress []schedule.Progress
+	for _, day := range days {
+		over := !onCalendar(day.EndOfDay, now).After(now)
+
+		window := schedule.Day{Start: day.StartOfDay, End: day.EndOfDay}
+		// The time of today that has passed can't be used anymore
+		if start := onCalendar(day.StartOfDay, now); start.Before(now) {
+			window.Start = window.Start.Add(now.Sub(start))
+		}
+		for i := range day.Events {
+			event := &day.Events[i]
+			if event.finished(now) {
+				continue
+			}
+
+			fixed := !event.Start.IsZero() && !event.Scheduled
+			switch {
+			case fixed && !over:
+				window.Busy = append(window.Busy, schedule.Span{Start: event.Start, End: event.End})
+				progress = append(progress, schedule.Progress{Work: []schedule.Span{{Start: event.Start, End: event.End}}})
+			case fixed:
+				// It ended unfinished and waits for a new plan
+				progress = append(progress, schedule.Progress{Left: event.Duration})
+			default:
+				items = append(items, schedule.Item{
+					ID:       int64(len(events)),
+					Name:     event.Name,
+					Duration: event.Duration,
+					Deadline: event.Deadline,
+					Day:      schedule.AnyDay,
+				})
+				progress = append(progress, schedule.Progress{})
+			}
+			events = append(events, event)
+		}
+		if !over {
+			forecastDays = append(forecastDays, window)
+		}
+	}
+
+	for _, p := range schedule.Forecast(forecastDays, items, maxLoad) {
+		progress[p.Item.ID] = p
 	}
 
-	newEvent.End, err = getEndOf(newEvent.Start, layout, reader, "event")
+	var risks []risk
+	for i, event := range events {
+		if event.Deadline.IsZero() {
+			continue
+		}
+		short := progress[i].LeftAt(event.Deadline)
+		if short <= 0 {
+			continue
+		}
+
+		r := risk{event: event, finish: progress[i].Finish(), short: short}
+		if !r.finish.IsZero() {
+			r.late = r.finish.Sub(event.Deadline)
+		}
+		risks = append(risks, r)
+	}
+	sort.SliceStable(risks, func(i, j int) bool { return risks[i].event.Deadline.Before(risks[j].event.Deadline) })
+	return risks
+}
+
+// printRisks lists the events that are forecast to miss their deadline and by how much
+func printRisks(days []*Day, reader *bufio.Reader, now time.Time) error {
+	if len(days) == 0 {
+		return fmt.Errorf("there were no days created so far")
+	}
+
+	maxLoad, err := getMaxLoad(reader)
 	if err != nil {
-		return days, err
+		return err
 	}
 
-	newEvent.Duration = newEvent.End.Sub(newEvent.Start)
+	risks := forecastRisks(days, maxLoad, now)
+	if len(risks) == 0 {
+		fmt.Println("Every deadline can be met.")
+		return nil
+	}
+	for _, r := range risks {
+		when := fmt.Sprintf("done by %s, %s late", r.finish.Format("Jan 2 15:04"), formatDuration(r.late))
+		if r.finish.IsZero() {
+			when = "the days have no room for all of its work"
+		}
+		fmt.Printf("%s is at risk: due %s, %s, %s of work left at the deadline\n", r.event.Name,
+			r.event.Deadline.Format("Jan 2 15:04"), when, formatDuration(r.short))
+	}
+	return nil
+}
 
-	day.Events = append(day.Events, *newEvent)
+// warnRisks points out the events at risk of missing their deadline after a change, with
+// the default maximum load
+func warnRisks(days []*Day, now time.Time) {
+	var names []string
+	for _, r := range forecastRisks(days, schedule.DefaultMaxLoad, now) {
+		names = append(names, r.event.Name)
+	}
+	if len(names) > 0 {
+		fmt.Printf("Warning: %s may miss the deadline, option 12 shows by how much\n", strings.Join(names, ", "))
+	}
+}
 
-	return days, nil
+// getEventByIndex asks for one of the events of a day
+func getEventByIndex(day *Day, reader *bufio.Reader) (*Event, error) {
+	if len(day.Events) == 0 {
+		return nil, fmt.Errorf("the day has no events")
+	}
+
+	for {
+		fmt.Print("Please enter the index of the event: ")
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("error in reading from stdin")
+		}
+
+		index, err := strconv.Atoi(strings.TrimSpace(response))
+		if err != nil || index < 1 || index > len(day.Events) {
+			fmt.Println("Invalid input! Please enter the index of an event.")
+			continue
+		}
+		return &day.Events[index-1], nil
+	}
+}
+
+// getTodoIndex asks for one of the todos of an event, it returns -1 for the event itself
+func getTodoIndex(event *Event, reader *bufio.Reader) (int, error) {
+	for i, todo := range event.TodoList {
+		fmt.Printf("%d. %s\n", i+1, todo.Name)
+	}
+
+	for {
+		fmt.Print("Please enter the index of a todo(Just press enter for the whole event): ")
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return -1, fmt.Errorf("error in reading from stdin")
+		}
+
+		response = strings.TrimSpace(response)
+		if response == "" {
+			return -1, nil
+		}
+		index, err := strconv.Atoi(response)
+		if err != nil || index < 1 || index > len(event.TodoList) {
+			fmt.Println("Invalid input! Please enter the index of a todo.")
+			continue
+		}
+		return index - 1, nil
+	}
+}
+
+// runningEvent returns the event the timer runs for, nil if no timer runs
+func runningEvent(days []*Day) *Event {
+	for _, day := range days {
+		for i := range day.Events {
+			if track.Runs(day.Events[i].Entries) {
+				return &day.Events[i]
+			}
+		}
+	}
+	return nil
+}
+
+// getNote asks for an optional note on a time entry
+func getNote(reader *bufio.Reader) (string, error) {
+	fmt.Print("Please enter a note(If there is none just press enter): ")
+	note, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("error in reading from stdin")
+	}
+	return strings.TrimSpace(note), nil
+}
+
+// stopEvent stops the timer of an event, a note replaces the one given at the start
+func stopEvent(event *Event, note string, now time.Time) error {
+	entry, err := track.Stop(event.Entries, note, now)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Stopped the timer for %s after %s, %s tracked in total\n", event.Name,
+		formatDuration(entry.Duration(now)), formatDuration(track.Total(event.Entries, now)))
+	return nil
+}
+
+// startTimer starts tracking time for an event of a day. A timer that runs for another
+// event is stopped first.
+func startTimer(days []*Day, reader *bufio.Reader, layout string, now time.Time) error {
+	day, err := getDayByIndex(days, reader, layout, "track time on")
+	if err != nil {
+		return err
+	}
+
+	event, err := getEventByIndex(day, reader)
+	if err != nil {
+		return err
+	}
+
+	running := runningEvent(days)
+	if running == event {
+		fmt.Printf("The timer already runs for %s\n", event.Name)
+		return nil
+	}
+
+	note, err := getNote(reader)
+	if err != nil {
+		return err
+	}
+	if running != nil {
+		if err = stopEvent(running, "", now); err != nil {
+			return err
+		}
+	}
+	event.Entries = append(event.Entries, track.Entry{Start: now, Note: note})
+	fmt.Printf("Started the timer for %s at %s\n", event.Name, now.Format("15:04"))
+	return nil
+}
+
+// toggleDone marks an event as done or, if it already is, as not done
+func toggleDone(days []*Day, reader *bufio.Reader, layout string) error {
+	day, err := getDayByIndex(days, reader, layout, "mark an event of")
+	if err != nil {
+		return err
+	}
+
+	event, err := getEventByIndex(day, reader)
+	if err != nil {
+		return err
+	}
+
+	event.Done = !event.Done
+	if event.Done {
+		fmt.Printf("Marked %s as done\n", event.Name)
+	} else {
+		fmt.Printf("Marked %s as not done\n", event.Name)
+	}
+	return nil
+}
+
+// stopTimer stops the running timer
+func stopTimer(days []*Day, reader *bufio.Reader, now time.Time) error {
+	event := runningEvent(days)
+	if event == nil {
+		return track.ErrNotRunning
+	}
+
+	note, err := getNote(reader)
+	if err != nil {
+		return err
+	}
+	return stopEvent(event, note, now)
+}
+
+// eventTracked adds up the time tracked for an event and its todos
+func eventTracked(event Event, now time.Time) time.Duration {
+	tracked := track.Total(event.Entries, now)
+	for _, todo := range event.TodoList {
+		tracked += track.Total(todo.Entries, now)
+	}
+	return tracked
+}
+
+// formatDifference formats how far the tracked time is off the plan
+func formatDifference(c track.Comparison) string {
+	difference := c.Difference().Round(time.Minute)
+	switch {
+	case difference > 0:
+		return formatDuration(difference) + " over"
+	case difference < 0:
+		return formatDuration(-difference) + " under"
+	default:
+		return "as planned"
+	}
+}
+
+// reportTimes compares the planned with the tracked time of every event, running timers
+// count up to now
+func reportTimes(days []*Day, now time.Time) error {
+	if len(days) == 0 {
+		return fmt.Errorf("there were no days created so far")
+	}
+
+	var total track.Comparison
+	for _, day := range days {
+		fmt.Printf("%s:\n", day.StartOfDay.Format("Jan 2"))
+		if len(day.Events) == 0 {
+			fmt.Println("\tNo events")
+			continue
+		}
+
+		for _, event := range day.Events {
+			c := track.Comparison{Estimated: event.Duration, Actual: eventTracked(event, now)}
+			fmt.Printf("\t%s: planned %s, tracked %s, %s (%.2f hours)\n", event.Name, formatDuration(c.Estimated),
+				formatDuration(c.Actual), formatDifference(c), track.Hours(c.Actual))
+			total.Estimated += c.Estimated
+			total.Actual += c.Actual
+		}
+	}
+	fmt.Printf("Total: planned %s, tracked %s, %s (%.2f hours)\n", formatDuration(total.Estimated),
+		formatDuration(total.Actual), formatDifference(total), track.Hours(total.Actual))
+
+	return nil
 }
 
 func main() {
 	var input string
 	var err error
-	var days []*Day
-	layout := ""
+
+	// "focus" runs a single focus session without the menu, "start" and "stop" the timer,
+	// e.g. from a script
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "focus":
+			os.Exit(runFocus(os.Args[2:]))
+		case "start":
+			os.Exit(runStart(os.Args[2:]))
+		case "stop":
+			os.Exit(runStop(os.Args[2:]))
+		}
+	}
+
+	dataFile := flag.String("data", defaultDataFile, "file the days are kept in")
+	flag.Parse()
+	days, layout, err := loadDays(*dataFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error in loading the days:", err)
+		os.Exit(1)
+	}
 
 	reader := bufio.NewReader(os.Stdin)
 
@@ -358,7 +1386,7 @@ func main() {
 			os.Exit(0)
 
 		case "1":
-			days, layout, err = createDay(days, layout)
+			days, layout, err = createDay(days, layout, time.Now())
 			if err != nil {
 				fmt.Println("Error in creating day:", err)
 				continue
@@ -367,22 +1395,105 @@ func main() {
 			fmt.Println("Successfully added new day")
 
 		case "2":
-			days, err = addNewEvent(days, reader, layout)
+			days, err = addNewEvent(days, reader, layout, time.Now())
 			if err != nil {
 				fmt.Println("Error in adding new event:", err)
 				continue
 			}
 			fmt.Println("Successfully added a new event")
 
+		case "3":
+			err = addNewTodo(days, reader, layout)
+			if err != nil {
+				fmt.Println("Error in adding new todo:", err)
+				continue
+			}
+			fmt.Println("Successfully added a new todo")
+
 		case "4":
-			err = printDays(days, layout)
+			opts, err := readPrintOptions(reader)
+			if err != nil {
+				fmt.Println("Error in reading print options:", err)
+				continue
+			}
+			err = printDays(days, layout, opts)
 			if err != nil {
 				fmt.Println("Error in printing days:", err)
 				continue
 			}
 
+		case "5":
+			err = scheduleEvents(days, layout)
+			if err != nil {
+				fmt.Println("Error in scheduling events:", err)
+				continue
+			}
+
+		case "6":
+			err = rebalanceEvents(days, reader, time.Now())
+			if err != nil {
+				fmt.Println("Error in rebalancing events:", err)
+				continue
+			}
+
+		case "7":
+			err = startTimer(days, reader, layout, time.Now())
+			if err != nil {
+				fmt.Println("Error in starting the timer:", err)
+				continue
+			}
+
+		case "8":
+			err = stopTimer(days, reader, time.Now())
+			if err != nil {
+				fmt.Println("Error in stopping the timer:", err)
+				continue
+			}
+
+		case "9":
+			err = reportTimes(days, time.Now())
+			if err != nil {
+				fmt.Println("Error in comparing the times:", err)
+				continue
+			}
+
+		case "10":
+			err = focusSession(days, reader, layout)
+			if err != nil {
+				fmt.Println("Error in the focus session:", err)
+				continue
+			}
+
+		case "11":
+			err = rollOver(days, reader, time.Now())
+			if err != nil {
+				fmt.Println("Error in rolling over unfinished work:", err)
+				continue
+			}
+
+		case "12":
+			err = printRisks(days, reader, time.Now())
+			if err != nil {
+				fmt.Println("Error in forecasting the deadlines:", err)
+				continue
+			}
+
+		case "13":
+			err = toggleDone(days, reader, layout)
+			if err != nil {
+				fmt.Println("Error in marking the event:", err)
+				continue
+			}
+
 		default:
 			fmt.Printf("%s is an invalid option!\n", input)
 		}
+
+		if changingOptions[input] {
+			warnRisks(days, time.Now())
+		}
+		if err := saveDays(*dataFile, days, layout); err != nil {
+			fmt.Println("Error in saving the days:", err)
+		}
 	}
 }
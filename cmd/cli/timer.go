@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/track"
+	"os"
+	"time"
+)
+
+// runStart starts the timer of the event that -day and -event pick, so that it can be
+// scripted. A timer that runs for another event is stopped first. It returns the exit
+// code of the program.
+func runStart(args []string) int {
+	flags := flag.NewFlagSet("start", flag.ContinueOnError)
+	dataFile := flags.String("data", defaultDataFile, "file the days are kept in")
+	dayIndex := flags.Int("day", 0, "index of the day of the event, as the menu lists it")
+	eventIndex := flags.Int("event", 0, "index of the event on its day")
+	note := flags.String("note", "", "note on the time entry")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	days, layout, err := loadDays(*dataFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error in loading the days:", err)
+		return 1
+	}
+	event, _, err := selectWork(days, *dayIndex, *eventIndex, 0)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error in starting the timer:", err)
+		return 2
+	}
+
+	now := time.Now()
+	running := runningEvent(days)
+	if running == event {
+		fmt.Printf("The timer already runs for %s\n", event.Name)
+		return 0
+	}
+	if running != nil {
+		if err = stopEvent(running, "", now); err != nil {
+			fmt.Fprintln(os.Stderr, "Error in stopping the timer:", err)
+			return 1
+		}
+	}
+	event.Entries = append(event.Entries, track.Entry{Start: now, Note: *note})
+	fmt.Printf("Started the timer for %s at %s\n", event.Name, now.Format("15:04"))
+
+	if err = saveDays(*dataFile, days, layout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error in saving the days:", err)
+		return 1
+	}
+	return 0
+}
+
+// runStop stops the running timer, a -note replaces the one given at the start. It
+// returns the exit code of the program.
+func runStop(args []string) int {
+	flags := flag.NewFlagSet("stop", flag.ContinueOnError)
+	dataFile := flags.String("data", defaultDataFile, "file the days are kept in")
+	note := flags.String("note", "", "note on the time entry, replaces the one given at the start")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	days, layout, err := loadDays(*dataFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error in loading the days:", err)
+		return 1
+	}
+	event := runningEvent(days)
+	if event == nil {
+		fmt.Fprintln(os.Stderr, "Error in stopping the timer:", track.ErrNotRunning)
+		return 1
+	}
+	if err = stopEvent(event, *note, time.Now()); err != nil {
+		fmt.Fprintln(os.Stderr, "Error in stopping the timer:", err)
+		return 1
+	}
+
+	if err = saveDays(*dataFile, days, layout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error in saving the days:", err)
+		return 1
+	}
+	return 0
+}
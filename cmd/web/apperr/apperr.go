@@ -0,0 +1,109 @@
+// Package apperr defines the errors that handlers show to users. The message of an
+// error is safe to display, the wrapped cause is only written to the log.
+package apperr
+
+import (
+	"errors"
+	"net/http"
+)
+
+type Kind int
+
+const (
+	Internal Kind = iota
+	Validation
+	NotFound
+	Unauthorized
+	Conflict
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Validation:
+		return "validation"
+	case NotFound:
+		return "not_found"
+	case Unauthorized:
+		return "unauthorized"
+	case Conflict:
+		return "conflict"
+	}
+	return "internal"
+}
+
+// Status returns the HTTP status code of the kind
+func (k Kind) Status() int {
+	switch k {
+	case Validation:
+		return http.StatusUnprocessableEntity
+	case NotFound:
+		return http.StatusNotFound
+	case Unauthorized:
+		return http.StatusUnauthorized
+	case Conflict:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is an error with a kind and a message that can be shown to the user
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) AppErrorKind() Kind {
+	return e.Kind
+}
+
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Wrap attaches a user facing message to err
+func Wrap(kind Kind, message string, err error) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+// kinded is implemented by every error that knows its kind, e.g. validation errors of
+// the store
+type kinded interface {
+	error
+	AppErrorKind() Kind
+}
+
+// KindOf returns the kind of err, errors without one are internal
+func KindOf(err error) Kind {
+	var k kinded
+	if errors.As(err, &k) {
+		return k.AppErrorKind()
+	}
+	return Internal
+}
+
+// Message returns the text that can be shown to the user. Internal errors never reveal
+// their cause.
+func Message(err error) string {
+	var k kinded
+	if !errors.As(err, &k) || k.AppErrorKind() == Internal {
+		return "Something went wrong on our side. Please try again later."
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Message
+	}
+	return k.Error()
+}
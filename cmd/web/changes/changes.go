@@ -0,0 +1,210 @@
+// Package changes passes notifications about changed days, events and todos from the
+// task store to the pages a user has open
+package changes
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Kind string
+
+const (
+	Created Kind = "created"
+	Updated Kind = "updated"
+	Deleted Kind = "deleted"
+)
+
+type Entity string
+
+const (
+	Day   Entity = "day"
+	Event Entity = "event"
+	Todo  Entity = "todo"
+)
+
+// Change describes a single day, event or todo of a user that was written. DayID and
+// EventID point to the parents, so that a page knows which card to refresh.
+type Change struct {
+	ID       string `json:"-"`
+	UserID   int    `json:"-"`
+	Kind     Kind   `json:"kind"`
+	Entity   Entity `json:"entity"`
+	EntityID int64  `json:"id"`
+	DayID    int64  `json:"day,omitempty"`
+	EventID  int64  `json:"event,omitempty"`
+}
+
+const (
+	defaultHistory = 1024
+	subscriberBuf  = 64
+)
+
+// Default is the bus the task store publishes to
+var Default = NewBus(defaultHistory)
+
+// Publish sends c to the subscribers of its user on the default bus
+func Publish(c Change) {
+	Default.Publish(c)
+}
+
+// Bus fans changes out to the subscribers of a user. It keeps the latest changes so that
+// a client that lost its connection can resume where it stopped.
+//
+// Change ids are "<epoch>-<sequence>", the epoch changes with every start of the server
+// so that ids of an earlier process are never mistaken for current ones.
+type Bus struct {
+	epoch string
+
+	mu      sync.Mutex
+	seq     uint64
+	history []Change
+	size    int
+	subs    map[int]map[*Subscription]struct{}
+}
+
+func NewBus(historySize int) *Bus {
+	return &Bus{
+		epoch: strconv.FormatInt(time.Now().UnixNano(), 36),
+		size:  historySize,
+		subs:  map[int]map[*Subscription]struct{}{},
+	}
+}
+
+// Subscription receives the changes of one user until it is closed. C is closed as well
+// when the subscriber can't keep up, it then has to resume with the id of the last
+// change it received.
+type Subscription struct {
+	C <-chan Change
+
+	c      chan Change
+	bus    *Bus
+	userId int
+	closed bool
+}
+
+func (b *Bus) id(seq uint64) string {
+	return b.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// parseID returns the sequence of an id of this bus
+func (b *Bus) parseID(id string) (uint64, bool) {
+	epoch, seq, found := strings.Cut(id, "-")
+	if !found || epoch != b.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
+
+// LastID returns the id of the latest change, pages pass it to Subscribe to get
+// everything that happened after they were rendered
+func (b *Bus) LastID() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.id(b.seq)
+}
+
+// Publish assigns the change an id and hands it to all subscribers of its user
+func (b *Bus) Publish(c Change) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	c.ID = b.id(b.seq)
+
+	b.history = append(b.history, c)
+	if len(b.history) > b.size {
+		b.history = b.history[len(b.history)-b.size:]
+	}
+
+	for sub := range b.subs[c.UserID] {
+		select {
+		case sub.c <- c:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe starts receiving the changes of a user. Missed holds the changes made after
+// lastID. If they aren't known anymore, e.g. because the server restarted, resumed is
+// false and the client has to load everything again. An empty lastID starts with the
+// next change.
+func (b *Bus) Subscribe(userId int, lastID string) (sub *Subscription, missed []Change, resumed bool) {
+	c := make(chan Change, subscriberBuf)
+	sub = &Subscription{C: c, c: c, bus: b, userId: userId}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subs[userId] == nil {
+		b.subs[userId] = map[*Subscription]struct{}{}
+	}
+	b.subs[userId][sub] = struct{}{}
+
+	if lastID == "" {
+		return sub, nil, true
+	}
+
+	seq, ok := b.parseID(lastID)
+	if !ok || seq > b.seq {
+		return sub, nil, false
+	}
+	// The history has to reach back to the change right after lastID
+	if seq < b.seq && !b.knows(seq+1) {
+		return sub, nil, false
+	}
+
+	for _, change := range b.history {
+		n, _ := b.parseID(change.ID)
+		if n > seq && change.UserID == userId {
+			missed = append(missed, change)
+		}
+	}
+	return sub, missed, true
+}
+
+// knows reports whether the change with the given sequence is still in the history
+func (b *Bus) knows(seq uint64) bool {
+	if len(b.history) == 0 {
+		return false
+	}
+	first, _ := b.parseID(b.history[0].ID)
+	return seq >= first
+}
+
+// Subscribers returns the number of open subscriptions
+func (b *Bus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := 0
+	for _, subs := range b.subs {
+		n += len(subs)
+	}
+	return n
+}
+
+// remove has to be called with b.mu held
+func (b *Bus) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.c)
+
+	delete(b.subs[sub.userId], sub)
+	if len(b.subs[sub.userId]) == 0 {
+		delete(b.subs, sub.userId)
+	}
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
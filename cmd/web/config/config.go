@@ -0,0 +1,336 @@
+// Package config loads the settings of the web server. Values are taken from, in
+// increasing order of precedence: the defaults, a YAML config file, TASKWEAVE_*
+// environment variables and command line flags.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const envPrefix = "TASKWEAVE_"
+
+// Registration modes
+const (
+	RegistrationOpen   = "open"
+	RegistrationClosed = "closed"
+)
+
+type TLS struct {
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+	// RedirectListen is the address of a plain HTTP listener that redirects to HTTPS
+	RedirectListen string `yaml:"redirect_listen"`
+}
+
+type Cookie struct {
+	// Secure is "auto", "true" or "false". With auto the cookie is only marked secure
+	// when the server is reached over HTTPS.
+	Secure   string `yaml:"secure"`
+	Domain   string `yaml:"domain"`
+	SameSite string `yaml:"same_site"`
+}
+
+type Session struct {
+	Lifetime    time.Duration `yaml:"lifetime"`
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+}
+
+type Config struct {
+	Listen       string  `yaml:"listen"`
+	DBPath       string  `yaml:"db_path"`
+	BaseURL      string  `yaml:"base_url"`
+	TLS          TLS     `yaml:"tls"`
+	Cookie       Cookie  `yaml:"cookie"`
+	Session      Session `yaml:"session"`
+	Registration string  `yaml:"registration"`
+	LogLevel     string  `yaml:"log_level"`
+	LogFormat    string  `yaml:"log_format"`
+	Dev          bool    `yaml:"dev"`
+	// ShutdownTimeout is how long in-flight requests and workers get to finish on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// ShutdownDelay is how long /readyz reports unready before the listener is closed
+	ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+}
+
+// Default returns the configuration used when nothing else is set
+func Default() Config {
+	return Config{
+		Listen:  ":8080",
+		DBPath:  "./db/app.db",
+		BaseURL: "http://localhost:8080",
+		Cookie: Cookie{
+			Secure:   "auto",
+			SameSite: "lax",
+		},
+		Session: Session{
+			Lifetime: 30 * 24 * time.Hour,
+		},
+		Registration:    RegistrationOpen,
+		LogLevel:        "info",
+		LogFormat:       "text",
+		ShutdownTimeout: 30 * time.Second,
+	}
+}
+
+// setting is a single option that can be given as flag and environment variable
+type setting struct {
+	name  string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+func setString(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func setDuration(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+var settings = []setting{
+	{"listen", "address the server listens on", setString(func(c *Config) *string { return &c.Listen })},
+	{"db-path", "path of the SQLite database", setString(func(c *Config) *string { return &c.DBPath })},
+	{"base-url", "URL under which the server is reached", setString(func(c *Config) *string { return &c.BaseURL })},
+	{"tls-cert", "TLS certificate file", setString(func(c *Config) *string { return &c.TLS.Cert })},
+	{"tls-key", "TLS private key file", setString(func(c *Config) *string { return &c.TLS.Key })},
+	{"tls-redirect-listen", "address of a plain HTTP listener that redirects to HTTPS, empty disables it", setString(func(c *Config) *string { return &c.TLS.RedirectListen })},
+	{"cookie-secure", "mark the session cookie as secure: auto, true or false", setString(func(c *Config) *string { return &c.Cookie.Secure })},
+	{"cookie-domain", "domain of the session cookie", setString(func(c *Config) *string { return &c.Cookie.Domain })},
+	{"cookie-same-site", "SameSite mode of the session cookie: lax, strict or none", setString(func(c *Config) *string { return &c.Cookie.SameSite })},
+	{"session-lifetime", "time after which a session expires", setDuration(func(c *Config) *time.Duration { return &c.Session.Lifetime })},
+	{"session-idle-timeout", "time after which an unused session expires, 0 disables it", setDuration(func(c *Config) *time.Duration { return &c.Session.IdleTimeout })},
+	{"registration", "who can sign up: open or closed", setString(func(c *Config) *string { return &c.Registration })},
+	{"log-level", "log level: debug, info, warn or error", setString(func(c *Config) *string { return &c.LogLevel })},
+	{"log-format", "log format: text or json", setString(func(c *Config) *string { return &c.LogFormat })},
+	{"shutdown-timeout", "time in-flight requests get to finish on shutdown", setDuration(func(c *Config) *time.Duration { return &c.ShutdownTimeout })},
+	{"shutdown-delay", "time /readyz fails before the server stops accepting connections", setDuration(func(c *Config) *time.Duration { return &c.ShutdownDelay })},
+	{"dev", "reload templates and serve static files from the working directory", func(c *Config, value string) error {
+		dev, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		c.Dev = dev
+		return nil
+	}},
+}
+
+// envName returns the environment variable of a setting, e.g. TASKWEAVE_DB_PATH
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Load builds the configuration from the command line arguments, the environment and
+// the config file given with -config or TASKWEAVE_CONFIG
+func Load(args []string) (Config, error) {
+	fs := flag.NewFlagSet("taskweave", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv(envPrefix+"CONFIG"), "path of a YAML config file")
+	for _, s := range settings {
+		if s.name == "dev" {
+			fs.Bool(s.name, false, s.usage)
+			continue
+		}
+		fs.String(s.name, "", fmt.Sprintf("%s (env %s)", s.usage, envName(s.name)))
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg := Default()
+	if *configPath != "" {
+		err = cfg.readFile(*configPath)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings {
+		value, ok := os.LookupEnv(envName(s.name))
+		if !ok {
+			continue
+		}
+		err = s.set(&cfg, value)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s: %w", envName(s.name), err)
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.name == f.Name && err == nil {
+				err = s.set(&cfg, f.Value.String())
+				if err != nil {
+					err = fmt.Errorf("-%s: %w", f.Name, err)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return Config{}, err
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(c)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Validate reports every invalid setting at once
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		invalid("listen: %v", err)
+	}
+	if c.DBPath == "" {
+		invalid("db_path must not be empty")
+	}
+
+	if u, err := url.Parse(c.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		invalid("base_url must be an absolute http or https URL")
+	}
+
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		invalid("tls: cert and key have to be set together")
+	}
+	for _, file := range []string{c.TLS.Cert, c.TLS.Key} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			invalid("tls: %v", err)
+		}
+	}
+	if c.TLS.RedirectListen != "" {
+		if !c.TLSEnabled() {
+			invalid("tls.redirect_listen requires tls.cert and tls.key")
+		}
+		if _, _, err := net.SplitHostPort(c.TLS.RedirectListen); err != nil {
+			invalid("tls.redirect_listen: %v", err)
+		}
+	}
+
+	switch c.Cookie.Secure {
+	case "auto", "true", "false":
+	default:
+		invalid("cookie.secure must be auto, true or false")
+	}
+	sameSite, err := c.SameSite()
+	if err != nil {
+		invalid("cookie.same_site: %v", err)
+	} else if sameSite == http.SameSiteNoneMode && !c.CookieSecure() {
+		invalid("cookie.same_site none requires a secure cookie")
+	}
+
+	if c.Session.Lifetime <= 0 {
+		invalid("session.lifetime must be positive")
+	}
+	if c.Session.IdleTimeout < 0 {
+		invalid("session.idle_timeout must not be negative")
+	}
+
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		invalid("log_format must be text or json")
+	}
+	if c.ShutdownTimeout <= 0 {
+		invalid("shutdown_timeout must be positive")
+	}
+	if c.ShutdownDelay < 0 {
+		invalid("shutdown_delay must not be negative")
+	}
+
+	if c.Registration != RegistrationOpen && c.Registration != RegistrationClosed {
+		invalid("registration must be open or closed")
+	}
+	if _, err := c.Level(); err != nil {
+		invalid("log_level: %v", err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// TLSEnabled reports whether the server serves HTTPS itself
+func (c Config) TLSEnabled() bool {
+	return c.TLS.Cert != "" && c.TLS.Key != ""
+}
+
+// CookieSecure resolves the "auto" setting of cookie.secure
+func (c Config) CookieSecure() bool {
+	switch c.Cookie.Secure {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return c.HTTPS()
+}
+
+// HTTPS reports whether clients reach the server over HTTPS, either served by the server
+// itself or by a proxy in front of it
+func (c Config) HTTPS() bool {
+	return c.TLSEnabled() || strings.HasPrefix(c.BaseURL, "https://")
+}
+
+func (c Config) SameSite() (http.SameSite, error) {
+	switch strings.ToLower(c.Cookie.SameSite) {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("unknown mode %q", c.Cookie.SameSite)
+}
+
+func (c Config) Level() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(c.LogLevel))
+	return level, err
+}
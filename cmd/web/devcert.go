@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/certs"
+	"path/filepath"
+	"strings"
+)
+
+// devCert implements `TaskWeave dev-cert`. It creates a local CA and a certificate for
+// the given hosts so that the server can be tried with HTTPS and secure cookies.
+func devCert(args []string) error {
+	fs := flag.NewFlagSet("taskweave dev-cert", flag.ContinueOnError)
+	dir := fs.String("dir", "certs", "directory the CA and the certificate are written to")
+	hosts := fs.String("hosts", "localhost,127.0.0.1,::1", "comma separated host names and IP addresses of the certificate")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for _, host := range strings.Split(*hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			names = append(names, host)
+		}
+	}
+
+	err = certs.GenerateDev(*dir, names)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created a certificate for %s in %s\n\n", strings.Join(names, ", "), *dir)
+	fmt.Printf("Add %s to the trusted certificates of your browser or system, then start the server with\n",
+		filepath.Join(*dir, certs.CAFile))
+	fmt.Printf("  TaskWeave -tls-cert %s -tls-key %s -base-url https://localhost:8080\n",
+		filepath.Join(*dir, certs.CertFile), filepath.Join(*dir, certs.KeyFile))
+	return nil
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// BalancePage is the data of templates/balance.html and the answer for API clients. GET
+// shows the proposed moves, POST applies them.
+type BalancePage struct {
+	// From and To are the first and the last date of the range
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	MaxLoad int       `json:"max_load"`
+	// Plan identifies the moves, POST only applies them when it is sent along unchanged
+	Plan     string          `json:"plan"`
+	Applied  bool            `json:"applied"`
+	Moves    []BalanceMove   `json:"moves"`
+	Unplaced []UnplacedEvent `json:"unplaced"`
+	Days     []BalanceDay    `json:"days"`
+}
+
+type BalanceMove struct {
+	ID       int64         `json:"id"`
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"-"`
+	Minutes  int           `json:"minutes"`
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+}
+
+// BalanceDay is the load of a day once the moves are applied
+type BalanceDay struct {
+	ID   int64     `json:"id"`
+	Date time.Time `json:"date"`
+	// Load is the booked share of the working window in percent
+	Load int `json:"load"`
+	// Left is the time that may still be booked before the maximum load is reached
+	Left        time.Duration `json:"-"`
+	LeftMinutes int           `json:"left_minutes"`
+}
+
+// balanceRange holds the query of the balance page
+type balanceRange struct {
+	from, to time.Time
+	maxLoad  int
+}
+
+// readBalanceRange reads the from and to dates and the max_load percentage. The range
+// defaults to next week and the maximum load to the user's setting.
+func readBalanceRange(r *http.Request, today time.Time, settings internal.UserSettings) (balanceRange, error) {
+	var br balanceRange
+	br.from = startOfWeek(today).AddDate(0, 0, 7)
+	br.to = br.from.AddDate(0, 0, 6)
+	br.maxLoad = int(settings.Load()*100 + 0.5)
+
+	var err error
+	if from := r.FormValue("from"); from != "" {
+		br.from, err = time.ParseInLocation(formDateLayout, from, today.Location())
+		if err != nil {
+			return br, apperr.New(apperr.Validation, "Invalid date, expected YYYY-MM-DD")
+		}
+		br.to = br.from.AddDate(0, 0, 6)
+	}
+	if to := r.FormValue("to"); to != "" {
+		br.to, err = time.ParseInLocation(formDateLayout, to, today.Location())
+		if err != nil {
+			return br, apperr.New(apperr.Validation, "Invalid date, expected YYYY-MM-DD")
+		}
+	}
+	if br.to.Before(br.from) {
+		return br, apperr.New(apperr.Validation, "The range has to end on or after its first day")
+	}
+	if load := r.FormValue("max_load"); load != "" {
+		br.maxLoad, err = strconv.Atoi(load)
+		if err != nil || br.maxLoad < 1 || br.maxLoad > 100 {
+			return br, apperr.New(apperr.Validation, "The maximum load has to be between 1 and 100 percent")
+		}
+	}
+
+	return br, nil
+}
+
+func newBalancePage(br balanceRange, balance internal.Rebalance, loc *time.Location) BalancePage {
+	page := BalancePage{From: br.from, To: br.to, MaxLoad: br.maxLoad, Plan: balance.Hash(), Moves: []BalanceMove{}, Unplaced: []UnplacedEvent{}, Days: []BalanceDay{}}
+	days := localizeDays(balance.Days, loc)
+
+	for _, m := range balance.Plan.Moves {
+		page.Moves = append(page.Moves, BalanceMove{
+			ID:       m.Item.ID,
+			Name:     m.Item.Name,
+			Duration: m.Item.Duration,
+			Minutes:  int(m.Item.Duration.Minutes()),
+			From:     days[m.From].Date,
+			To:       days[m.To].Date,
+		})
+	}
+	for _, u := range balance.Plan.Unplaced {
+		page.Unplaced = append(page.Unplaced, UnplacedEvent{u.Item.ID, u.Item.Name, u.Reason})
+	}
+	for i, load := range balance.Plan.Loads {
+		left := load.Capacity - load.Flexible
+		if left < 0 {
+			left = 0
+		}
+		page.Days = append(page.Days, BalanceDay{
+			ID:          days[i].ID,
+			Date:        days[i].Date,
+			Load:        int(load.Share()*100 + 0.5),
+			Left:        left,
+			LeftMinutes: int(left.Minutes()),
+		})
+	}
+	return page
+}
+
+// BalanceHandler spreads the flexible events of a range of days, next week by default, so
+// that no day is booked beyond the maximum load. GET previews the moves, POST makes them if
+// they are still the ones of the preview.
+func BalanceHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := internal.GetUserSettings(r.Context(), userId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	loc := userPrefs(r).Location
+	now := time.Now().In(loc)
+	br, err := readBalanceRange(r, onDate(now, loc), settings)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	var result internal.Rebalance
+	if r.Method == http.MethodPost {
+		result, err = internal.RebalanceEvents(r.Context(), userId, br.from, br.to.AddDate(0, 0, 1), float64(br.maxLoad)/100, now, r.FormValue("plan"))
+	} else {
+		result, err = internal.PreviewRebalance(r.Context(), userId, br.from, br.to.AddDate(0, 0, 1), float64(br.maxLoad)/100, now)
+	}
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	page := newBalancePage(br, result, loc)
+	page.Applied = r.Method == http.MethodPost
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, page)
+		return
+	}
+	renderPage(w, r, "balance", page)
+}
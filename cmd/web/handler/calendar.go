@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/locale"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultFirstHour = 8
+	defaultLastHour  = 18
+)
+
+// CalendarEvent is an event with a fixed time together with its position in the day
+// column. Top and Height are percentages of the shown hours.
+type CalendarEvent struct {
+	internal.Event
+	Top    float64
+	Height float64
+}
+
+// CalendarDeadline is an event or todo which is due on a calendar day
+type CalendarDeadline struct {
+	Name     string
+	Deadline time.Time
+	Done     bool
+}
+
+// CalendarDay is a single cell of the calendar
+type CalendarDay struct {
+	Date         time.Time
+	Today        bool
+	InMonth      bool
+	Day          *internal.Day
+	WindowTop    float64
+	WindowHeight float64
+	Events       []CalendarEvent
+	Unscheduled  []internal.Event
+	Deadlines    []CalendarDeadline
+}
+
+// CalendarWeek is a row of the month view
+type CalendarWeek struct {
+	Number int
+	Days   []CalendarDay
+}
+
+// CalendarPage holds the data of the day, week and month view
+type CalendarPage struct {
+	View  string
+	Title string
+	Prev  string
+	Next  string
+	Today string
+	Week  int
+	Hours []int
+	Days  []CalendarDay
+	Weeks []CalendarWeek
+}
+
+func dateKey(t time.Time) string {
+	return t.Format(formDateLayout)
+}
+
+func startOfDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns the monday of the ISO week t is in
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return startOfDate(t).AddDate(0, 0, -offset)
+}
+
+// calendarIndex groups the days of a user and all deadlines by date
+type calendarIndex struct {
+	days      map[string]*internal.Day
+	deadlines map[string][]CalendarDeadline
+}
+
+func newCalendarIndex(days []internal.Day) calendarIndex {
+	index := calendarIndex{
+		days:      map[string]*internal.Day{},
+		deadlines: map[string][]CalendarDeadline{},
+	}
+
+	for i := range days {
+		day := &days[i]
+		index.days[dateKey(day.Date)] = day
+
+		for _, event := range day.Events {
+			if !event.Deadline.IsZero() {
+				key := dateKey(event.Deadline)
+				index.deadlines[key] = append(index.deadlines[key], CalendarDeadline{Name: event.Name, Deadline: event.Deadline})
+			}
+			for _, todo := range event.TodoList {
+				if !todo.Deadline.IsZero() {
+					key := dateKey(todo.Deadline)
+					index.deadlines[key] = append(index.deadlines[key], CalendarDeadline{Name: todo.Name, Deadline: todo.Deadline, Done: todo.Done})
+				}
+			}
+		}
+	}
+
+	return index
+}
+
+// hourRange returns the hours that have to be shown so that every working window and
+// every event of the dates is visible
+func (index calendarIndex) hourRange(dates []time.Time) (int, int) {
+	first, last := defaultFirstHour, defaultLastHour
+
+	include := func(start, end time.Time) {
+		if start.IsZero() || end.IsZero() {
+			return
+		}
+		first = min(first, start.Hour())
+		endHour := end.Hour()
+		if end.Minute() > 0 {
+			endHour++
+		}
+		if !sameDate(start, end) {
+			endHour = 24
+		}
+		last = max(last, endHour)
+	}
+
+	for _, date := range dates {
+		day, ok := index.days[dateKey(date)]
+		if !ok {
+			continue
+		}
+		include(day.StartOfDay, day.EndOfDay)
+		for _, event := range day.Events {
+			include(event.Start, event.End)
+		}
+	}
+
+	return first, last
+}
+
+func sameDate(a, b time.Time) bool {
+	return dateKey(a) == dateKey(b)
+}
+
+func (index calendarIndex) calendarDay(date time.Time, today time.Time, firstHour, lastHour int) CalendarDay {
+	cell := CalendarDay{
+		Date:      date,
+		Today:     sameDate(date, today),
+		InMonth:   true,
+		Deadlines: index.deadlines[dateKey(date)],
+	}
+
+	day, ok := index.days[dateKey(date)]
+	if !ok {
+		return cell
+	}
+	cell.Day = day
+
+	top := time.Date(date.Year(), date.Month(), date.Day(), firstHour, 0, 0, 0, date.Location())
+	total := time.Duration(lastHour-firstHour) * time.Hour
+	position := func(start, end time.Time) (float64, float64) {
+		return 100 * float64(start.Sub(top)) / float64(total), 100 * float64(end.Sub(start)) / float64(total)
+	}
+
+	cell.WindowTop, cell.WindowHeight = position(day.StartOfDay, day.EndOfDay)
+	for _, event := range day.Events {
+		if !event.Fixed() {
+			cell.Unscheduled = append(cell.Unscheduled, event)
+			continue
+		}
+
+		calendarEvent := CalendarEvent{Event: event}
+		calendarEvent.Top, calendarEvent.Height = position(event.Start, event.End)
+		cell.Events = append(cell.Events, calendarEvent)
+	}
+
+	return cell
+}
+
+// buildCalendar creates the page for the view around the anchor date. All times have to
+// be in the same location as anchor and now.
+func buildCalendar(view string, anchor time.Time, now time.Time, days []internal.Day, l *locale.Locale) CalendarPage {
+	index := newCalendarIndex(days)
+	anchor = startOfDate(anchor)
+	page := CalendarPage{View: view, Today: dateKey(now)}
+
+	var dates []time.Time
+	switch view {
+	case "day":
+		dates = []time.Time{anchor}
+		page.Title = l.LongDate(anchor)
+		page.Prev = dateKey(anchor.AddDate(0, 0, -1))
+		page.Next = dateKey(anchor.AddDate(0, 0, 1))
+	case "week":
+		monday := startOfWeek(anchor)
+		for i := 0; i < 7; i++ {
+			dates = append(dates, monday.AddDate(0, 0, i))
+		}
+		year, week := monday.ISOWeek()
+		page.Title = l.T("Week %d, %d", week, year)
+		page.Prev = dateKey(monday.AddDate(0, 0, -7))
+		page.Next = dateKey(monday.AddDate(0, 0, 7))
+	case "month":
+		first := time.Date(anchor.Year(), anchor.Month(), 1, 0, 0, 0, 0, anchor.Location())
+		page.Title = l.MonthYear(first)
+		page.Prev = dateKey(first.AddDate(0, -1, 0))
+		page.Next = dateKey(first.AddDate(0, 1, 0))
+
+		for monday := startOfWeek(first); monday.Before(first.AddDate(0, 1, 0)); monday = monday.AddDate(0, 0, 7) {
+			_, number := monday.ISOWeek()
+			week := CalendarWeek{Number: number}
+			for i := 0; i < 7; i++ {
+				date := monday.AddDate(0, 0, i)
+				cell := index.calendarDay(date, now, defaultFirstHour, defaultLastHour)
+				cell.InMonth = date.Month() == first.Month()
+				week.Days = append(week.Days, cell)
+			}
+			page.Weeks = append(page.Weeks, week)
+		}
+		return page
+	}
+
+	_, page.Week = dates[0].ISOWeek()
+	firstHour, lastHour := index.hourRange(dates)
+	for hour := firstHour; hour < lastHour; hour++ {
+		page.Hours = append(page.Hours, hour)
+	}
+	for _, date := range dates {
+		page.Days = append(page.Days, index.calendarDay(date, now, firstHour, lastHour))
+	}
+
+	return page
+}
+
+// renderCalendar shows the day, week or month view of the user's tasks
+func renderCalendar(w http.ResponseWriter, r *http.Request, view string, days []internal.Day) {
+	prefs := userPrefs(r)
+	now := time.Now().In(prefs.Location)
+	anchor := now
+	if date := r.URL.Query().Get("date"); date != "" {
+		parsed, err := time.ParseInLocation(formDateLayout, date, prefs.Location)
+		if err != nil {
+			renderError(w, r, apperr.New(apperr.Validation, "Invalid date, expected YYYY-MM-DD"))
+			return
+		}
+		anchor = parsed
+	}
+
+	renderPage(w, r, "calendar", buildCalendar(view, anchor, now, localizeDays(days, prefs.Location), prefs.Locale))
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/changes"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"net/http"
+	"time"
+)
+
+// heartbeatInterval keeps proxies from closing an idle change stream
+const heartbeatInterval = 25 * time.Second
+
+// ChangesHandler streams the changes to the user's days, events and todos as server-sent
+// events named created, updated and deleted. Clients resume with the Last-Event-ID header
+// or the since parameter, a reset event tells them to load the page again because the
+// missed changes are unknown.
+func ChangesHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("since")
+	}
+
+	sub, missed, resumed := changes.Default.Subscribe(userId, lastID)
+	defer sub.Close()
+
+	// The stream stays open much longer than the write timeout of the server
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if !resumed {
+		fmt.Fprintf(w, "id: %s\nevent: reset\ndata: {}\n\n", changes.Default.LastID())
+	}
+	for _, change := range missed {
+		writeChange(w, change)
+	}
+	if rc.Flush() != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-shutdownStarted:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case change, ok := <-sub.C:
+			if !ok {
+				// Too slow to keep up, the client reconnects and gets the missed changes
+				return
+			}
+			writeChange(w, change)
+		}
+
+		if rc.Flush() != nil {
+			return
+		}
+	}
+}
+
+func writeChange(w http.ResponseWriter, change changes.Change) {
+	data, _ := json.Marshal(change)
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", change.ID, change.Kind, data)
+}
+
+// DayCardHandler returns the card of a single day, the page uses it to refresh the day
+// after a change
+func DayCardHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	dayId, err := pathID(r, "day")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	respondInline(w, r, fmt.Sprintf("day-%d", dayId), "day", func() (any, error) {
+		return internal.GetDay(r.Context(), userId, dayId)
+	})
+}
+
+// EventCardHandler returns the card of a single event including its todos
+func EventCardHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	eventId, err := pathID(r, "event")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	respondInline(w, r, fmt.Sprintf("event-%d", eventId), "event", func() (any, error) {
+		return internal.GetEvent(r.Context(), userId, eventId)
+	})
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"net/http"
+)
+
+// NewDayHandler shows and handles the form to create a day with its working window
+func NewDayHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	page := newFormPage(r, "New day", "/tasks/days/new")
+	page.Values["start"] = "09:00"
+	page.Values["end"] = "17:00"
+
+	if r.Method == http.MethodPost {
+		page.readForm(r, "date", "start", "end")
+
+		day := internal.Day{}
+		day.Date = page.date("date")
+		day.StartOfDay = page.clock("start", day.Date)
+		day.EndOfDay = page.clock("end", day.Date)
+
+		if len(page.Errors) == 0 {
+			err := internal.AddDay(r.Context(), userId, &day)
+			if err == nil {
+				http.Redirect(w, r, "/tasks", http.StatusSeeOther)
+				return
+			}
+			if !page.mergeErrors(err) {
+				renderError(w, r, err)
+				return
+			}
+		}
+
+		renderPageStatus(w, r, http.StatusUnprocessableEntity, "day_form", page)
+		return
+	}
+
+	renderPage(w, r, "day_form", page)
+}
+
+// DeleteDayHandler deletes a day together with its events and todos
+func DeleteDayHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	dayId, err := pathID(r, "day")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = internal.DeleteDay(r.Context(), userId, dayId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/tasks", http.StatusSeeOther)
+}
@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DependenciesPage is the data of templates/dependencies.html and the answer for API
+// clients. It lists what an event or a todo waits for and what waits for it.
+type DependenciesPage struct {
+	Kind          internal.ItemKind `json:"kind"`
+	ID            int64             `json:"id"`
+	Name          string            `json:"name"`
+	Blocked       bool              `json:"blocked"`
+	Prerequisites []DependencyItem  `json:"prerequisites"`
+	Dependents    []DependencyItem  `json:"dependents"`
+	// Candidates can be picked as new prerequisites
+	Candidates []DependencyItem `json:"-"`
+	// Cycle names the items of the cycle a rejected prerequisite would have closed
+	Cycle []string `json:"-"`
+}
+
+// DependencyItem is an event or a todo on a dependencies page, Date is the date of its day
+type DependencyItem struct {
+	DependencyID int64     `json:"dependency_id,omitempty"`
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Date         time.Time `json:"date"`
+	Done         bool      `json:"done"`
+}
+
+// Path is the address of the dependencies page of the item
+func (p DependenciesPage) Path() string {
+	return fmt.Sprintf("/tasks/%ss/%d/dependencies", p.Kind, p.ID)
+}
+
+// dependencyItems returns every event or every todo of the days by id together with
+// their prerequisites
+func dependencyItems(days []internal.Day, kind internal.ItemKind, now time.Time) (map[int64]DependencyItem, map[int64][]internal.Prerequisite, []int64) {
+	items := map[int64]DependencyItem{}
+	prerequisites := map[int64][]internal.Prerequisite{}
+	var order []int64
+	add := func(item DependencyItem, p []internal.Prerequisite) {
+		items[item.ID] = item
+		prerequisites[item.ID] = p
+		order = append(order, item.ID)
+	}
+
+	for _, day := range days {
+		for _, event := range day.Events {
+			if kind == internal.EventItem {
+				add(DependencyItem{ID: event.ID, Name: event.Name, Date: day.Date, Done: event.Finished(now)}, event.Prerequisites)
+				continue
+			}
+			for _, todo := range event.TodoList {
+				name := fmt.Sprintf("%s (%s)", todo.Name, event.Name)
+				add(DependencyItem{ID: todo.ID, Name: name, Date: day.Date, Done: todo.Done}, todo.Prerequisites)
+			}
+		}
+	}
+	return items, prerequisites, order
+}
+
+// newDependenciesPage collects the prerequisites, dependents and candidates of an item
+func newDependenciesPage(days []internal.Day, kind internal.ItemKind, id int64, now time.Time) (DependenciesPage, error) {
+	items, prerequisites, order := dependencyItems(days, kind, now)
+	item, ok := items[id]
+	if !ok {
+		return DependenciesPage{}, internal.ErrNotFound
+	}
+
+	page := DependenciesPage{
+		Kind:          kind,
+		ID:            id,
+		Name:          item.Name,
+		Prerequisites: []DependencyItem{},
+		Dependents:    []DependencyItem{},
+	}
+	linked := map[int64]bool{id: true}
+	for _, p := range prerequisites[id] {
+		prerequisite := items[p.ID]
+		prerequisite.DependencyID = p.DependencyID
+		page.Prerequisites = append(page.Prerequisites, prerequisite)
+		page.Blocked = page.Blocked || !p.Done
+		linked[p.ID] = true
+	}
+	for _, other := range order {
+		for _, p := range prerequisites[other] {
+			if p.ID == id {
+				page.Dependents = append(page.Dependents, items[other])
+			}
+		}
+	}
+	for _, other := range order {
+		if !linked[other] {
+			page.Candidates = append(page.Candidates, items[other])
+		}
+	}
+
+	return page, nil
+}
+
+// EventDependenciesHandler shows the events an event waits for, POST adds one
+func EventDependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	dependencies(w, r, internal.EventItem, "event")
+}
+
+// TodoDependenciesHandler shows the todos a todo waits for, POST adds one
+func TodoDependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	dependencies(w, r, internal.TodoItem, "todo")
+}
+
+// dependencies shows and adds the prerequisites of the item named by the path variable.
+// A prerequisite that would close a cycle is rejected and the cycle is shown.
+func dependencies(w http.ResponseWriter, r *http.Request, kind internal.ItemKind, name string) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := pathID(r, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var cycle *internal.CycleError
+	if r.Method == http.MethodPost {
+		prerequisite, parseErr := strconv.ParseInt(r.PostFormValue("prerequisite"), 10, 64)
+		if parseErr != nil {
+			renderError(w, r, internal.ValidationErrors{"prerequisite": "Please choose what it depends on"})
+			return
+		}
+
+		err = internal.AddDependency(r.Context(), userId, kind, prerequisite, id)
+		if err != nil && (!errors.As(err, &cycle) || wantsJSON(r)) {
+			renderError(w, r, err)
+			return
+		}
+		if err == nil && !wantsJSON(r) {
+			http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+			return
+		}
+	}
+
+	days, err := internal.GetDays(r.Context(), userId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	loc := userPrefs(r).Location
+	page, err := newDependenciesPage(localizeDays(days, loc), kind, id, time.Now())
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	switch {
+	case wantsJSON(r):
+		writeJSON(w, http.StatusOK, page)
+	case cycle != nil:
+		page.Cycle = cycle.Cycle
+		renderPageStatus(w, r, http.StatusConflict, "dependencies", page)
+	default:
+		renderPage(w, r, "dependencies", page)
+	}
+}
+
+// DeleteEventDependencyHandler removes a prerequisite of an event
+func DeleteEventDependencyHandler(w http.ResponseWriter, r *http.Request) {
+	deleteDependency(w, r, internal.EventItem, "event")
+}
+
+// DeleteTodoDependencyHandler removes a prerequisite of a todo
+func DeleteTodoDependencyHandler(w http.ResponseWriter, r *http.Request) {
+	deleteDependency(w, r, internal.TodoItem, "todo")
+}
+
+func deleteDependency(w http.ResponseWriter, r *http.Request, kind internal.ItemKind, name string) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := pathID(r, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	dependencyId, err := pathID(r, "dependency")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = internal.DeleteDependency(r.Context(), userId, kind, id, dependencyId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, DependenciesPage{Kind: kind, ID: id}.Path(), http.StatusSeeOther)
+}
+
+// UnblockedPage is the data of templates/unblocked.html and the answer for API clients.
+// It lists the events and todos that can be worked on now, grouped by day.
+type UnblockedPage struct {
+	Days []UnblockedDay `json:"days"`
+}
+
+type UnblockedDay struct {
+	ID     int64            `json:"id"`
+	Date   time.Time        `json:"date"`
+	Events []UnblockedEvent `json:"events"`
+}
+
+type UnblockedEvent struct {
+	ID       int64           `json:"id"`
+	Name     string          `json:"name"`
+	Start    time.Time       `json:"start"`
+	Deadline time.Time       `json:"deadline"`
+	Todos    []UnblockedTodo `json:"todos"`
+}
+
+type UnblockedTodo struct {
+	ID       int64     `json:"id"`
+	Name     string    `json:"name"`
+	Deadline time.Time `json:"deadline"`
+}
+
+// UnblockedHandler lists what the user can work on now: the open events and todos that
+// don't wait for anything that isn't done yet
+func UnblockedHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	days, err := internal.GetUnblocked(r.Context(), userId, time.Now())
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	page := UnblockedPage{Days: []UnblockedDay{}}
+	for _, day := range localizeDays(days, userPrefs(r).Location) {
+		unblockedDay := UnblockedDay{ID: day.ID, Date: day.Date, Events: []UnblockedEvent{}}
+		for _, event := range day.Events {
+			unblockedEvent := UnblockedEvent{ID: event.ID, Name: event.Name, Start: event.Start, Deadline: event.Deadline, Todos: []UnblockedTodo{}}
+			for _, todo := range event.TodoList {
+				unblockedEvent.Todos = append(unblockedEvent.Todos, UnblockedTodo{todo.ID, todo.Name, todo.Deadline})
+			}
+			unblockedDay.Events = append(unblockedDay.Events, unblockedEvent)
+		}
+		page.Days = append(page.Days, unblockedDay)
+	}
+
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, page)
+		return
+	}
+	renderPage(w, r, "unblocked", page)
+}
+
+// cycleOf returns the cycle of a rejected dependency, it is nil for other errors
+func cycleOf(err error) []string {
+	var cycle *internal.CycleError
+	if errors.As(err, &cycle) {
+		return cycle.Cycle
+	}
+	return nil
+}
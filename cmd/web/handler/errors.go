@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/locale"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"github.com/Shu-AFK/TaskWeave/conflict"
+	"net/http"
+	"strings"
+)
+
+// ErrorPage is the data of templates/error.html
+type ErrorPage struct {
+	Status    int
+	Title     string
+	Message   string
+	RequestID string
+}
+
+type errorBody struct {
+	Kind      string              `json:"kind"`
+	Message   string              `json:"message"`
+	Fields    map[string]string   `json:"fields,omitempty"`
+	Conflicts []conflict.Conflict `json:"conflicts,omitempty"`
+	Cycle     []string            `json:"cycle,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+}
+
+// wantsJSON reports whether the client asked for JSON instead of a page
+func wantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
+
+// renderError answers with an error page, a JSON error or plain text for fragment
+// requests. Only the user facing message is sent, the cause of internal errors is
+// logged together with the request id.
+func renderError(w http.ResponseWriter, r *http.Request, err error) {
+	kind := apperr.KindOf(err)
+	status := kind.Status()
+	l := userPrefs(r).Locale
+	message := l.T(apperr.Message(err))
+	fields := translateFields(l, err)
+	if fields != nil {
+		message = fields.Error()
+	}
+	requestID := logging.RequestID(r.Context())
+
+	logger := logging.FromContext(r.Context())
+	if kind == apperr.Internal {
+		logger.Error("Request failed", "error", err)
+	} else {
+		logger.Debug("Request rejected", "kind", kind.String(), "error", err)
+	}
+
+	switch {
+	case wantsJSON(r):
+		body := errorBody{Kind: kind.String(), Message: message, Fields: fields, RequestID: requestID}
+		var conflicts *internal.ConflictError
+		if errors.As(err, &conflicts) {
+			body.Conflicts = localizeConflicts(conflicts.Conflicts, userPrefs(r).Location)
+		}
+		body.Cycle = cycleOf(err)
+		writeJSON(w, status, map[string]errorBody{"error": body})
+	case isFragmentRequest(r):
+		http.Error(w, message, status)
+	default:
+		renderPageStatus(w, r, status, "error", ErrorPage{
+			Status:    status,
+			Title:     l.T(http.StatusText(status)),
+			Message:   message,
+			RequestID: requestID,
+		})
+	}
+}
+
+// translateFields returns the translated messages of validation errors, it is nil for
+// other errors
+func translateFields(l *locale.Locale, err error) internal.ValidationErrors {
+	var errs internal.ValidationErrors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+
+	translated := internal.ValidationErrors{}
+	for field, msg := range errs {
+		translated[field] = l.T(msg)
+	}
+	return translated
+}
+
+// RenderError is renderError for the middleware, e.g. to answer after a panic
+func RenderError(w http.ResponseWriter, r *http.Request, err error) {
+	renderError(w, r, err)
+}
+
+// NotFoundHandler shows the error page for routes that don't exist
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	renderError(w, r, apperr.New(apperr.NotFound, "This page does not exist"))
+}
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"github.com/Shu-AFK/TaskWeave/conflict"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var eventFields = []string{"name", "start", "end", "duration", "deadline", "urgent", "important", "allow_overlap", "repeat", "scope"}
+
+// readEvent parses the event form into event. The start and end time are placed on the
+// date of the day, if both are empty the duration is used instead.
+func readEvent(page *FormPage, r *http.Request, event *internal.Event) {
+	page.readForm(r, eventFields...)
+
+	start, end := event.Start, event.End
+	event.Name = page.Values["name"]
+	event.Deadline = page.dateTime("deadline")
+	event.Priority = page.priority()
+	event.AllowOverlap = page.Values["allow_overlap"] != ""
+	event.Start = page.clock("start", page.Day.Date)
+	event.End = page.clock("end", page.Day.Date)
+
+	// Changing the times of a scheduled event pins it to them
+	if !event.Start.Equal(start) || !event.End.Equal(end) {
+		event.Scheduled = false
+	}
+
+	if event.Start.IsZero() && event.End.IsZero() {
+		event.Duration = page.minutes("duration")
+	} else {
+		event.Duration = event.End.Sub(event.Start)
+	}
+}
+
+func fillEventValues(page *FormPage, event internal.Event) {
+	page.Values["name"] = event.Name
+	page.Values["start"] = page.formatTime(event.Start, formClockLayout)
+	page.Values["end"] = page.formatTime(event.End, formClockLayout)
+	page.Values["deadline"] = page.formatTime(event.Deadline, formDateTimeLayout)
+	page.fillPriority(event.Priority)
+	if event.AllowOverlap {
+		page.Values["allow_overlap"] = "on"
+	}
+	if event.Duration > 0 {
+		page.Values["duration"] = strconv.Itoa(int(event.Duration.Minutes()))
+	}
+}
+
+// SavedEvent is the answer for API clients that create or change an event. SeriesID is
+// set for occurrences of a series, GET /tasks/series/{id} lists the others.
+type SavedEvent struct {
+	ID           int64               `json:"id"`
+	DayID        int64               `json:"day_id"`
+	Name         string              `json:"name"`
+	Start        time.Time           `json:"start"`
+	End          time.Time           `json:"end"`
+	Minutes      int                 `json:"minutes"`
+	Deadline     time.Time           `json:"deadline"`
+	Urgent       bool                `json:"urgent"`
+	Important    bool                `json:"important"`
+	AllowOverlap bool                `json:"allow_overlap"`
+	SeriesID     int64               `json:"series_id,omitempty"`
+	Conflicts    []conflict.Conflict `json:"conflicts"`
+}
+
+// writeSavedEvent answers API clients with the event as it was stored, including the
+// conflicts that were allowed
+func writeSavedEvent(w http.ResponseWriter, r *http.Request, userId int, eventId int64, status int) {
+	event, err := internal.GetEvent(r.Context(), userId, eventId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	loc := userPrefs(r).Location
+	event = localizeEvent(event, loc)
+	conflicts := localizeConflicts(event.Conflicts, loc)
+	if conflicts == nil {
+		conflicts = []conflict.Conflict{}
+	}
+	writeJSON(w, status, SavedEvent{
+		ID:           event.ID,
+		DayID:        event.DayID,
+		Name:         event.Name,
+		Start:        event.Start,
+		End:          event.End,
+		Minutes:      int(event.Duration.Minutes()),
+		Deadline:     event.Deadline,
+		Urgent:       event.Priority.Urgent,
+		Important:    event.Priority.Important,
+		AllowOverlap: event.AllowOverlap,
+		SeriesID:     event.SeriesID,
+		Conflicts:    conflicts,
+	})
+}
+
+// NewEventHandler shows and handles the form to add an event to a day
+func NewEventHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	dayId, err := pathID(r, "day")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	day, err := internal.GetDay(r.Context(), userId, dayId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	page := newFormPage(r, "New event", fmt.Sprintf("/tasks/days/%d/events/new", dayId))
+	page.Day = localizeDay(day, page.loc)
+
+	if r.Method == http.MethodPost {
+		event := internal.Event{DayID: dayId}
+		readEvent(page, r, &event)
+		rule := page.rule("repeat")
+
+		if len(page.Errors) == 0 {
+			if rule != nil {
+				err = internal.AddSeries(r.Context(), userId, &event, *rule, page.loc)
+			} else {
+				err = internal.AddEvent(r.Context(), userId, &event)
+			}
+			if err == nil && wantsJSON(r) {
+				writeSavedEvent(w, r, userId, event.ID, http.StatusCreated)
+				return
+			}
+			if err == nil {
+				http.Redirect(w, r, "/tasks", http.StatusSeeOther)
+				return
+			}
+			// API clients get the conflicts and validation errors as JSON
+			if wantsJSON(r) || !page.mergeErrors(err) {
+				renderError(w, r, err)
+				return
+			}
+		}
+		if wantsJSON(r) {
+			renderError(w, r, page.Errors)
+			return
+		}
+
+		renderPageStatus(w, r, page.status(), "event_form", page)
+		return
+	}
+
+	renderPage(w, r, "event_form", page)
+}
+
+// EditEventHandler shows and handles the form to change an existing event
+func EditEventHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	eventId, err := pathID(r, "event")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	event, err := internal.GetEvent(r.Context(), userId, eventId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	day, err := internal.GetDay(r.Context(), userId, event.DayID)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	page := newFormPage(r, "Edit event", fmt.Sprintf("/tasks/events/%d/edit", eventId))
+	page.Day = localizeDay(day, page.loc)
+	page.Event = event
+	fillEventValues(page, event)
+	if event.SeriesID != 0 {
+		series, err := internal.GetSeries(r.Context(), userId, event.SeriesID)
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+		page.Series = &series
+		page.Values["repeat"] = series.Rule.String()
+		page.Values["scope"] = string(internal.ThisOccurrence)
+	}
+
+	if r.Method == http.MethodPost {
+		readEvent(page, r, &event)
+		rule := page.rule("repeat")
+		scope := page.scope("scope")
+		// An unchanged rule of a series only changes the event
+		if rule != nil && page.Series != nil && rule.String() == page.Series.Rule.String() {
+			rule = nil
+		}
+
+		if len(page.Errors) == 0 {
+			err = internal.UpdateOccurrences(r.Context(), userId, event, scope, rule, page.loc)
+			if err == nil && wantsJSON(r) {
+				writeSavedEvent(w, r, userId, event.ID, http.StatusOK)
+				return
+			}
+			if err == nil {
+				http.Redirect(w, r, "/tasks", http.StatusSeeOther)
+				return
+			}
+			// API clients get the conflicts and validation errors as JSON
+			if wantsJSON(r) || !page.mergeErrors(err) {
+				renderError(w, r, err)
+				return
+			}
+		}
+		if wantsJSON(r) {
+			renderError(w, r, page.Errors)
+			return
+		}
+
+		renderPageStatus(w, r, page.status(), "event_form", page)
+		return
+	}
+
+	renderPage(w, r, "event_form", page)
+}
+
+// DeleteEventHandler deletes an event together with its todos. For an occurrence of a
+// series the scope field tells whether the following or all occurrences go as well.
+func DeleteEventHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	eventId, err := pathID(r, "event")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	scope, ok := internal.ParseScope(r.PostFormValue("scope"))
+	if !ok {
+		renderError(w, r, internal.ValidationErrors{"scope": "Please choose which occurrences to change"})
+		return
+	}
+
+	err = internal.DeleteOccurrences(r.Context(), userId, eventId, scope)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/tasks", http.StatusSeeOther)
+}
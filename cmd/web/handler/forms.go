@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"errors"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"github.com/Shu-AFK/TaskWeave/conflict"
+	"github.com/Shu-AFK/TaskWeave/priority"
+	"github.com/Shu-AFK/TaskWeave/recur"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	formDateLayout     = "2006-01-02"
+	formClockLayout    = "15:04"
+	formDateTimeLayout = "2006-01-02T15:04"
+)
+
+// FormPage holds the data passed to the create and edit templates. Values contains the
+// raw input so that it can be shown again next to the validation errors.
+type FormPage struct {
+	Title  string
+	Action string
+	Values map[string]string
+	Errors internal.ValidationErrors
+	// Conflicts kept the event from being saved, they are shown above the form
+	Conflicts []conflict.Conflict
+	Day       internal.Day
+	Event     internal.Event
+	// Series is set when the event of the page is an occurrence of a series
+	Series *internal.Series
+	// Repeatable offers to add a new todo to the following occurrences of its event as well
+	Repeatable bool
+
+	// loc is the timezone of the user, times are read and shown in it
+	loc *time.Location
+}
+
+func newFormPage(r *http.Request, title string, action string) *FormPage {
+	return &FormPage{
+		Title:  title,
+		Action: action,
+		Values: map[string]string{},
+		Errors: internal.ValidationErrors{},
+		loc:    userPrefs(r).Location,
+	}
+}
+
+// readForm copies the given form fields into the page values
+func (p *FormPage) readForm(r *http.Request, fields ...string) {
+	for _, field := range fields {
+		p.Values[field] = strings.TrimSpace(r.PostFormValue(field))
+	}
+}
+
+// date parses a date field, empty fields result in the zero time
+func (p *FormPage) date(field string) time.Time {
+	if p.Values[field] == "" {
+		return time.Time{}
+	}
+
+	t, err := time.ParseInLocation(formDateLayout, p.Values[field], p.loc)
+	if err != nil {
+		p.Errors[field] = "Invalid date format! Please try again."
+		return time.Time{}
+	}
+	return t
+}
+
+// clock parses a time of day field and places it on the given date
+func (p *FormPage) clock(field string, date time.Time) time.Time {
+	if p.Values[field] == "" || date.IsZero() {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(formClockLayout, p.Values[field])
+	if err != nil {
+		p.Errors[field] = "Invalid time format! Please try again."
+		return time.Time{}
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, date.Location())
+}
+
+// dateTime parses a date and time field, empty fields result in the zero time
+func (p *FormPage) dateTime(field string) time.Time {
+	if p.Values[field] == "" {
+		return time.Time{}
+	}
+
+	t, err := time.ParseInLocation(formDateTimeLayout, p.Values[field], p.loc)
+	if err != nil {
+		p.Errors[field] = "Invalid date format! Please try again."
+		return time.Time{}
+	}
+	return t
+}
+
+// minutes parses a duration given in minutes
+func (p *FormPage) minutes(field string) time.Duration {
+	if p.Values[field] == "" {
+		return 0
+	}
+
+	m, err := strconv.Atoi(p.Values[field])
+	if err != nil || m < 0 {
+		p.Errors[field] = "Please enter a positive number of minutes"
+		return 0
+	}
+	return time.Duration(m) * time.Minute
+}
+
+// percent parses a whole percentage, empty fields result in 0
+func (p *FormPage) percent(field string) int {
+	if p.Values[field] == "" {
+		return 0
+	}
+
+	n, err := strconv.Atoi(p.Values[field])
+	if err != nil || n < 1 || n > 100 {
+		p.Errors[field] = "Please enter a number between 1 and 100"
+		return 0
+	}
+	return n
+}
+
+// rule parses a recurrence rule, empty fields result in nil
+func (p *FormPage) rule(field string) *recur.Rule {
+	if p.Values[field] == "" {
+		return nil
+	}
+
+	rule, err := recur.Parse(p.Values[field], p.loc)
+	if err != nil {
+		p.Errors[field] = "Please enter a rule like FREQ=WEEKLY;BYDAY=MO, see RFC 5545"
+		return nil
+	}
+	return &rule
+}
+
+// scope reads which occurrences of a series a change applies to
+func (p *FormPage) scope(field string) internal.Scope {
+	scope, ok := internal.ParseScope(p.Values[field])
+	if !ok {
+		p.Errors[field] = "Please choose which occurrences to change"
+	}
+	return scope
+}
+
+// priority reads the urgent and important checkboxes
+func (p *FormPage) priority() priority.Priority {
+	return priority.Priority{Urgent: p.Values["urgent"] != "", Important: p.Values["important"] != ""}
+}
+
+// fillPriority checks the urgent and important checkboxes
+func (p *FormPage) fillPriority(prio priority.Priority) {
+	if prio.Urgent {
+		p.Values["urgent"] = "on"
+	}
+	if prio.Important {
+		p.Values["important"] = "on"
+	}
+}
+
+// mergeErrors adds validation errors and conflicts of the store to the page. It returns
+// false if err is neither.
+func (p *FormPage) mergeErrors(err error) bool {
+	var conflicts *internal.ConflictError
+	if errors.As(err, &conflicts) {
+		p.Conflicts = localizeConflicts(conflicts.Conflicts, p.loc)
+		return true
+	}
+
+	var errs internal.ValidationErrors
+	if !errors.As(err, &errs) {
+		return false
+	}
+
+	for field, msg := range errs {
+		p.Errors[field] = msg
+	}
+	return true
+}
+
+// status is the status code of a form that is shown again because it couldn't be saved
+func (p *FormPage) status() int {
+	if len(p.Conflicts) > 0 {
+		return http.StatusConflict
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// formatTime formats t in the user's timezone to fill a form field
+func (p *FormPage) formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(p.loc).Format(layout)
+}
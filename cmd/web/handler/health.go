@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/worker"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// WorkerStatus reports the background workers, it is set at startup
+var WorkerStatus func() []worker.Status
+
+var shuttingDown atomic.Bool
+
+// shutdownStarted is closed by SetShuttingDown, it ends long running responses which
+// would otherwise keep the server from shutting down
+var (
+	shutdownStarted = make(chan struct{})
+	shutdownOnce    sync.Once
+)
+
+// SetShuttingDown makes /readyz fail so that no new traffic is sent to the server
+func SetShuttingDown() {
+	shuttingDown.Store(true)
+	shutdownOnce.Do(func() { close(shutdownStarted) })
+}
+
+type check struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// newCheck returns the result of the check name. The cause of a failure is only logged,
+// /readyz is public and only tells that the check is unavailable.
+func newCheck(r *http.Request, name string, err error) check {
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("Readiness check failed", "check", name, "error", err)
+		return check{OK: false, Error: "unavailable"}
+	}
+	return check{OK: true}
+}
+
+type readiness struct {
+	Status  string           `json:"status"`
+	Checks  map[string]check `json:"checks"`
+	Workers []worker.Status  `json:"workers"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// HealthzHandler reports that the process is alive
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadyzHandler reports whether the server can handle requests. It checks the database,
+// the schema version, the templates and the background workers.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	res := readiness{Status: "ready", Checks: map[string]check{}, Workers: []worker.Status{}}
+
+	var shutdownErr error
+	if shuttingDown.Load() {
+		shutdownErr = errors.New("server is shutting down")
+	}
+	res.Checks["shutdown"] = newCheck(r, "shutdown", shutdownErr)
+	res.Checks["database"] = newCheck(r, "database", internal.CheckSchema(ctx))
+
+	var templatesErr error
+	if templates == nil {
+		templatesErr = errors.New("templates are not loaded")
+	}
+	res.Checks["templates"] = newCheck(r, "templates", templatesErr)
+
+	var workersErr error
+	if WorkerStatus != nil {
+		res.Workers = WorkerStatus()
+		for i, status := range res.Workers {
+			if !status.Running {
+				workersErr = fmt.Errorf("worker %s is not running", status.Name)
+			}
+			if status.LastError != "" {
+				logging.FromContext(r.Context()).Debug("Worker failed on its last run", "worker", status.Name, "error", status.LastError)
+				res.Workers[i].LastError = "failed"
+			}
+		}
+	}
+	res.Checks["workers"] = newCheck(r, "workers", workersErr)
+
+	status := http.StatusOK
+	for _, c := range res.Checks {
+		if !c.OK {
+			res.Status = "unready"
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	writeJSON(w, status, res)
+}
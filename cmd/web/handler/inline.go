@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"net/http"
+)
+
+// The handlers in this file change a single field of a todo or event. Requests sent by
+// static/tasks.js carry the HX-Request header and get the re-rendered card back as an
+// HTML fragment, plain form posts are redirected back to the tasks page.
+
+func isFragmentRequest(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
+
+// respondInline either renders the fragment returned by load or redirects to anchor
+func respondInline(w http.ResponseWriter, r *http.Request, anchor string, name string, load func() (any, error)) {
+	if !isFragmentRequest(r) {
+		http.Redirect(w, r, "/tasks#"+anchor, http.StatusSeeOther)
+		return
+	}
+
+	data, err := load()
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	renderFragment(w, r, name, data)
+}
+
+// handleInlineError reports validation errors as plain text for fragment requests and
+// sends plain form posts to the full edit form
+func handleInlineError(w http.ResponseWriter, r *http.Request, editURL string, err error) {
+	var errs internal.ValidationErrors
+	if !errors.As(err, &errs) {
+		renderError(w, r, err)
+		return
+	}
+
+	if isFragmentRequest(r) {
+		http.Error(w, userPrefs(r).Locale.T(errs["name"]), http.StatusUnprocessableEntity)
+		return
+	}
+	http.Redirect(w, r, editURL, http.StatusSeeOther)
+}
+
+// moveDelta converts the direction form value into a position delta
+func moveDelta(r *http.Request) (int, bool) {
+	switch r.PostFormValue("direction") {
+	case "up":
+		return -1, true
+	case "down":
+		return 1, true
+	}
+	return 0, false
+}
+
+// ToggleTodoHandler marks a todo as done or not done
+func ToggleTodoHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	todoId, err := pathID(r, "todo")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	todo, err := internal.ToggleTodo(r.Context(), userId, todoId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	respondInline(w, r, fmt.Sprintf("todo-%d", todoId), "todo", func() (any, error) {
+		return todo, nil
+	})
+}
+
+// ToggleEventHandler marks an event as done or not done
+func ToggleEventHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	eventId, err := pathID(r, "event")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	event, err := internal.ToggleEvent(r.Context(), userId, eventId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	respondInline(w, r, fmt.Sprintf("event-%d", eventId), "event", func() (any, error) {
+		return event, nil
+	})
+}
+
+// RenameTodoHandler changes the name of a todo
+func RenameTodoHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	todoId, err := pathID(r, "todo")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = internal.RenameTodo(r.Context(), userId, todoId, r.PostFormValue("name"))
+	if err != nil {
+		handleInlineError(w, r, fmt.Sprintf("/tasks/todos/%d/edit", todoId), err)
+		return
+	}
+
+	respondInline(w, r, fmt.Sprintf("todo-%d", todoId), "todo", func() (any, error) {
+		return internal.GetTodo(r.Context(), userId, todoId)
+	})
+}
+
+// MoveTodoHandler moves a todo one place up or down within its event
+func MoveTodoHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	todoId, err := pathID(r, "todo")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	delta, ok := moveDelta(r)
+	if !ok {
+		renderError(w, r, apperr.New(apperr.Validation, "The direction has to be up or down"))
+		return
+	}
+
+	eventId, err := internal.MoveTodo(r.Context(), userId, todoId, delta)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	respondInline(w, r, fmt.Sprintf("todo-%d", todoId), "event", func() (any, error) {
+		return internal.GetEvent(r.Context(), userId, eventId)
+	})
+}
+
+// RenameEventHandler changes the title of an event
+func RenameEventHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	eventId, err := pathID(r, "event")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = internal.RenameEvent(r.Context(), userId, eventId, r.PostFormValue("name"))
+	if err != nil {
+		handleInlineError(w, r, fmt.Sprintf("/tasks/events/%d/edit", eventId), err)
+		return
+	}
+
+	respondInline(w, r, fmt.Sprintf("event-%d", eventId), "event", func() (any, error) {
+		return internal.GetEvent(r.Context(), userId, eventId)
+	})
+}
+
+// MoveEventHandler moves an event one place up or down within its day
+func MoveEventHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	eventId, err := pathID(r, "event")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	delta, ok := moveDelta(r)
+	if !ok {
+		renderError(w, r, apperr.New(apperr.Validation, "The direction has to be up or down"))
+		return
+	}
+
+	dayId, err := internal.MoveEvent(r.Context(), userId, eventId, delta)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	respondInline(w, r, fmt.Sprintf("event-%d", eventId), "day", func() (any, error) {
+		return internal.GetDay(r.Context(), userId, dayId)
+	})
+}
@@ -3,9 +3,11 @@
 package handler
 
 import (
+	"errors"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
 	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
-	"html/template"
-	"log"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/metrics"
 	"net/http"
 )
 
@@ -14,45 +16,64 @@ type Credentials struct {
 	Password string
 }
 
+// AuthPage is the data of the login and signup pages. Error is shown above the form.
+type AuthPage struct {
+	Username string
+	Email    string
+	Error    string
+}
+
+// renderAuthError shows errors the user can fix on the form itself, everything else
+// gets the error page
+func renderAuthError(w http.ResponseWriter, r *http.Request, page string, data AuthPage, err error) {
+	kind := apperr.KindOf(err)
+	if kind == apperr.Internal || wantsJSON(r) {
+		renderError(w, r, err)
+		return
+	}
+
+	data.Error = userPrefs(r).Locale.T(apperr.Message(err))
+	renderPageStatus(w, r, kind.Status(), page, data)
+}
+
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	creds := Credentials{}
+	logger := logging.FromContext(r.Context())
 	if r.Method == http.MethodPost {
 		creds.Username = r.PostFormValue("username")
 		creds.Password = r.PostFormValue("password")
 
 		// Do authentication
-		err := internal.ValidateUser(creds.Username, creds.Password)
+		err := internal.ValidateUser(r.Context(), creds.Username, creds.Password)
 		if err != nil {
-			http.Error(w, "Invalid username or password", http.StatusUnauthorized)
-			log.Println(err.Error())
+			if errors.Is(err, internal.ErrInvalidCredentials) {
+				metrics.Logins.Inc("failure")
+				logger.Info("Login failed", "username", creds.Username)
+			}
+			renderAuthError(w, r, "login", AuthPage{Username: creds.Username}, err)
 			return
 		}
 
-		// TODO: Fix redirect
 		correct, err := internal.CheckIfSessionIsCorrect(creds.Username, r)
 		if err != nil {
-			log.Println(err)
+			renderError(w, r, err)
 			return
 		}
 
 		if !correct {
-			err = internal.SetSessionCookie(w, creds.Username)
+			err = internal.SetSessionCookie(r.Context(), w, creds.Username)
 			if err != nil {
-				log.Println(err)
+				renderError(w, r, err)
 				return
 			}
 		}
 
-		log.Printf("Login success for %s\n", creds.Username)
-		http.Redirect(w, r, "/tasks", http.StatusTemporaryRedirect)
-	}
-
-	// If not a POST request
-	tmpl, err := template.ParseFiles("templates/login.html")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		metrics.Logins.Inc("success")
+		logger.Info("Login success", "username", creds.Username)
+		http.Redirect(w, r, "/tasks", http.StatusSeeOther)
 		return
 	}
 
-	tmpl.Execute(w, nil)
+	// If not a POST request
+	renderPage(w, r, "login", AuthPage{})
 }
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"context"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/locale"
+	"github.com/Shu-AFK/TaskWeave/conflict"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+// Prefs decide the language of the pages, how times are shown to the user and how the
+// times they enter are read
+type Prefs struct {
+	Location *time.Location
+	Locale   *locale.Locale
+}
+
+func defaultPrefs() Prefs {
+	return Prefs{Location: time.Local, Locale: locale.Default}
+}
+
+// browserPrefs are the defaults in the language the browser asks for
+func browserPrefs(r *http.Request) Prefs {
+	prefs := defaultPrefs()
+	prefs.Locale = locale.Negotiate(r.Header.Get("Accept-Language"))
+	return prefs
+}
+
+type prefsKey struct{}
+
+// UserPrefs reserves room in the request context for the preferences of the logged-in
+// user, currentUser fills them in. Pages without a user get the server's timezone and
+// the language the browser asks for.
+func UserPrefs(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefs := browserPrefs(r)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), prefsKey{}, &prefs)))
+	})
+}
+
+func userPrefs(r *http.Request) Prefs {
+	if prefs, ok := r.Context().Value(prefsKey{}).(*Prefs); ok {
+		return *prefs
+	}
+	// Requests that didn't match a route never passed UserPrefs
+	return browserPrefs(r)
+}
+
+func setUserPrefs(r *http.Request, settings internal.UserSettings) {
+	prefs, ok := r.Context().Value(prefsKey{}).(*Prefs)
+	if !ok {
+		return
+	}
+
+	// A language picked by the user wins over the one of the browser
+	prefs.Location = settings.Location(time.Local)
+	prefs.Locale = browserPrefs(r).Locale
+	if l, ok := locale.Get(settings.Locale); ok {
+		prefs.Locale = l
+	}
+}
+
+// funcs are the template functions that depend on the user. Calendar dates like the date
+// of a day are shown as they are, points in time are converted into the user's timezone.
+func (p Prefs) funcs(now time.Time) template.FuncMap {
+	local := func(t time.Time) time.Time {
+		return t.In(p.Location)
+	}
+
+	return template.FuncMap{
+		// t translates a text of the page, e.g. {{t "Deadline: %s" (dateTime .Deadline)}}
+		"t":            p.Locale.T,
+		"lang":         func() string { return p.Locale.Tag },
+		"date":         p.Locale.Date,
+		"longDate":     p.Locale.LongDate,
+		"shortWeekday": p.Locale.ShortWeekday,
+		"clock":        func(t time.Time) string { return p.Locale.Clock(local(t)) },
+		"dateTime":     func(t time.Time) string { return p.Locale.DateTime(local(t)) },
+		"duration":     p.Locale.Duration,
+		// relTime describes t from now, e.g. "in 3h"
+		"relTime": func(t time.Time) string {
+			if t.IsZero() {
+				return ""
+			}
+			return p.Locale.Relative(t, now)
+		},
+		// due describes a deadline from now, e.g. "overdue by 2 days"
+		"due": func(t time.Time) string {
+			if t.IsZero() {
+				return ""
+			}
+			return p.Locale.Due(t, now)
+		},
+	}
+}
+
+// onDate returns midnight of the calendar date of t in loc
+func onDate(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// localizeDay moves all times of a day into loc. The date stays the same calendar date,
+// so that times entered for the day are placed on it in the user's timezone.
+func localizeDay(day internal.Day, loc *time.Location) internal.Day {
+	day.Date = onDate(day.Date, loc)
+	day.StartOfDay = day.StartOfDay.In(loc)
+	day.EndOfDay = day.EndOfDay.In(loc)
+
+	events := make([]internal.Event, len(day.Events))
+	for i, event := range day.Events {
+		events[i] = localizeEvent(event, loc)
+	}
+	day.Events = events
+
+	return day
+}
+
+func localizeEvent(event internal.Event, loc *time.Location) internal.Event {
+	event.Start = event.Start.In(loc)
+	event.End = event.End.In(loc)
+	event.Deadline = event.Deadline.In(loc)
+	event.Occurrence = event.Occurrence.In(loc)
+	event.Conflicts = localizeConflicts(event.Conflicts, loc)
+
+	todos := make([]internal.Todo, len(event.TodoList))
+	for i, todo := range event.TodoList {
+		todo.Deadline = todo.Deadline.In(loc)
+		todos[i] = todo
+	}
+	event.TodoList = todos
+
+	return event
+}
+
+func localizeConflicts(conflicts []conflict.Conflict, loc *time.Location) []conflict.Conflict {
+	var localized []conflict.Conflict
+	for _, c := range conflicts {
+		c.Start = c.Start.In(loc)
+		c.End = c.End.In(loc)
+		if c.With != nil {
+			with := *c.With
+			with.Start = with.Start.In(loc)
+			with.End = with.End.In(loc)
+			c.With = &with
+		}
+		localized = append(localized, c)
+	}
+	return localized
+}
+
+func localizeDays(days []internal.Day, loc *time.Location) []internal.Day {
+	localized := make([]internal.Day, len(days))
+	for i, day := range days {
+		localized[i] = localizeDay(day, loc)
+	}
+	return localized
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"github.com/Shu-AFK/TaskWeave/priority"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ListOptions filter and order the list view, they come from the priority and sort
+// query parameters
+type ListOptions struct {
+	// Quadrant only shows work of this quadrant, 0 shows everything
+	Quadrant   priority.Quadrant
+	ByPriority bool
+}
+
+func readListOptions(r *http.Request) (ListOptions, error) {
+	var opts ListOptions
+	query := r.URL.Query()
+
+	if code := query.Get("priority"); code != "" {
+		q, ok := priority.Parse(code)
+		if !ok {
+			return opts, apperr.New(apperr.Validation, "The priority has to be one of do, schedule, delegate or eliminate")
+		}
+		opts.Quadrant = q
+	}
+
+	switch query.Get("sort") {
+	case "", "position":
+	case "priority":
+		opts.ByPriority = true
+	default:
+		return opts, apperr.New(apperr.Validation, "The list can only be sorted by position or priority")
+	}
+
+	return opts, nil
+}
+
+// Filtered reports whether some work is hidden
+func (o ListOptions) Filtered() bool {
+	return o.Quadrant != 0
+}
+
+// apply filters and sorts the events and todos of the days. Filtering keeps the todos of
+// the quadrant and the events that are in it or hold such a todo, days without events
+// are left out.
+func (o ListOptions) apply(days []internal.Day) []internal.Day {
+	var arranged []internal.Day
+	for _, day := range days {
+		var events []internal.Event
+		for _, event := range day.Events {
+			var todos []internal.Todo
+			for _, todo := range event.TodoList {
+				if o.matches(todo.Priority) {
+					todos = append(todos, todo)
+				}
+			}
+			if !o.matches(event.Priority) && len(todos) == 0 {
+				continue
+			}
+
+			if o.ByPriority {
+				sort.SliceStable(todos, func(i, j int) bool {
+					return todos[i].Priority.Quadrant() > todos[j].Priority.Quadrant()
+				})
+			}
+			event.TodoList = todos
+			events = append(events, event)
+		}
+
+		if o.Filtered() && len(events) == 0 {
+			continue
+		}
+		if o.ByPriority {
+			sort.SliceStable(events, func(i, j int) bool {
+				return events[i].Priority.Quadrant() > events[j].Priority.Quadrant()
+			})
+		}
+		day.Events = events
+		arranged = append(arranged, day)
+	}
+	return arranged
+}
+
+func (o ListOptions) matches(p priority.Priority) bool {
+	return o.Quadrant == 0 || p.Quadrant() == o.Quadrant
+}
+
+// MatrixPage is the data of templates/matrix.html
+type MatrixPage struct {
+	Cells []MatrixCell
+}
+
+// MatrixCell holds the open work of one quadrant
+type MatrixCell struct {
+	Quadrant priority.Quadrant
+	Events   []internal.Event
+	Todos    []internal.Todo
+}
+
+// buildMatrix sorts the events from today on and all open todos into the quadrants
+func buildMatrix(days []internal.Day, today time.Time) MatrixPage {
+	cells := map[priority.Quadrant]*MatrixCell{}
+	var page MatrixPage
+	for _, q := range priority.Quadrants() {
+		page.Cells = append(page.Cells, MatrixCell{Quadrant: q})
+	}
+	for i := range page.Cells {
+		cells[page.Cells[i].Quadrant] = &page.Cells[i]
+	}
+
+	for _, day := range days {
+		past := day.Date.Before(today)
+		for _, event := range day.Events {
+			if !past {
+				cell := cells[event.Priority.Quadrant()]
+				cell.Events = append(cell.Events, event)
+			}
+
+			for _, todo := range event.TodoList {
+				if todo.Done {
+					continue
+				}
+				cell := cells[todo.Priority.Quadrant()]
+				cell.Todos = append(cell.Todos, todo)
+			}
+		}
+	}
+
+	return page
+}
+
+// renderMatrix shows the events and todos in the Eisenhower matrix
+func renderMatrix(w http.ResponseWriter, r *http.Request, days []internal.Day) {
+	prefs := userPrefs(r)
+	today := onDate(time.Now().In(prefs.Location), prefs.Location)
+	renderPage(w, r, "matrix", buildMatrix(localizeDays(days, prefs.Location), today))
+}
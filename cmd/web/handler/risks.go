@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"net/http"
+	"time"
+)
+
+// RisksPage is the data of templates/risks.html and of the at risk section of the tasks
+// page, and the answer for API clients
+type RisksPage struct {
+	Risks []AtRisk `json:"risks"`
+}
+
+// AtRisk is an event or a todo that is forecast to miss its deadline. Finish is null when
+// the planned days have no room for all of its work.
+type AtRisk struct {
+	Kind         internal.ItemKind `json:"kind"`
+	ID           int64             `json:"id"`
+	Name         string            `json:"name"`
+	DayID        int64             `json:"day_id"`
+	EventID      int64             `json:"event_id"`
+	Deadline     time.Time         `json:"deadline"`
+	Finish       *time.Time        `json:"finish"`
+	Late         time.Duration     `json:"-"`
+	LateMinutes  int               `json:"late_minutes"`
+	Short        time.Duration     `json:"-"`
+	ShortMinutes int               `json:"short_minutes"`
+}
+
+func newRisksPage(risks []internal.Risk, loc *time.Location) RisksPage {
+	page := RisksPage{Risks: []AtRisk{}}
+	for _, risk := range risks {
+		atRisk := AtRisk{
+			Kind:         risk.Kind,
+			ID:           risk.ID,
+			Name:         risk.Name,
+			DayID:        risk.DayID,
+			EventID:      risk.EventID,
+			Deadline:     risk.Deadline.In(loc),
+			Late:         risk.Late,
+			LateMinutes:  int(risk.Late.Minutes()),
+			Short:        risk.Short,
+			ShortMinutes: int(risk.Short.Minutes()),
+		}
+		if !risk.Unplanned() {
+			finish := risk.Finish.In(loc)
+			atRisk.Finish = &finish
+		}
+		page.Risks = append(page.Risks, atRisk)
+	}
+	return page
+}
+
+// RisksHandler lists the events and todos that are forecast to miss their deadline. The
+// tasks page loads the section again with it after every change.
+func RisksHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	risks, err := internal.GetRisks(r.Context(), userId, time.Now())
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	page := newRisksPage(risks, userPrefs(r).Location)
+	switch {
+	case wantsJSON(r):
+		writeJSON(w, http.StatusOK, page)
+	case isFragmentRequest(r):
+		renderFragment(w, r, "risks", page)
+	default:
+		renderPage(w, r, "risks", page)
+	}
+}
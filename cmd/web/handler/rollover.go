@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"github.com/Shu-AFK/TaskWeave/schedule"
+	"net/http"
+	"time"
+)
+
+// RolloverPage is the data of templates/rollover.html and the answer for API clients
+type RolloverPage struct {
+	Moved    []RolledEvent   `json:"moved"`
+	Missed   []MissedEvent   `json:"missed"`
+	Unplaced []UnplacedEvent `json:"unplaced"`
+}
+
+// RolledEvent moved from the day From to the day To, Rollovers counts this move as well
+type RolledEvent struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Rollovers int       `json:"rollovers"`
+}
+
+// MissedEvent is a fixed event that ended with open todos, Start is when it started
+type MissedEvent struct {
+	ID    int64     `json:"id"`
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`
+}
+
+func newRolloverPage(rollover internal.Rollover, loc *time.Location) RolloverPage {
+	page := RolloverPage{Moved: []RolledEvent{}, Missed: []MissedEvent{}, Unplaced: []UnplacedEvent{}}
+	days := localizeDays(rollover.Days, loc)
+	rollovers := map[int64]int{}
+	for _, day := range days {
+		for _, event := range day.Events {
+			rollovers[event.ID] = event.Rollovers
+		}
+	}
+
+	for _, move := range rollover.Plan.Moves {
+		page.Moved = append(page.Moved, RolledEvent{
+			ID:        move.Item.ID,
+			Name:      move.Item.Name,
+			From:      days[move.From].Date,
+			To:        days[move.To].Date,
+			Rollovers: rollovers[move.Item.ID] + 1,
+		})
+	}
+	for _, event := range rollover.Missed {
+		page.Missed = append(page.Missed, MissedEvent{event.ID, event.Name, event.Start.In(loc)})
+	}
+	// Only the stuck events were left behind by this rollover, the other unplaced ones were
+	// missed already or are on days that aren't over
+	reasons := map[int64]schedule.Reason{}
+	for _, u := range rollover.Plan.Unplaced {
+		reasons[u.Item.ID] = u.Reason
+	}
+	for _, event := range rollover.Stuck {
+		page.Unplaced = append(page.Unplaced, UnplacedEvent{event.ID, event.Name, reasons[event.ID]})
+	}
+	return page
+}
+
+// RolloverHandler moves the unfinished work of days that are over right away instead of
+// waiting for the rollover job
+func RolloverHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	rollover, err := internal.RollOver(r.Context(), userId, time.Now())
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	page := newRolloverPage(rollover, userPrefs(r).Location)
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, page)
+		return
+	}
+	renderPage(w, r, "rollover", page)
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"github.com/Shu-AFK/TaskWeave/schedule"
+	"net/http"
+	"time"
+)
+
+// SchedulePage is the data of templates/schedule.html and the answer for API clients
+type SchedulePage struct {
+	Placed   []ScheduledEvent `json:"placed"`
+	Unplaced []UnplacedEvent  `json:"unplaced"`
+}
+
+type ScheduledEvent struct {
+	ID    int64     `json:"id"`
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type UnplacedEvent struct {
+	ID     int64           `json:"id"`
+	Name   string          `json:"name"`
+	Reason schedule.Reason `json:"reason"`
+}
+
+func newSchedulePage(plan schedule.Plan, loc *time.Location) SchedulePage {
+	page := SchedulePage{Placed: []ScheduledEvent{}, Unplaced: []UnplacedEvent{}}
+	for _, p := range plan.Placed {
+		page.Placed = append(page.Placed, ScheduledEvent{p.Item.ID, p.Item.Name, p.Start.In(loc), p.End.In(loc)})
+	}
+	for _, u := range plan.Unplaced {
+		page.Unplaced = append(page.Unplaced, UnplacedEvent{u.Item.ID, u.Item.Name, u.Reason})
+	}
+	return page
+}
+
+// ScheduleHandler places the flexible events of the user into the free time of their
+// days and shows where they ended up and which didn't fit
+func ScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	plan, err := internal.ScheduleEvents(r.Context(), userId, time.Now())
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	page := newSchedulePage(plan, userPrefs(r).Location)
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, page)
+		return
+	}
+	renderPage(w, r, "schedule", page)
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"net/http"
+	"time"
+)
+
+// maxDateRange limits how many days a page lists at once
+const maxDateRange = 366
+
+// SeriesPage is the data of templates/series.html and the answer for API clients. It
+// lists the occurrences of a series in a range of days, the next four weeks by default.
+type SeriesPage struct {
+	ID      int64         `json:"id"`
+	Name    string        `json:"name"`
+	Rule    string        `json:"rule"`
+	Start   time.Time     `json:"start"`
+	Timed   bool          `json:"timed"`
+	Length  time.Duration `json:"-"`
+	Minutes int           `json:"minutes"`
+	ExDates []time.Time   `json:"exdates"`
+	// From and To are the first and the last date of the range
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	Occurrences []SeriesOccurrence `json:"occurrences"`
+}
+
+// SeriesOccurrence is an occurrence of a series. Occurrences on dates without a day have
+// no event yet, they get one once the day is added.
+type SeriesOccurrence struct {
+	Start   time.Time `json:"start"`
+	EventID int64     `json:"event_id,omitempty"`
+	DayID   int64     `json:"day_id,omitempty"`
+	Name    string    `json:"name"`
+	// Detached occurrences were changed on their own
+	Detached bool `json:"detached"`
+}
+
+// readDateRange reads the from and to dates of a page that lists a range of days. The
+// range starts at start by default and is length days long unless to is given.
+func readDateRange(r *http.Request, start time.Time, length int) (from, to time.Time, err error) {
+	from, to = start, start.AddDate(0, 0, length-1)
+	if value := r.FormValue("from"); value != "" {
+		from, err = time.ParseInLocation(formDateLayout, value, start.Location())
+		if err != nil {
+			return from, to, apperr.New(apperr.Validation, "Invalid date, expected YYYY-MM-DD")
+		}
+		to = from.AddDate(0, 0, length-1)
+	}
+	if value := r.FormValue("to"); value != "" {
+		to, err = time.ParseInLocation(formDateLayout, value, start.Location())
+		if err != nil {
+			return from, to, apperr.New(apperr.Validation, "Invalid date, expected YYYY-MM-DD")
+		}
+	}
+	if to.Before(from) {
+		return from, to, apperr.New(apperr.Validation, "The range has to end on or after its first day")
+	}
+	if to.After(from.AddDate(0, 0, maxDateRange)) {
+		return from, to, apperr.New(apperr.Validation, "The range can't be longer than a year")
+	}
+
+	return from, to, nil
+}
+
+// SeriesHandler lists the occurrences of a recurring event in a range of days. Only the
+// range is expanded, so series without an end can be browsed as far as wanted.
+func SeriesHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	seriesId, err := pathID(r, "series")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	loc := userPrefs(r).Location
+	from, to, err := readDateRange(r, onDate(time.Now().In(loc), loc), 28)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	series, occurrences, err := internal.SeriesOccurrences(r.Context(), userId, seriesId, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	page := SeriesPage{
+		ID:          series.ID,
+		Name:        series.Name,
+		Rule:        series.Rule.String(),
+		Start:       series.Start.In(loc),
+		Timed:       series.Timed,
+		Length:      series.Duration,
+		Minutes:     int(series.Duration.Minutes()),
+		ExDates:     []time.Time{},
+		From:        from,
+		To:          to,
+		Occurrences: []SeriesOccurrence{},
+	}
+	for _, ex := range series.ExDates {
+		page.ExDates = append(page.ExDates, ex.In(loc))
+	}
+	for _, o := range occurrences {
+		occurrence := SeriesOccurrence{Start: o.Start.In(loc), Name: series.Name}
+		if o.Event != nil {
+			occurrence.EventID = o.Event.ID
+			occurrence.DayID = o.Event.DayID
+			occurrence.Name = o.Event.Name
+			occurrence.Detached = o.Event.Detached
+		}
+		page.Occurrences = append(page.Occurrences, occurrence)
+	}
+
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, page)
+		return
+	}
+	renderPage(w, r, "series", page)
+}
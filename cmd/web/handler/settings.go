@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/locale"
+	"github.com/Shu-AFK/TaskWeave/schedule"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// commonTimezones are suggested in the settings form, any IANA name is accepted
+var commonTimezones = []string{
+	"UTC",
+	"Europe/London", "Europe/Berlin", "Europe/Paris", "Europe/Madrid", "Europe/Rome", "Europe/Warsaw",
+	"Europe/Helsinki", "Europe/Istanbul", "Europe/Moscow",
+	"America/New_York", "America/Chicago", "America/Denver", "America/Los_Angeles", "America/Sao_Paulo",
+	"Asia/Dubai", "Asia/Kolkata", "Asia/Shanghai", "Asia/Tokyo", "Asia/Singapore",
+	"Australia/Sydney", "Pacific/Auckland",
+}
+
+// SettingsPage is the data of templates/settings.html
+type SettingsPage struct {
+	*FormPage
+	Locales         []*locale.Locale
+	Timezones       []string
+	DefaultTimezone string
+	DefaultMaxLoad  int
+	Saved           bool
+}
+
+// SettingsHandler shows and stores the timezone, the language and the maximum load per
+// day of the user
+func SettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	page := SettingsPage{
+		FormPage:        newFormPage(r, "Settings", "/tasks/settings"),
+		Locales:         locale.All(),
+		Timezones:       commonTimezones,
+		DefaultTimezone: time.Local.String(),
+		DefaultMaxLoad:  int(schedule.DefaultMaxLoad * 100),
+	}
+
+	if r.Method == http.MethodPost {
+		page.readForm(r, "timezone", "locale", "max_load")
+		settings := internal.UserSettings{
+			Timezone: page.Values["timezone"],
+			Locale:   page.Values["locale"],
+			MaxLoad:  page.percent("max_load"),
+		}
+
+		// Nothing is stored while a field can't be read
+		var err error = page.Errors
+		if len(page.Errors) == 0 {
+			err = internal.UpdateUserSettings(r.Context(), userId, settings)
+		}
+		if err != nil {
+			if !page.mergeErrors(err) {
+				renderError(w, r, err)
+				return
+			}
+			renderPageStatus(w, r, http.StatusUnprocessableEntity, "settings", page)
+			return
+		}
+
+		// The page is already shown with the new settings
+		setUserPrefs(r, settings)
+		page.Saved = true
+		renderPage(w, r, "settings", page)
+		return
+	}
+
+	settings, err := internal.GetUserSettings(r.Context(), userId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+	page.Values["timezone"] = settings.Timezone
+	page.Values["locale"] = settings.Locale
+	if settings.MaxLoad > 0 {
+		page.Values["max_load"] = strconv.Itoa(settings.MaxLoad)
+	}
+
+	renderPage(w, r, "settings", page)
+}
@@ -3,9 +3,9 @@
 package handler
 
 import (
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
 	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
-	"html/template"
-	"log"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
 	"net/http"
 )
 
@@ -16,51 +16,56 @@ type SignupCreds struct {
 	PasswordRetyped string
 }
 
+// RegistrationOpen controls whether new users can sign up
+var RegistrationOpen = true
+
 func SignupHandler(w http.ResponseWriter, r *http.Request) {
 	creds := SignupCreds{}
+	logger := logging.FromContext(r.Context())
+
+	if !RegistrationOpen {
+		renderError(w, r, apperr.New(apperr.Unauthorized, "Registration is closed"))
+		return
+	}
 
 	if r.Method == http.MethodPost {
 		creds.Username = r.PostFormValue("username")
 		creds.Email = r.PostFormValue("email")
 		creds.Password = r.PostFormValue("password")
 		creds.PasswordRetyped = r.PostFormValue("password_retyped")
+		page := AuthPage{Username: creds.Username, Email: creds.Email}
 
 		if creds.Password != creds.PasswordRetyped {
-			http.Error(w, "Passwords do not match", http.StatusBadRequest)
+			renderAuthError(w, r, "signup", page, apperr.New(apperr.Validation, "Passwords do not match"))
 			return
 		}
 
 		// Handle Adding to db
-		err := internal.AddUser(creds.Username, creds.Email, creds.Password)
+		err := internal.AddUser(r.Context(), creds.Username, creds.Email, creds.Password)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			log.Println("Error:", err)
+			logger.Info("Signup failed", "username", creds.Username, "error", err)
+			renderAuthError(w, r, "signup", page, err)
 			return
 		}
 
 		correct, err := internal.CheckIfSessionIsCorrect(creds.Username, r)
 		if err != nil {
-			log.Println(err)
+			renderError(w, r, err)
 			return
 		}
 
 		if !correct {
-			err = internal.SetSessionCookie(w, creds.Username)
+			err = internal.SetSessionCookie(r.Context(), w, creds.Username)
 			if err != nil {
-				log.Println(err)
+				renderError(w, r, err)
 				return
 			}
 		}
-		log.Println("User has been created:", creds.Username)
-		http.Redirect(w, r, "/tasks", http.StatusTemporaryRedirect)
-	}
-
-	// Else server the site
-	tmpl, err := template.ParseFiles("templates/signup.html")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.Info("User has been created", "username", creds.Username)
+		http.Redirect(w, r, "/tasks", http.StatusSeeOther)
 		return
 	}
 
-	tmpl.Execute(w, nil)
+	// Else server the site
+	renderPage(w, r, "signup", AuthPage{})
 }
@@ -1,59 +1,158 @@
 package handler
 
 import (
-	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/changes"
 	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
-	"html/template"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"github.com/Shu-AFK/TaskWeave/priority"
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/gorilla/mux"
 )
 
-func RenderDays(w http.ResponseWriter, tmpl string, days []internal.Day) {
-	tmplPath := fmt.Sprintf("templates/%s.html", tmpl)
-	t, err := template.ParseFiles(tmplPath)
+// TasksPage is the data of templates/tasks.html
+type TasksPage struct {
+	Days []internal.Day
+	// ChangesSince is the id of the last change included in Days, the page follows the
+	// change stream from there
+	ChangesSince string
+	Options      ListOptions
+	Quadrants    []priority.Quadrant
+	// Timer is the running time entry, nil if no timer runs
+	Timer *internal.TimeEntry
+	// Postponed are the events that rolled over again and again
+	Postponed []internal.Event
+	// Stuck are the flexible events of days that are over that no later day had room for
+	Stuck []internal.Event
+	// Risks are the events and todos that are forecast to miss their deadline
+	Risks RisksPage
+}
+
+// postponed returns the events of the days that rolled over so often that they are shown
+// with a warning
+func postponed(days []internal.Day) []internal.Event {
+	var events []internal.Event
+	for _, day := range days {
+		for _, event := range day.Events {
+			if event.Postponed() {
+				events = append(events, event)
+			}
+		}
+	}
+	return events
+}
+
+// stuck returns the unfinished flexible events that were missed because the rollover found
+// no later day with room for them
+func stuck(days []internal.Day, now time.Time) []internal.Event {
+	var events []internal.Event
+	for _, day := range days {
+		for _, event := range day.Events {
+			if event.Missed && (!event.Fixed() || event.Scheduled) && !event.Finished(now) {
+				events = append(events, event)
+			}
+		}
+	}
+	return events
+}
+
+func RenderDays(w http.ResponseWriter, r *http.Request, tmpl string, page TasksPage) {
+	renderPage(w, r, tmpl, page)
+}
+
+// currentUser returns the id of the logged-in user. If there is no valid session the
+// client is redirected to the login page and ok is false.
+func currentUser(w http.ResponseWriter, r *http.Request) (userId int, ok bool) {
+	cookie, err := r.Cookie("SessionID")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		loginRequired(w, r)
+		return -1, false
+	}
+
+	userId, err = internal.GetUserIdBySessionID(r.Context(), cookie.Value)
+	if err != nil {
+		loginRequired(w, r)
+		return -1, false
 	}
+	logging.SetUserID(r.Context(), userId)
 
-	err = t.Execute(w, days)
+	settings, err := internal.GetUserSettings(r.Context(), userId)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Warn("Loading user settings failed, using the defaults", "error", err)
+	} else {
+		setUserPrefs(r, settings)
+	}
+
+	return userId, true
+}
+
+// loginRequired sends browsers to the login page, API clients get a 401
+func loginRequired(w http.ResponseWriter, r *http.Request) {
+	if wantsJSON(r) {
+		renderError(w, r, apperr.New(apperr.Unauthorized, "You need to log in"))
 		return
 	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// pathID returns the numeric route variable with the given name
+func pathID(r *http.Request, name string) (int64, error) {
+	return strconv.ParseInt(mux.Vars(r)[name], 10, 64)
 }
 
 func TasksHandler(w http.ResponseWriter, r *http.Request) {
-	todos := []internal.Todo{
-		{
-			Name:        "TODO",
-			Description: "hello, I'm a todo",
-			Deadline:    time.Now(),
-			Done:        false,
-		},
-	}
-
-	events := []internal.Event{
-		{
-			Name:     "ABC",
-			Duration: 0,
-			Deadline: time.Now(),
-			Start:    time.Now(),
-			End:      time.Now(),
-			TodoList: todos,
-		},
-	}
-
-	days := []internal.Day{
-		{
-			Date:   time.Now(),
-			Events: events,
-		},
-		{
-			Date:   time.Now().Add(24 * time.Hour),
-			Events: events,
-		},
-	}
-
-	RenderDays(w, "tasks", days)
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// Taken before loading so that no change gets lost between loading and following
+	// the change stream
+	since := changes.Default.LastID()
+	days, err := internal.GetDays(r.Context(), userId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	switch view := r.URL.Query().Get("view"); view {
+	case "", "list":
+		opts, err := readListOptions(r)
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+		page := TasksPage{
+			Days:         opts.apply(days),
+			ChangesSince: since,
+			Options:      opts,
+			Quadrants:    priority.Quadrants(),
+			Postponed:    postponed(days),
+			Stuck:        stuck(days, time.Now()),
+		}
+		timer, running, err := internal.RunningTimer(r.Context(), userId)
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+		if running {
+			page.Timer = &timer
+		}
+		settings, err := internal.GetUserSettings(r.Context(), userId)
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+		page.Risks = newRisksPage(internal.ForecastRisks(days, settings.Load(), time.Now()), userPrefs(r).Location)
+		RenderDays(w, r, "tasks", page)
+	case "day", "week", "month":
+		renderCalendar(w, r, view, days)
+	case "matrix":
+		renderMatrix(w, r, days)
+	default:
+		renderError(w, r, apperr.New(apperr.Validation, "The view has to be one of list, day, week, month or matrix"))
+	}
 }
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/middleware"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// templateSet holds every page parsed together with the base layout and the partials.
+// Pages are templates/*.html, the layout lives in templates/layouts and blocks shared
+// between pages in templates/partials.
+type templateSet struct {
+	fsys   fs.FS
+	reload bool
+
+	mu       sync.RWMutex
+	pages    map[string]*template.Template
+	partials *template.Template
+}
+
+var templates *templateSet
+
+// noncePlaceholder is written by the cspNonce template func and replaced with the nonce
+// of the request once a page is rendered, so the templates can be parsed only once. It
+// is random so that it can't be guessed and put into user content.
+var noncePlaceholder = "nonce-placeholder-" + randomHex(16)
+
+// templateFuncs are available in every template. The functions depending on the user
+// are replaced with the ones of the request's user before a template is executed.
+func templateFuncs() template.FuncMap {
+	funcs := defaultPrefs().funcs(time.Now())
+	funcs["cspNonce"] = func() string { return noncePlaceholder }
+	return funcs
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// LoadTemplates parses all templates of fsys once. With reload set the templates are
+// parsed again on every request, which is meant for development.
+func LoadTemplates(fsys fs.FS, reload bool) error {
+	set := &templateSet{fsys: fsys, reload: reload}
+	err := set.parse()
+	if err != nil {
+		return err
+	}
+
+	templates = set
+	return nil
+}
+
+func (s *templateSet) parse() error {
+	shared, err := fs.Glob(s.fsys, "partials/*.html")
+	if err != nil {
+		return err
+	}
+	layouts, err := fs.Glob(s.fsys, "layouts/*.html")
+	if err != nil {
+		return err
+	}
+
+	partials, err := template.New("partials").Funcs(templateFuncs()).ParseFS(s.fsys, shared...)
+	if err != nil {
+		return err
+	}
+
+	base, err := template.New("base").Funcs(templateFuncs()).ParseFS(s.fsys, append(layouts, shared...)...)
+	if err != nil {
+		return err
+	}
+
+	files, err := fs.Glob(s.fsys, "*.html")
+	if err != nil {
+		return err
+	}
+
+	pages := map[string]*template.Template{}
+	for _, file := range files {
+		page, err := base.Clone()
+		if err != nil {
+			return err
+		}
+
+		page, err = page.ParseFS(s.fsys, file)
+		if err != nil {
+			return err
+		}
+
+		pages[strings.TrimSuffix(path.Base(file), ".html")] = page
+	}
+
+	s.mu.Lock()
+	s.pages = pages
+	s.partials = partials
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *templateSet) lookup(page string) (*template.Template, error) {
+	if s.reload {
+		err := s.parse()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if page == "" {
+		return s.partials, nil
+	}
+
+	t, ok := s.pages[page]
+	if !ok {
+		return nil, fmt.Errorf("template %s does not exist", page)
+	}
+	return t, nil
+}
+
+// execute renders into a buffer first so that a failing template doesn't leave a half
+// written page behind
+func execute(w http.ResponseWriter, r *http.Request, status int, t *template.Template, name string, data any) {
+	// The parsed templates are never executed themselves, which keeps them cloneable
+	t, err := t.Clone()
+	if err != nil {
+		templateFailed(w, r, err)
+		return
+	}
+	t.Funcs(userPrefs(r).funcs(time.Now()))
+
+	var buf bytes.Buffer
+	err = t.ExecuteTemplate(&buf, name, data)
+	if err != nil {
+		templateFailed(w, r, err)
+		return
+	}
+
+	out := bytes.ReplaceAll(buf.Bytes(), []byte(noncePlaceholder), []byte(middleware.Nonce(r.Context())))
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(out)
+}
+
+// templateFailed can't use the error page, it might be the template that is broken
+func templateFailed(w http.ResponseWriter, r *http.Request, err error) {
+	logging.FromContext(r.Context()).Error("Rendering template failed", "error", err)
+	http.Error(w, userPrefs(r).Locale.T(apperr.Message(err)), http.StatusInternalServerError)
+}
+
+// renderPage executes the page with the given name inside the base layout
+func renderPage(w http.ResponseWriter, r *http.Request, page string, data any) {
+	renderPageStatus(w, r, http.StatusOK, page, data)
+}
+
+// renderPageStatus is renderPage with a status code other than 200
+func renderPageStatus(w http.ResponseWriter, r *http.Request, status int, page string, data any) {
+	t, err := templates.lookup(page)
+	if err != nil {
+		templateFailed(w, r, err)
+		return
+	}
+
+	execute(w, r, status, t, "base", data)
+}
+
+// renderFragment executes a single block defined in one of the partials
+func renderFragment(w http.ResponseWriter, r *http.Request, name string, data any) {
+	t, err := templates.lookup("")
+	if err != nil {
+		templateFailed(w, r, err)
+		return
+	}
+
+	execute(w, r, http.StatusOK, t, name, data)
+}
@@ -0,0 +1,404 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"github.com/Shu-AFK/TaskWeave/track"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TimerEntry is a time entry in the answers for API clients and on the time page
+type TimerEntry struct {
+	ID      int64             `json:"id"`
+	Kind    internal.ItemKind `json:"kind"`
+	ItemID  int64             `json:"item_id"`
+	Name    string            `json:"name"`
+	Start   time.Time         `json:"start"`
+	Stop    time.Time         `json:"stop"`
+	Running bool              `json:"running"`
+	Note    string            `json:"note"`
+	// Length counts up to now while the entry is running
+	Length  time.Duration `json:"-"`
+	Minutes int           `json:"minutes"`
+}
+
+func newTimerEntry(entry internal.TimeEntry, loc *time.Location, now time.Time) TimerEntry {
+	length := entry.Duration(now)
+	return TimerEntry{
+		ID:      entry.ID,
+		Kind:    entry.Kind,
+		ItemID:  entry.ItemID,
+		Name:    entry.Name,
+		Start:   entry.Start.In(loc),
+		Stop:    entry.Stop.In(loc),
+		Running: entry.Running(),
+		Note:    entry.Note,
+		Length:  length,
+		Minutes: int(length.Minutes()),
+	}
+}
+
+// itemAnchor is the place of an event or a todo on the tasks page
+func itemAnchor(kind internal.ItemKind, id int64) string {
+	return fmt.Sprintf("/tasks#%s-%d", kind, id)
+}
+
+// StartEventTimerHandler starts tracking time for an event
+func StartEventTimerHandler(w http.ResponseWriter, r *http.Request) {
+	startTimer(w, r, internal.EventItem, "event")
+}
+
+// StartTodoTimerHandler starts tracking time for a todo
+func StartTodoTimerHandler(w http.ResponseWriter, r *http.Request) {
+	startTimer(w, r, internal.TodoItem, "todo")
+}
+
+// startTimer starts the timer for the item named by the path variable, a timer running
+// for something else is stopped
+func startTimer(w http.ResponseWriter, r *http.Request, kind internal.ItemKind, name string) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := pathID(r, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	now := time.Now()
+	entry, err := internal.StartTimer(r.Context(), userId, kind, id, strings.TrimSpace(r.PostFormValue("note")), now)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusCreated, newTimerEntry(entry, userPrefs(r).Location, now))
+		return
+	}
+	http.Redirect(w, r, itemAnchor(kind, id), http.StatusSeeOther)
+}
+
+// TimerHandler answers API clients with the running timer
+func TimerHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	entry, running, err := internal.RunningTimer(r.Context(), userId)
+	if err == nil && !running {
+		err = internal.ErrNoTimer
+	}
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newTimerEntry(entry, userPrefs(r).Location, time.Now()))
+}
+
+// StopTimerHandler stops the running timer, the optional note replaces the one given at
+// the start
+func StopTimerHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	entry, err := internal.StopTimer(r.Context(), userId, strings.TrimSpace(r.PostFormValue("note")), now)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, newTimerEntry(entry, userPrefs(r).Location, now))
+		return
+	}
+	http.Redirect(w, r, itemAnchor(entry.Kind, entry.ItemID), http.StatusSeeOther)
+}
+
+// TimePage is the data of templates/time.html and the answer for API clients. It lists
+// the time entries of an event or a todo, time tracked without the timer can be added.
+type TimePage struct {
+	Kind    internal.ItemKind `json:"kind"`
+	ID      int64             `json:"id"`
+	Name    string            `json:"name"`
+	Entries []TimerEntry      `json:"entries"`
+	// Total is the time of the entries, for events without the time of their todos
+	Total        time.Duration `json:"-"`
+	TotalMinutes int           `json:"total_minutes"`
+	Form         *FormPage     `json:"-"`
+}
+
+// Path is the address of the time page of the item
+func (p TimePage) Path() string {
+	return fmt.Sprintf("/tasks/%ss/%d/time", p.Kind, p.ID)
+}
+
+// EventTimeHandler lists the time entries of an event, POST adds one
+func EventTimeHandler(w http.ResponseWriter, r *http.Request) {
+	timeEntries(w, r, internal.EventItem, "event")
+}
+
+// TodoTimeHandler lists the time entries of a todo, POST adds one
+func TodoTimeHandler(w http.ResponseWriter, r *http.Request) {
+	timeEntries(w, r, internal.TodoItem, "todo")
+}
+
+// readTimeEntry reads the form of the time page, the entry starts and stops on the date
+func readTimeEntry(page *FormPage, r *http.Request, entry *internal.TimeEntry) {
+	page.readForm(r, "date", "start", "stop", "note")
+
+	date := page.date("date")
+	if date.IsZero() && page.Errors["date"] == "" {
+		page.Errors["date"] = "Please enter a date"
+	}
+	entry.Start = page.clock("start", date)
+	entry.Stop = page.clock("stop", date)
+	entry.Note = page.Values["note"]
+}
+
+func timeEntries(w http.ResponseWriter, r *http.Request, kind internal.ItemKind, name string) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := pathID(r, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	loc := userPrefs(r).Location
+	now := time.Now()
+	page := TimePage{Kind: kind, ID: id, Entries: []TimerEntry{}}
+	page.Form = newFormPage(r, "Add time", page.Path())
+	page.Form.Values["date"] = now.In(loc).Format(formDateLayout)
+
+	status := http.StatusOK
+	if r.Method == http.MethodPost {
+		entry := internal.TimeEntry{Kind: kind, ItemID: id}
+		readTimeEntry(page.Form, r, &entry)
+
+		if len(page.Form.Errors) == 0 {
+			err = internal.AddTimeEntry(r.Context(), userId, &entry, now)
+			if err != nil && (wantsJSON(r) || !page.Form.mergeErrors(err)) {
+				renderError(w, r, err)
+				return
+			}
+		}
+
+		switch {
+		case len(page.Form.Errors) > 0 && wantsJSON(r):
+			renderError(w, r, page.Form.Errors)
+			return
+		case len(page.Form.Errors) > 0:
+			status = page.Form.status()
+		case !wantsJSON(r):
+			http.Redirect(w, r, page.Path(), http.StatusSeeOther)
+			return
+		default:
+			status = http.StatusCreated
+		}
+	}
+
+	entries, err := internal.GetTimeEntries(r.Context(), userId, kind, id)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+	for _, entry := range entries {
+		page.Name = entry.Name
+		timerEntry := newTimerEntry(entry, loc, now)
+		page.Entries = append(page.Entries, timerEntry)
+		page.Total += timerEntry.Length
+	}
+	page.TotalMinutes = int(page.Total.Minutes())
+	if page.Name == "" {
+		page.Name, err = itemName(r, userId, kind, id)
+		if err != nil {
+			renderError(w, r, err)
+			return
+		}
+	}
+
+	if wantsJSON(r) {
+		writeJSON(w, status, page)
+		return
+	}
+	renderPageStatus(w, r, status, "time", page)
+}
+
+// itemName returns the name of an event or a todo of the user
+func itemName(r *http.Request, userId int, kind internal.ItemKind, id int64) (string, error) {
+	if kind == internal.TodoItem {
+		todo, err := internal.GetTodo(r.Context(), userId, id)
+		return todo.Name, err
+	}
+	event, err := internal.GetEvent(r.Context(), userId, id)
+	return event.Name, err
+}
+
+// DeleteEventTimeEntryHandler removes a time entry of an event
+func DeleteEventTimeEntryHandler(w http.ResponseWriter, r *http.Request) {
+	deleteTimeEntry(w, r, internal.EventItem, "event")
+}
+
+// DeleteTodoTimeEntryHandler removes a time entry of a todo
+func DeleteTodoTimeEntryHandler(w http.ResponseWriter, r *http.Request) {
+	deleteTimeEntry(w, r, internal.TodoItem, "todo")
+}
+
+func deleteTimeEntry(w http.ResponseWriter, r *http.Request, kind internal.ItemKind, name string) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := pathID(r, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	entryId, err := pathID(r, "entry")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = internal.DeleteTimeEntry(r.Context(), userId, kind, id, entryId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, TimePage{Kind: kind, ID: id}.Path(), http.StatusSeeOther)
+}
+
+// ReportPage is the data of templates/report.html and the answer for API clients. It
+// compares the planned length of the events in a range of days, this week by default,
+// with the time tracked for them and their todos.
+type ReportPage struct {
+	// From and To are the first and the last date of the range
+	From  time.Time    `json:"from"`
+	To    time.Time    `json:"to"`
+	Days  []ReportDay  `json:"days"`
+	Total ReportAmount `json:"total"`
+}
+
+type ReportDay struct {
+	ID     int64         `json:"id"`
+	Date   time.Time     `json:"date"`
+	Events []ReportEvent `json:"events"`
+	Total  ReportAmount  `json:"total"`
+}
+
+type ReportEvent struct {
+	ID     int64        `json:"id"`
+	Name   string       `json:"name"`
+	Amount ReportAmount `json:"amount"`
+	Todos  []ReportTodo `json:"todos"`
+}
+
+type ReportTodo struct {
+	ID      int64         `json:"id"`
+	Name    string        `json:"name"`
+	Tracked time.Duration `json:"-"`
+	Minutes int           `json:"minutes"`
+	Hours   float64       `json:"hours"`
+}
+
+// ReportAmount is the planned and the tracked time of some work. Hours is the tracked
+// time as it is billed.
+type ReportAmount struct {
+	track.Comparison `json:"-"`
+	EstimatedMinutes int     `json:"estimated_minutes"`
+	ActualMinutes    int     `json:"actual_minutes"`
+	Hours            float64 `json:"hours"`
+	// Percent is the tracked time in percent of the planned time
+	Percent int `json:"percent"`
+}
+
+func newReportAmount(c track.Comparison) ReportAmount {
+	return ReportAmount{
+		Comparison:       c,
+		EstimatedMinutes: int(c.Estimated.Minutes()),
+		ActualMinutes:    int(c.Actual.Minutes()),
+		Hours:            track.Hours(c.Actual),
+		Percent:          c.Percent(),
+	}
+}
+
+// Off is how far the tracked time is off the plan to the minute, see Over for the
+// direction
+func (a ReportAmount) Off() time.Duration {
+	d := a.Difference().Round(time.Minute)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func newReportPage(days []internal.Day, from, to time.Time) ReportPage {
+	page := ReportPage{From: from, To: to, Days: []ReportDay{}}
+	var total track.Comparison
+	for _, day := range days {
+		reportDay := ReportDay{ID: day.ID, Date: day.Date, Events: []ReportEvent{}}
+		var dayTotal track.Comparison
+		for _, event := range day.Events {
+			c := track.Comparison{Estimated: event.Duration, Actual: event.Tracked}
+			reportEvent := ReportEvent{ID: event.ID, Name: event.Name, Amount: newReportAmount(c), Todos: []ReportTodo{}}
+			for _, todo := range event.TodoList {
+				if todo.Tracked > 0 {
+					reportEvent.Todos = append(reportEvent.Todos, ReportTodo{todo.ID, todo.Name, todo.Tracked, int(todo.Tracked.Minutes()), track.Hours(todo.Tracked)})
+				}
+			}
+			reportDay.Events = append(reportDay.Events, reportEvent)
+			dayTotal.Estimated += c.Estimated
+			dayTotal.Actual += c.Actual
+		}
+		reportDay.Total = newReportAmount(dayTotal)
+		page.Days = append(page.Days, reportDay)
+		total.Estimated += dayTotal.Estimated
+		total.Actual += dayTotal.Actual
+	}
+	page.Total = newReportAmount(total)
+	return page
+}
+
+// ReportHandler compares the planned with the tracked time of the events of a range of
+// days, this week by default
+func ReportHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	loc := userPrefs(r).Location
+	from, to, err := readDateRange(r, startOfWeek(time.Now().In(loc)), 7)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	days, err := internal.GetTimeReport(r.Context(), userId, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	page := newReportPage(localizeDays(days, loc), from, to)
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, page)
+		return
+	}
+	renderPage(w, r, "report", page)
+}
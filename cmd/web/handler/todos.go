@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"net/http"
+)
+
+var todoFields = []string{"name", "description", "deadline", "done", "urgent", "important", "repeat"}
+
+func readTodo(page *FormPage, r *http.Request, todo *internal.Todo) {
+	page.readForm(r, todoFields...)
+
+	todo.Name = page.Values["name"]
+	todo.Description = page.Values["description"]
+	todo.Deadline = page.dateTime("deadline")
+	todo.Done = page.Values["done"] != ""
+	todo.Priority = page.priority()
+}
+
+func fillTodoValues(page *FormPage, todo internal.Todo) {
+	page.Values["name"] = todo.Name
+	page.Values["description"] = todo.Description
+	page.Values["deadline"] = page.formatTime(todo.Deadline, formDateTimeLayout)
+	if todo.Done {
+		page.Values["done"] = "on"
+	}
+	page.fillPriority(todo.Priority)
+}
+
+// NewTodoHandler shows and handles the form to attach a todo to an event
+func NewTodoHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	eventId, err := pathID(r, "event")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	event, err := internal.GetEvent(r.Context(), userId, eventId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	page := newFormPage(r, "New todo", fmt.Sprintf("/tasks/events/%d/todos/new", eventId))
+	page.Event = event
+	page.Repeatable = event.SeriesID != 0
+
+	if r.Method == http.MethodPost {
+		todo := internal.Todo{EventID: eventId}
+		readTodo(page, r, &todo)
+
+		if len(page.Errors) == 0 {
+			if page.Values["repeat"] != "" {
+				err = internal.AddRepeatingTodo(r.Context(), userId, &todo)
+			} else {
+				err = internal.AddTodo(r.Context(), userId, &todo)
+			}
+			if err == nil {
+				http.Redirect(w, r, "/tasks", http.StatusSeeOther)
+				return
+			}
+			if !page.mergeErrors(err) {
+				renderError(w, r, err)
+				return
+			}
+		}
+
+		renderPageStatus(w, r, http.StatusUnprocessableEntity, "todo_form", page)
+		return
+	}
+
+	renderPage(w, r, "todo_form", page)
+}
+
+// EditTodoHandler shows and handles the form to change an existing todo
+func EditTodoHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	todoId, err := pathID(r, "todo")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	todo, err := internal.GetTodo(r.Context(), userId, todoId)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	event, err := internal.GetEvent(r.Context(), userId, todo.EventID)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	page := newFormPage(r, "Edit todo", fmt.Sprintf("/tasks/todos/%d/edit", todoId))
+	page.Event = event
+	fillTodoValues(page, todo)
+
+	if r.Method == http.MethodPost {
+		readTodo(page, r, &todo)
+
+		if len(page.Errors) == 0 {
+			err = internal.UpdateTodo(r.Context(), userId, todo)
+			if err == nil {
+				http.Redirect(w, r, "/tasks", http.StatusSeeOther)
+				return
+			}
+			if !page.mergeErrors(err) {
+				renderError(w, r, err)
+				return
+			}
+		}
+
+		renderPageStatus(w, r, http.StatusUnprocessableEntity, "todo_form", page)
+		return
+	}
+
+	renderPage(w, r, "todo_form", page)
+}
+
+// DeleteTodoHandler removes a todo from its event. A todo that repeats with a series is
+// removed from the following occurrences as well if the scope field says so.
+func DeleteTodoHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	todoId, err := pathID(r, "todo")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch scope, _ := internal.ParseScope(r.PostFormValue("scope")); scope {
+	case internal.ThisOccurrence:
+		err = internal.DeleteTodo(r.Context(), userId, todoId)
+	case internal.FollowingOccurrences:
+		err = internal.DeleteRepeatingTodo(r.Context(), userId, todoId)
+	default:
+		err = internal.ValidationErrors{"scope": "Please choose which occurrences to change"}
+	}
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/tasks", http.StatusSeeOther)
+}
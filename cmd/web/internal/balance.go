@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/changes"
+	"github.com/Shu-AFK/TaskWeave/schedule"
+	"time"
+)
+
+// Rebalance is a proposal to move flexible events between the days of a range
+type Rebalance struct {
+	// Days are the days of the range, the moves and loads of Plan refer to their indexes
+	Days []Day
+	Plan schedule.Balanced
+}
+
+// Hash identifies the moves of the plan. The preview hands it out and RebalanceEvents only
+// applies a plan whose moves still have the same hash.
+func (b Rebalance) Hash() string {
+	h := sha256.New()
+	for _, move := range b.Plan.Moves {
+		fmt.Fprintf(h, "%d:%d:%d\n", move.Item.ID, b.Days[move.From].ID, b.Days[move.To].ID)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// errPlanChanged is returned by RebalanceEvents when the events changed since the preview
+var errPlanChanged = apperr.New(apperr.Conflict, "The events have changed since the preview, please check the moves again")
+
+// inRange reports whether the date of the day is from the date of from up to but not
+// including the date of to
+func inRange(day Day, from, to time.Time) bool {
+	date := day.Date.Format(dateLayout)
+	return date >= from.Format(dateLayout) && date < to.Format(dateLayout)
+}
+
+// PlanRebalance spreads the flexible events of the days in the range so that no day is
+// booked beyond maxLoad of its working window. Events that have started are left alone
+// and time before now is never used. Events never move in front of the events they
+// depend on.
+func PlanRebalance(days []Day, from, to time.Time, maxLoad float64, now time.Time) Rebalance {
+	var balance Rebalance
+	var balanceDays []schedule.Day
+	var items []schedule.Item
+	events := eventsByID(days)
+	for _, day := range days {
+		if !inRange(day, from, to) {
+			continue
+		}
+		i := len(balance.Days)
+		balance.Days = append(balance.Days, day)
+
+		window := openWindow(day, now)
+		for _, event := range day.Events {
+			switch {
+			case replaceable(event, now):
+				items = append(items, scheduleItem(event, i, events, now))
+			case event.Fixed():
+				window.Busy = append(window.Busy, schedule.Span{Start: event.Start, End: event.End})
+			}
+		}
+		balanceDays = append(balanceDays, window)
+	}
+
+	balance.Plan = schedule.Balance(balanceDays, items, maxLoad)
+	return balance
+}
+
+// PreviewRebalance returns the moves RebalanceEvents would make without applying them
+func PreviewRebalance(ctx context.Context, userId int, from, to time.Time, maxLoad float64, now time.Time) (Rebalance, error) {
+	days, err := GetDays(ctx, userId)
+	if err != nil {
+		return Rebalance{}, err
+	}
+	return PlanRebalance(days, from, to, maxLoad, now), nil
+}
+
+// RebalanceEvents moves the flexible events of a user between the days of the range.
+// plan is the Hash of the previewed moves, nothing is moved if the moves aren't the same
+// anymore or an event left its day or got a fixed time in the meantime. Moved events lose
+// the times the scheduler gave them, they are placed again on their new day by the next
+// run of the scheduler.
+func RebalanceEvents(ctx context.Context, userId int, from, to time.Time, maxLoad float64, now time.Time, plan string) (Rebalance, error) {
+	balance, err := PreviewRebalance(ctx, userId, from, to, maxLoad, now)
+	if err != nil {
+		return Rebalance{}, err
+	}
+	if balance.Hash() != plan {
+		return Rebalance{}, errPlanChanged
+	}
+	if len(balance.Plan.Moves) == 0 {
+		return balance, nil
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return Rebalance{}, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Rebalance{}, err
+	}
+	defer tx.Rollback()
+
+	changed := map[int64]bool{}
+	for _, move := range balance.Plan.Moves {
+		fromDay, toDay := balance.Days[move.From].ID, balance.Days[move.To].ID
+
+		res, err := tx.ExecContext(ctx, "UPDATE DayEvents SET dayId=? WHERE eventId=? AND dayId=?", toDay, move.Item.ID, fromDay)
+		if err != nil {
+			return Rebalance{}, err
+		}
+		updated, err := res.RowsAffected()
+		if err != nil {
+			return Rebalance{}, err
+		}
+		if updated == 0 {
+			return Rebalance{}, errPlanChanged
+		}
+
+		// The event goes to the end of its new day. An occurrence of a series is detached, it
+		// isn't on the date its rule gives it anymore.
+		res, err = tx.ExecContext(ctx, `
+			UPDATE Events SET start='', end='', scheduled=0, detached=(seriesId != 0), position=(
+				SELECT COALESCE(MAX(e.position), 0) + 1 FROM Events e JOIN DayEvents de ON de.eventId = e.id
+				WHERE de.dayId=?
+			) WHERE id=? AND (start='' OR scheduled=1)
+		`, toDay, move.Item.ID)
+		if err != nil {
+			return Rebalance{}, err
+		}
+		updated, err = res.RowsAffected()
+		if err != nil {
+			return Rebalance{}, err
+		}
+		if updated == 0 {
+			return Rebalance{}, errPlanChanged
+		}
+		changed[fromDay], changed[toDay] = true, true
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Rebalance{}, err
+	}
+
+	// Both days of a move change, refreshing them also updates the conflicts of the events
+	for dayId := range changed {
+		notify(userId, changes.Updated, changes.Day, dayId, dayId, 0)
+	}
+	return balance, nil
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/conflict"
+)
+
+// ConflictError is returned when an event overlaps other events or lies outside of the
+// working window of its day
+type ConflictError struct {
+	Conflicts []conflict.Conflict
+}
+
+func (e *ConflictError) Error() string {
+	return "The event overlaps other events or lies outside of the working window"
+}
+
+func (e *ConflictError) AppErrorKind() apperr.Kind {
+	return apperr.Conflict
+}
+
+func dayWindow(day Day) conflict.Window {
+	return conflict.Window{Start: day.StartOfDay, End: day.EndOfDay}
+}
+
+func (e Event) conflictEvent() conflict.Event {
+	return conflict.Event{ID: e.ID, Name: e.Name, Start: e.Start, End: e.End, AllowOverlap: e.AllowOverlap}
+}
+
+// fixedEvents returns the events that have a time, only they can conflict
+func fixedEvents(events []Event) []conflict.Event {
+	var fixed []conflict.Event
+	for _, event := range events {
+		if event.Fixed() {
+			fixed = append(fixed, event.conflictEvent())
+		}
+	}
+	return fixed
+}
+
+// checkConflicts is used by every path that creates or changes the time of an event.
+// Overlaps are accepted if one of the events may be double-booked, times outside of the
+// working window never are.
+func checkConflicts(day Day, event Event) error {
+	if !event.Fixed() {
+		return nil
+	}
+
+	checked := event.conflictEvent()
+	conflicts := conflict.Unallowed(checked, conflict.Check(dayWindow(day), fixedEvents(day.Events), checked))
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return &ConflictError{Conflicts: conflicts}
+}
+
+// markConflicts fills in the conflicts of the events of a day so that they can be shown
+func markConflicts(day *Day) {
+	found := conflict.ForDay(dayWindow(*day), fixedEvents(day.Events))
+	for i := range day.Events {
+		day.Events[i].Conflicts = found[day.Events[i].ID]
+	}
+}
+
+// Clashes reports whether the event has conflicts that weren't accepted. Accepted
+// overlaps are still shown, but not as a problem.
+func (e Event) Clashes() bool {
+	return len(conflict.Unallowed(e.conflictEvent(), e.Conflicts)) > 0
+}
@@ -1,86 +1,70 @@
 package internal
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
 	_ "github.com/mattn/go-sqlite3"
 	"golang.org/x/crypto/bcrypt"
 	"log"
 	"net/mail"
+	"os"
+	"path/filepath"
 )
 
+// ErrInvalidCredentials is returned by ValidateUser for an unknown user or a wrong password
+var ErrInvalidCredentials = apperr.New(apperr.Unauthorized, "Invalid username or password")
+
+// DBPath is the location of the SQLite database
+var DBPath = "./db/app.db"
+
+// store is the connection pool shared by all requests, it is opened by CreateDB
+var store *sql.DB
+
 func CreateDB() {
-	db, err := sql.Open("sqlite3", "./db/app.db")
+	err := os.MkdirAll(filepath.Dir(DBPath), 0o755)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Wait for locks instead of failing right away when requests write concurrently
+	store, err = sql.Open("sqlite3", DBPath+"?_busy_timeout=5000")
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
-
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS Users (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			username TEXT UNIQUE,
-			email TEXT UNIQUE,
-			password TEXT
-		);
-
-		CREATE TABLE IF NOT EXISTS Sessions (
-		    sessionId TEXT PRIMARY KEY,
-		    userId INTEGER,
-		    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		    FOREIGN KEY(userId) REFERENCES Users(userId)
-		);
-
-		CREATE TABLE IF NOT EXISTS Todos (
-			id INTEGER PRIMARY KEY AUTOINCREMENT, 
-			name TEXT, 
-			description TEXT, 
-			deadline TEXT,
-			done BOOLEAN CHECK(done IN (0, 1))
-		);
-
-		CREATE TABLE IF NOT EXISTS Events (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT, 
-			duration TEXT,
-			deadline TEXT,
-			start TEXT,
-			end TEXT
-		);
-
-		CREATE TABLE IF NOT EXISTS Days (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			userId INTEGER,
-			date TEXT,
-			FOREIGN KEY(userId) REFERENCES Users(id)
-		);
-
-		CREATE TABLE IF NOT EXISTS EventTodos (
-			eventId INTEGER,
-			todoId INTEGER,
-			FOREIGN KEY(eventId) REFERENCES Events(id),
-			FOREIGN KEY(todoId) REFERENCES Todos(id)
-		);
-
-		CREATE TABLE IF NOT EXISTS DayEvents (
-			dayId INTEGER,
-			eventId INTEGER,
-			FOREIGN KEY(dayId) REFERENCES Days(id),
-			FOREIGN KEY(eventId) REFERENCES Events(id)
-		);	
-	`)
+
+	err = migrate(context.Background(), store)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
+// CloseDB closes the database, it has to be called after all requests and background
+// workers finished
+func CloseDB() error {
+	if store == nil {
+		return nil
+	}
+	return store.Close()
+}
+
+// openDB returns the application database
+func openDB() (storeDB, error) {
+	if store == nil {
+		return storeDB{}, errors.New("database is not open")
+	}
+	return storeDB{store}, nil
+}
+
 func emailValid(email string) bool {
 	_, err := mail.ParseAddress(email)
 	return err == nil
 }
 
-func valueExistsUserDB(db *sql.DB, isUsername bool, toCheck string) (bool, error) {
+func valueExistsUserDB(ctx context.Context, db storeDB, isUsername bool, toCheck string) (bool, error) {
 	var exists bool
 	var query string
 	if isUsername {
@@ -89,7 +73,7 @@ func valueExistsUserDB(db *sql.DB, isUsername bool, toCheck string) (bool, error
 		query = fmt.Sprintf(`SELECT EXISTS(SELECT * FROM Users WHERE %s=?)`, "email")
 	}
 
-	err := db.QueryRow(query, toCheck).Scan(&exists)
+	err := db.QueryRowContext(ctx, query, toCheck).Scan(&exists)
 	if err != nil {
 		return false, err
 	}
@@ -97,34 +81,33 @@ func valueExistsUserDB(db *sql.DB, isUsername bool, toCheck string) (bool, error
 	return exists, nil
 }
 
-func AddUser(username string, email string, password string) error {
-	db, err := sql.Open("sqlite3", "./db/app.db")
+func AddUser(ctx context.Context, username string, email string, password string) error {
+	db, err := openDB()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	defer db.Close()
 
 	// Check if email is valid
 	if username == "" || password == "" || email == "" {
-		return errors.New("username, password or email is empty")
+		return apperr.New(apperr.Validation, "Username, email and password are required")
 	}
 	if !emailValid(email) {
-		return errors.New("invalid email")
+		return apperr.New(apperr.Validation, "The email address is not valid")
 	}
 
-	exists, err := valueExistsUserDB(db, true, username)
+	exists, err := valueExistsUserDB(ctx, db, true, username)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return errors.New("user already exists")
+		return apperr.New(apperr.Conflict, "This username is already taken")
 	}
-	exists, err = valueExistsUserDB(db, false, email)
+	exists, err = valueExistsUserDB(ctx, db, false, email)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return errors.New("email already exists")
+		return apperr.New(apperr.Conflict, "An account with this email address already exists")
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -132,27 +115,31 @@ func AddUser(username string, email string, password string) error {
 		return err
 	}
 
-	_, err = db.Exec(`
+	_, err = db.ExecContext(ctx, `
 		INSERT INTO Users (username, email, password)
 		VALUES (?, ?, ?)
 	`, username, email, hashedPassword)
 	if err != nil {
 		return err
 	}
+	logging.FromContext(ctx).Debug("Stored new user", "username", username)
 
 	return nil
 }
 
-func ValidateUser(username string, password string) error {
-	db, err := sql.Open("sqlite3", "./db/app.db")
+func ValidateUser(ctx context.Context, username string, password string) error {
+	db, err := openDB()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	defer db.Close()
 
 	var storedHashedPassword []byte
 
-	err = db.QueryRow("SELECT password FROM Users WHERE username = ?", username).Scan(&storedHashedPassword)
+	err = db.QueryRowContext(ctx, "SELECT password FROM Users WHERE username = ?", username).Scan(&storedHashedPassword)
+	if errors.Is(err, sql.ErrNoRows) {
+		logging.FromContext(ctx).Debug("User does not exist", "username", username)
+		return ErrInvalidCredentials
+	}
 	if err != nil {
 		return err
 	}
@@ -160,7 +147,8 @@ func ValidateUser(username string, password string) error {
 	err = bcrypt.CompareHashAndPassword(storedHashedPassword, []byte(password))
 	if err != nil {
 		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return errors.New("invalid password")
+			logging.FromContext(ctx).Debug("Password does not match", "username", username)
+			return ErrInvalidCredentials
 		}
 		return err
 	}
@@ -168,15 +156,14 @@ func ValidateUser(username string, password string) error {
 	return nil
 }
 
-func CheckIfSessionExists(userId int) (bool, error) {
-	db, err := sql.Open("sqlite3", "./db/app.db")
+func CheckIfSessionExists(ctx context.Context, userId int) (bool, error) {
+	db, err := openDB()
 	if err != nil {
-		log.Fatal(err)
+		return false, err
 	}
-	defer db.Close()
 
 	var exists bool
-	err = db.QueryRow("SELECT EXISTS(SELECT * FROM Sessions WHERE userId=?)", userId).Scan(&exists)
+	err = db.QueryRowContext(ctx, "SELECT EXISTS(SELECT * FROM Sessions WHERE userId=? AND "+validSessionCondition()+")", userId).Scan(&exists)
 	if err != nil {
 		return false, err
 	}
@@ -187,15 +174,14 @@ func CheckIfSessionExists(userId int) (bool, error) {
 	return false, nil
 }
 
-func GetUserIdByName(username string) (int, error) {
-	db, err := sql.Open("sqlite3", "./db/app.db")
+func GetUserIdByName(ctx context.Context, username string) (int, error) {
+	db, err := openDB()
 	if err != nil {
-		log.Fatal(err)
+		return -1, err
 	}
-	defer db.Close()
 
 	var id int
-	err = db.QueryRow("SELECT id FROM Users WHERE username=?", username).Scan(&id)
+	err = db.QueryRowContext(ctx, "SELECT id FROM Users WHERE username=?", username).Scan(&id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return -1, errors.New("user does not exist")
@@ -207,15 +193,14 @@ func GetUserIdByName(username string) (int, error) {
 	return id, nil
 }
 
-func GetUsernameById(userId int) (string, error) {
-	db, err := sql.Open("sqlite3", "./db/app.db")
+func GetUsernameById(ctx context.Context, userId int) (string, error) {
+	db, err := openDB()
 	if err != nil {
 		return "", err
 	}
-	defer db.Close()
 
 	var username string
-	err = db.QueryRow("SELECT username FROM Users WHERE id=?", userId).Scan(&username)
+	err = db.QueryRowContext(ctx, "SELECT username FROM Users WHERE id=?", userId).Scan(&username)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", errors.New("user does not exist")
@@ -227,15 +212,14 @@ func GetUsernameById(userId int) (string, error) {
 	return username, nil
 }
 
-func SetSessionID(userId int, sessionID string) error {
-	db, err := sql.Open("sqlite3", "./db/app.db")
+func SetSessionID(ctx context.Context, userId int, sessionID string) error {
+	db, err := openDB()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	defer db.Close()
 
 	var exists bool
-	err = db.QueryRow("SELECT EXISTS(SELECT * FROM Sessions WHERE sessionId=?)", sessionID).Scan(&exists)
+	err = db.QueryRowContext(ctx, "SELECT EXISTS(SELECT * FROM Sessions WHERE sessionId=?)", sessionID).Scan(&exists)
 	if err != nil {
 		return err
 	}
@@ -243,7 +227,7 @@ func SetSessionID(userId int, sessionID string) error {
 		return errors.New("sessionID already exists")
 	}
 
-	_, err = db.Exec("INSERT INTO Sessions (sessionId, userId) VALUES (?, ?)", sessionID, userId)
+	_, err = db.ExecContext(ctx, "INSERT INTO Sessions (sessionId, userId, lastSeen) VALUES (?, ?, CURRENT_TIMESTAMP)", sessionID, userId)
 	if err != nil {
 		return err
 	}
@@ -251,18 +235,41 @@ func SetSessionID(userId int, sessionID string) error {
 	return nil
 }
 
-func GetSessionIDbyUserID(userid int) (string, error) {
-	db, err := sql.Open("sqlite3", "./db/app.db")
+func GetSessionIDbyUserID(ctx context.Context, userid int) (string, error) {
+	db, err := openDB()
 	if err != nil {
-		return "", nil
+		return "", err
 	}
-	defer db.Close()
 
 	var sessionID string
-	err = db.QueryRow("SELECT sessionId FROM Sessions WHERE userId=?", userid).Scan(&sessionID)
+	err = db.QueryRowContext(ctx, "SELECT sessionId FROM Sessions WHERE userId=? AND "+validSessionCondition()+" ORDER BY createdAt DESC", userid).Scan(&sessionID)
 	if err != nil {
 		return "", err
 	}
 
 	return sessionID, nil
 }
+
+func GetUserIdBySessionID(ctx context.Context, sessionID string) (int, error) {
+	db, err := openDB()
+	if err != nil {
+		return -1, err
+	}
+
+	var userId int
+	err = db.QueryRowContext(ctx, "SELECT userId FROM Sessions WHERE sessionId=? AND "+validSessionCondition(), sessionID).Scan(&userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return -1, errors.New("session does not exist")
+		} else {
+			return -1, err
+		}
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE Sessions SET lastSeen = CURRENT_TIMESTAMP WHERE sessionId=?", sessionID)
+	if err != nil {
+		return -1, err
+	}
+
+	return userId, nil
+}
@@ -0,0 +1,320 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"github.com/Shu-AFK/TaskWeave/depend"
+	"time"
+)
+
+// Prerequisite is an item another one has to wait for
+type Prerequisite struct {
+	// DependencyID identifies the dependency, it is needed to remove it again
+	DependencyID int64
+	ID           int64
+	Name         string
+	// Done is decided when the dependent is loaded. Todos are done once they are ticked
+	// off, events once all of their todos are or, without todos, once they have ended.
+	Done bool
+}
+
+// CycleError is returned when a dependency would make an item wait for itself. Cycle
+// names the items of the cycle, starting and ending with the dependent.
+type CycleError struct {
+	Cycle []string
+}
+
+func (e *CycleError) Error() string {
+	return "The dependency would create a cycle"
+}
+
+func (e *CycleError) AppErrorKind() apperr.Kind {
+	return apperr.Conflict
+}
+
+// Blocked reports whether the event waits for a prerequisite that isn't done
+func (e Event) Blocked() bool {
+	return len(e.WaitingFor()) > 0
+}
+
+// WaitingFor returns the prerequisites of the event that aren't done
+func (e Event) WaitingFor() []Prerequisite {
+	return waitingFor(e.Prerequisites)
+}
+
+// Blocked reports whether the todo waits for a prerequisite that isn't done
+func (t Todo) Blocked() bool {
+	return len(t.WaitingFor()) > 0
+}
+
+// WaitingFor returns the prerequisites of the todo that aren't done
+func (t Todo) WaitingFor() []Prerequisite {
+	return waitingFor(t.Prerequisites)
+}
+
+func waitingFor(prerequisites []Prerequisite) []Prerequisite {
+	var open []Prerequisite
+	for _, p := range prerequisites {
+		if !p.Done {
+			open = append(open, p)
+		}
+	}
+	return open
+}
+
+// finished decides whether an event is done, see Prerequisite. An event that was marked
+// as done always is.
+func finished(done bool, todos, open int, end, now time.Time) bool {
+	if done {
+		return true
+	}
+	if todos > 0 {
+		return open == 0
+	}
+	return !end.IsZero() && !end.After(now)
+}
+
+// Finished reports whether the event counts as done for the events that depend on it
+func (e Event) Finished(now time.Time) bool {
+	open := 0
+	for _, todo := range e.TodoList {
+		if !todo.Done {
+			open++
+		}
+	}
+	return finished(e.Done, len(e.TodoList), open, e.End, now)
+}
+
+// prerequisites maps the ids of dependents to what they wait for
+type prerequisites struct {
+	events map[int64][]Prerequisite
+	todos  map[int64][]Prerequisite
+}
+
+// getPrerequisites loads every dependency of a user together with the state of the
+// prerequisites
+func getPrerequisites(ctx context.Context, db storeDB, userId int, now time.Time) (prerequisites, error) {
+	p := prerequisites{events: map[int64][]Prerequisite{}, todos: map[int64][]Prerequisite{}}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT d.id, d.dependentId, e.id, e.name, e.end, e.done,
+			(SELECT COUNT(*) FROM EventTodos et WHERE et.eventId = e.id),
+			(SELECT COUNT(*) FROM EventTodos et JOIN Todos t ON t.id = et.todoId WHERE et.eventId = e.id AND t.done = 0)
+		FROM Dependencies d JOIN Events e ON e.id = d.prerequisiteId
+		WHERE d.userId=? AND d.kind=?
+		ORDER BY d.id
+	`, userId, EventItem)
+	if err != nil {
+		return p, err
+	}
+	for rows.Next() {
+		var prerequisite Prerequisite
+		var dependentId int64
+		var end sql.NullString
+		var done bool
+		var todos, open int
+		err = rows.Scan(&prerequisite.DependencyID, &dependentId, &prerequisite.ID, &prerequisite.Name, &end, &done, &todos, &open)
+		if err != nil {
+			rows.Close()
+			return p, err
+		}
+		endTime, err := parseTime(end)
+		if err != nil {
+			rows.Close()
+			return p, err
+		}
+		prerequisite.Done = finished(done, todos, open, endTime, now)
+		p.events[dependentId] = append(p.events[dependentId], prerequisite)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return p, err
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT d.id, d.dependentId, t.id, t.name, t.done
+		FROM Dependencies d JOIN Todos t ON t.id = d.prerequisiteId
+		WHERE d.userId=? AND d.kind=?
+		ORDER BY d.id
+	`, userId, TodoItem)
+	if err != nil {
+		return p, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var prerequisite Prerequisite
+		var dependentId int64
+		var done sql.NullBool
+		err = rows.Scan(&prerequisite.DependencyID, &dependentId, &prerequisite.ID, &prerequisite.Name, &done)
+		if err != nil {
+			return p, err
+		}
+		prerequisite.Done = done.Bool
+		p.todos[dependentId] = append(p.todos[dependentId], prerequisite)
+	}
+
+	return p, rows.Err()
+}
+
+// attach sets the prerequisites of the events and their todos
+func (p prerequisites) attach(events []Event) {
+	for i := range events {
+		events[i].Prerequisites = p.events[events[i].ID]
+		for j := range events[i].TodoList {
+			events[i].TodoList[j].Prerequisites = p.todos[events[i].TodoList[j].ID]
+		}
+	}
+}
+
+// dependencyGraph loads the dependencies of one kind
+func dependencyGraph(ctx context.Context, db querier, userId int, kind ItemKind) (depend.Graph, error) {
+	rows, err := db.QueryContext(ctx, "SELECT dependentId, prerequisiteId FROM Dependencies WHERE userId=? AND kind=?", userId, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	graph := depend.Graph{}
+	for rows.Next() {
+		var dependent, prerequisite int64
+		if err = rows.Scan(&dependent, &prerequisite); err != nil {
+			return nil, err
+		}
+		graph[dependent] = append(graph[dependent], prerequisite)
+	}
+
+	return graph, rows.Err()
+}
+
+// AddDependency makes dependent wait for prerequisite, both are events or both are
+// todos. It returns a CycleError if prerequisite already waits for dependent, even
+// through other items. Adding a dependency twice does nothing. The check and the insert run
+// in one transaction, so that two requests can't close a cycle together.
+func AddDependency(ctx context.Context, userId int, kind ItemKind, prerequisite, dependent int64) error {
+	if prerequisite == dependent {
+		return ValidationErrors{"prerequisite": "An item can't depend on itself"}
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, _, err = itemOwner(ctx, tx, userId, kind, prerequisite)
+	if err != nil {
+		return err
+	}
+	dayId, eventId, err := itemOwner(ctx, tx, userId, kind, dependent)
+	if err != nil {
+		return err
+	}
+
+	graph, err := dependencyGraph(ctx, tx, userId, kind)
+	if err != nil {
+		return err
+	}
+	for _, id := range graph[dependent] {
+		if id == prerequisite {
+			return nil
+		}
+	}
+	if cycle := graph.Cycle(dependent, prerequisite); cycle != nil {
+		cycleErr := &CycleError{}
+		for _, id := range cycle {
+			name, err := itemName(ctx, tx, kind, id)
+			if err != nil {
+				return err
+			}
+			cycleErr.Cycle = append(cycleErr.Cycle, name)
+		}
+		return cycleErr
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO Dependencies (userId, kind, prerequisiteId, dependentId) VALUES (?, ?, ?, ?)",
+		userId, kind, prerequisite, dependent)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Debug("Adding dependency", "kind", kind, "prerequisite", prerequisite, "dependent", dependent)
+	notifyItem(userId, kind, dependent, dayId, eventId)
+	return nil
+}
+
+// DeleteDependency removes a prerequisite of an event or a todo, the dependent doesn't
+// wait for it anymore
+func DeleteDependency(ctx context.Context, userId int, kind ItemKind, dependent, dependencyId int64) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	dayId, eventId, err := itemOwner(ctx, db, userId, kind, dependent)
+	if err != nil {
+		return err
+	}
+
+	res, err := db.ExecContext(ctx, "DELETE FROM Dependencies WHERE id=? AND userId=? AND kind=? AND dependentId=?",
+		dependencyId, userId, kind, dependent)
+	if err != nil {
+		return err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+
+	notifyItem(userId, kind, dependent, dayId, eventId)
+	return nil
+}
+
+// GetUnblocked returns the days of a user with only the events and todos that can be
+// worked on now: they aren't done and everything they depend on is. Todos of blocked
+// events are blocked too. Days with nothing left to do are left out.
+func GetUnblocked(ctx context.Context, userId int, now time.Time) ([]Day, error) {
+	days, err := GetDays(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var unblocked []Day
+	for _, day := range days {
+		var events []Event
+		for _, event := range day.Events {
+			if event.Blocked() || event.Finished(now) {
+				continue
+			}
+
+			var todos []Todo
+			for _, todo := range event.TodoList {
+				if !todo.Done && !todo.Blocked() {
+					todos = append(todos, todo)
+				}
+			}
+			event.TodoList = todos
+			events = append(events, event)
+		}
+
+		if len(events) > 0 {
+			day.Events = events
+			unblocked = append(unblocked, day)
+		}
+	}
+
+	return unblocked, nil
+}
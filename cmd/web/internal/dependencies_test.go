@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// openTestDB creates an empty database in a temporary directory for the test
+func openTestDB(t *testing.T) storeDB {
+	t.Helper()
+	DBPath = filepath.Join(t.TempDir(), "app.db")
+	CreateDB()
+	t.Cleanup(func() {
+		CloseDB()
+		store = nil
+	})
+
+	db, err := openDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestAddDependencyCycle(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO Users (id, username, email, password) VALUES (1, 'user', 'user@example.com', '');
+		INSERT INTO Days (id, userId, date, startOfDay, endOfDay) VALUES (1, 1, '2026-10-19', '', '');
+		INSERT INTO Events (id, name, duration) VALUES (1, 'report', '1h'), (2, 'review', '1h'), (3, 'invoice', '1h');
+		INSERT INTO DayEvents (dayId, eventId) VALUES (1, 1), (1, 2), (1, 3);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The invoice waits for the review, which waits for the report
+	if err = AddDependency(ctx, 1, EventItem, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err = AddDependency(ctx, 1, EventItem, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	err = AddDependency(ctx, 1, EventItem, 3, 1)
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("err = %v, want a CycleError", err)
+	}
+	want := []string{"report", "invoice", "review", "report"}
+	if !reflect.DeepEqual(cycleErr.Cycle, want) {
+		t.Errorf("cycle = %v, want %v", cycleErr.Cycle, want)
+	}
+
+	var count int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM Dependencies").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("%d dependencies are stored, want 2 without the back edge", count)
+	}
+
+	// Adding a dependency again does nothing
+	if err = AddDependency(ctx, 1, EventItem, 1, 2); err != nil {
+		t.Errorf("adding a dependency twice: %v", err)
+	}
+}
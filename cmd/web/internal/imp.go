@@ -1,59 +1,108 @@
 package internal
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"fmt"
-	"html/template"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"github.com/Shu-AFK/TaskWeave/conflict"
+	"github.com/Shu-AFK/TaskWeave/priority"
 	"net/http"
 	"time"
 )
 
+// ItemKind tells whether an id refers to an event or a todo
+type ItemKind string
+
+const (
+	EventItem ItemKind = "event"
+	TodoItem  ItemKind = "todo"
+)
+
 type Todo struct {
+	ID          int64
+	EventID     int64
 	Name        string
 	Description string
 	Deadline    time.Time
 	Done        bool
+	Priority    priority.Priority
+	// SeriesTodoID links the copies of a todo that repeats with the occurrences of a series
+	SeriesTodoID int64
+	// Prerequisites are the todos this one waits for
+	Prerequisites []Prerequisite
+	// Tracked is the time of the time entries of the todo, Timing is set while a timer
+	// runs for it
+	Tracked time.Duration
+	Timing  bool
 }
 
 type Event struct {
+	ID       int64
+	DayID    int64
 	Name     string
 	Duration time.Duration
 	Deadline time.Time
 	Start    time.Time
 	End      time.Time
+	Priority priority.Priority
+	// Scheduled events got their start and end from the scheduler, which may move them
+	Scheduled bool
+	// AllowOverlap lets the event be booked at the same time as other events
+	AllowOverlap bool
+	// SeriesID is the series the event is an occurrence of, 0 for single events
+	SeriesID int64
+	// Occurrence is the start the rule of the series gives the occurrence. It identifies
+	// the occurrence even after it was moved to another time or day.
+	Occurrence time.Time
+	// Detached occurrences were changed on their own and keep their changes when the
+	// series is edited
+	Detached bool
+	// Rollovers counts how often the event was moved on because its day ended before it
+	// was finished. Missed is set for fixed events that ended unfinished and for flexible
+	// ones that no later day had room for.
+	Rollovers int
+	Missed    bool
+	// Done is set when the event was marked as done by hand, see Finished
+	Done bool
+	// Conflicts are found when the day of the event is loaded, they are not stored
+	Conflicts []conflict.Conflict
+	// Prerequisites are the events this one waits for
+	Prerequisites []Prerequisite
+	// Tracked is the time of the time entries of the event and its todos, Timing is set
+	// while a timer runs for the event itself
+	Tracked  time.Duration
+	Timing   bool
 	TodoList []Todo
 }
 
-type Day struct {
-	Date   time.Time
-	Events []Event
+// Fixed reports whether the event has a fixed start and end time. Events without one
+// only carry a duration and a deadline.
+func (e Event) Fixed() bool {
+	return !e.Start.IsZero()
 }
 
-type EventPage struct {
-	Day    Day
-	Events []Event
+// Postponed reports whether the event rolled over so often that it deserves a warning
+func (e Event) Postponed() bool {
+	return e.Rollovers >= postponedRollovers
 }
 
-// TemplateData holds data passed to templates
-type TemplateData struct {
-	Title       string
-	LinkToTasks string
+type Day struct {
+	ID         int64
+	Date       time.Time
+	StartOfDay time.Time
+	EndOfDay   time.Time
+	Events     []Event
 }
 
-// RenderTemplate renders HTML templates
-func RenderTemplate(w http.ResponseWriter, tmpl string, data TemplateData) {
-	tmplPath := fmt.Sprintf("templates/%s.html", tmpl)
-	t, err := template.ParseFiles(tmplPath)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+// Duration returns the length of the working window of the day
+func (d Day) Duration() time.Duration {
+	return d.EndOfDay.Sub(d.StartOfDay)
+}
 
-	err = t.Execute(w, data)
-	if err != nil {
-		return
-	}
+type EventPage struct {
+	Day    Day
+	Events []Event
 }
 
 func GenerateSessionID() (string, error) {
@@ -68,27 +117,28 @@ func GenerateSessionID() (string, error) {
 	return hex.EncodeToString(sessionId), nil
 }
 
-func SetSessionCookie(w http.ResponseWriter, username string) error {
-	id, err := GetUserIdByName(username)
+func SetSessionCookie(ctx context.Context, w http.ResponseWriter, username string) error {
+	id, err := GetUserIdByName(ctx, username)
 	if err != nil {
 		return err
 	}
 
-	exists, err := CheckIfSessionExists(id)
+	exists, err := CheckIfSessionExists(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	var sessionID string
 	if exists {
-		sessionID, err = GetSessionIDbyUserID(id)
+		sessionID, err = GetSessionIDbyUserID(ctx, id)
 	} else {
 		sessionID, err = GenerateSessionID()
 		if err != nil {
 			return err
 		}
 
-		err = SetSessionID(id, sessionID)
+		logging.FromContext(ctx).Debug("Creating new session", "user_id", id)
+		err = SetSessionID(ctx, id, sessionID)
 		if err != nil {
 			if err.Error() == "sessionID already exists" {
 				for err.Error() == "sessionID already exists" {
@@ -97,7 +147,8 @@ func SetSessionCookie(w http.ResponseWriter, username string) error {
 						return err
 					}
 
-					err = SetSessionID(id, sessionID)
+					logging.FromContext(ctx).Debug("Creating new session", "user_id", id)
+					err = SetSessionID(ctx, id, sessionID)
 					if err == nil {
 						break
 					}
@@ -111,9 +162,12 @@ func SetSessionCookie(w http.ResponseWriter, username string) error {
 	cookie := &http.Cookie{
 		Name:     "SessionID",
 		Value:    sessionID,
-		Secure:   true,
+		Secure:   Sessions.CookieSecure,
 		HttpOnly: true,
 		Path:     "/tasks",
+		Domain:   Sessions.CookieDomain,
+		SameSite: Sessions.CookieSameSite,
+		MaxAge:   int(Sessions.Lifetime.Seconds()),
 	}
 
 	http.SetCookie(w, cookie)
@@ -125,13 +179,14 @@ func CheckIfSessionIsCorrect(username string, r *http.Request) (bool, error) {
 	if err != nil {
 		return false, nil
 	}
+	ctx := r.Context()
 
-	userId, err := GetUserIdByName(username)
+	userId, err := GetUserIdByName(ctx, username)
 	if err != nil {
 		return false, err
 	}
 
-	storedSessionID, err := GetSessionIDbyUserID(userId)
+	storedSessionID, err := GetSessionIDbyUserID(ctx, userId)
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,207 @@
+package internal
+
+import (
+	"context"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/changes"
+	"strings"
+)
+
+// ToggleTodo flips the done state of a todo and returns the updated todo
+func ToggleTodo(ctx context.Context, userId int, todoId int64) (Todo, error) {
+	db, err := openDB()
+	if err != nil {
+		return Todo{}, err
+	}
+
+	eventId, err := eventOfTodo(ctx, db, userId, todoId)
+	if err != nil {
+		return Todo{}, err
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE Todos SET done = NOT COALESCE(done, 0) WHERE id=?", todoId)
+	if err != nil {
+		return Todo{}, err
+	}
+	notify(userId, changes.Updated, changes.Todo, todoId, 0, eventId)
+
+	return GetTodo(ctx, userId, todoId)
+}
+
+// ToggleEvent flips the done state of an event and returns the updated event
+func ToggleEvent(ctx context.Context, userId int, eventId int64) (Event, error) {
+	db, err := openDB()
+	if err != nil {
+		return Event{}, err
+	}
+
+	dayId, err := dayOfEvent(ctx, db, userId, eventId)
+	if err != nil {
+		return Event{}, err
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE Events SET done = NOT done WHERE id=?", eventId)
+	if err != nil {
+		return Event{}, err
+	}
+	notify(userId, changes.Updated, changes.Event, eventId, dayId, eventId)
+
+	return GetEvent(ctx, userId, eventId)
+}
+
+// RenameTodo changes only the name of a todo
+func RenameTodo(ctx context.Context, userId int, todoId int64, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ValidationErrors{"name": "Please enter a name"}
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	eventId, err := eventOfTodo(ctx, db, userId, todoId)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE Todos SET name=? WHERE id=?", name, todoId)
+	if err != nil {
+		return err
+	}
+
+	notify(userId, changes.Updated, changes.Todo, todoId, 0, eventId)
+	return nil
+}
+
+// RenameEvent changes only the name of an event
+func RenameEvent(ctx context.Context, userId int, eventId int64, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ValidationErrors{"name": "Please enter a title"}
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	dayId, err := dayOfEvent(ctx, db, userId, eventId)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE Events SET name=?, detached=(seriesId != 0) WHERE id=?", name, eventId)
+	if err != nil {
+		return err
+	}
+
+	notify(userId, changes.Updated, changes.Event, eventId, dayId, eventId)
+	return nil
+}
+
+// MoveTodo moves a todo up (negative delta) or down (positive delta) within its event.
+// It returns the id of the event.
+func MoveTodo(ctx context.Context, userId int, todoId int64, delta int) (int64, error) {
+	db, err := openDB()
+	if err != nil {
+		return 0, err
+	}
+
+	eventId, err := eventOfTodo(ctx, db, userId, todoId)
+	if err != nil {
+		return 0, err
+	}
+
+	err = move(ctx, db, `
+		SELECT t.id FROM Todos t JOIN EventTodos et ON et.todoId = t.id
+		WHERE et.eventId=? ORDER BY t.position, t.id
+	`, eventId, "UPDATE Todos SET position=? WHERE id=?", todoId, delta)
+	if err != nil {
+		return 0, err
+	}
+
+	// The order of the todos is part of the event
+	notify(userId, changes.Updated, changes.Event, eventId, 0, eventId)
+	return eventId, nil
+}
+
+// MoveEvent moves an event up (negative delta) or down (positive delta) within its day.
+// It returns the id of the day.
+func MoveEvent(ctx context.Context, userId int, eventId int64, delta int) (int64, error) {
+	db, err := openDB()
+	if err != nil {
+		return 0, err
+	}
+
+	dayId, err := dayOfEvent(ctx, db, userId, eventId)
+	if err != nil {
+		return 0, err
+	}
+
+	err = move(ctx, db, `
+		SELECT e.id FROM Events e JOIN DayEvents de ON de.eventId = e.id
+		WHERE de.dayId=? ORDER BY e.position, e.id
+	`, dayId, "UPDATE Events SET position=? WHERE id=?", eventId, delta)
+	if err != nil {
+		return 0, err
+	}
+
+	// The order of the events is part of the day
+	notify(userId, changes.Updated, changes.Day, dayId, dayId, 0)
+	return dayId, nil
+}
+
+// move loads the ordered ids of all siblings, moves id by delta places and writes back
+// the new positions
+func move(ctx context.Context, db storeDB, siblingsQuery string, parentId int64, updateQuery string, id int64, delta int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, siblingsQuery, parentId)
+	if err != nil {
+		return err
+	}
+
+	var ids []int64
+	from := -1
+	for rows.Next() {
+		var sibling int64
+		err = rows.Scan(&sibling)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		if sibling == id {
+			from = len(ids)
+		}
+		ids = append(ids, sibling)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if from == -1 {
+		return ErrNotFound
+	}
+
+	to := min(max(from+delta, 0), len(ids)-1)
+	if to == from {
+		return nil
+	}
+
+	ids = append(ids[:from], ids[from+1:]...)
+	ids = append(ids[:to], append([]int64{id}, ids[to:]...)...)
+
+	for position, sibling := range ids {
+		_, err = tx.ExecContext(ctx, updateQuery, position+1, sibling)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/metrics"
+	"strings"
+	"time"
+)
+
+// storeDB wraps the connection pool to record the latency and errors of every query
+type storeDB struct {
+	*sql.DB
+}
+
+// storeTx wraps a transaction of storeDB in the same way
+type storeTx struct {
+	*sql.Tx
+}
+
+// querier is implemented by storeDB and storeTx, helpers that take it run inside or
+// outside of a transaction
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// queryOp returns the statement type of a query, e.g. select or insert
+func queryOp(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+
+	switch op := strings.ToLower(fields[0]); op {
+	case "select", "insert", "update", "delete":
+		return op
+	}
+	return "other"
+}
+
+func observeQuery(query string, start time.Time, err error) {
+	op := queryOp(query)
+	metrics.DBQueryDuration.Observe(time.Since(start).Seconds(), op)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		metrics.DBErrors.Inc(op)
+	}
+}
+
+func (db storeDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	observeQuery(query, start, err)
+	return rows, err
+}
+
+func (db storeDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	observeQuery(query, start, row.Err())
+	return row
+}
+
+func (db storeDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	res, err := db.DB.ExecContext(ctx, query, args...)
+	observeQuery(query, start, err)
+	return res, err
+}
+
+func (db storeDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (storeTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	return storeTx{tx}, err
+}
+
+func (tx storeTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	observeQuery(query, start, err)
+	return rows, err
+}
+
+func (tx storeTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	observeQuery(query, start, row.Err())
+	return row
+}
+
+func (tx storeTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	res, err := tx.Tx.ExecContext(ctx, query, args...)
+	observeQuery(query, start, err)
+	return res, err
+}
@@ -0,0 +1,25 @@
+package internal
+
+import "github.com/Shu-AFK/TaskWeave/cmd/web/changes"
+
+// notify tells the open pages of a user about a change, it is called once the change
+// is stored
+func notify(userId int, kind changes.Kind, entity changes.Entity, id int64, dayId int64, eventId int64) {
+	changes.Publish(changes.Change{
+		UserID:   userId,
+		Kind:     kind,
+		Entity:   entity,
+		EntityID: id,
+		DayID:    dayId,
+		EventID:  eventId,
+	})
+}
+
+// notifyItem tells the open pages about a change of an event or a todo
+func notifyItem(userId int, kind ItemKind, id, dayId, eventId int64) {
+	if kind == TodoItem {
+		notify(userId, changes.Updated, changes.Todo, id, dayId, eventId)
+		return
+	}
+	notify(userId, changes.Updated, changes.Event, id, dayId, eventId)
+}
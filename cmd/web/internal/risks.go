@@ -0,0 +1,237 @@
+package internal
+
+import (
+	"context"
+	"github.com/Shu-AFK/TaskWeave/schedule"
+	"sort"
+	"time"
+)
+
+// Risk is an event or a todo that is forecast to miss its deadline
+type Risk struct {
+	Kind ItemKind
+	ID   int64
+	Name string
+	// DayID and EventID tell where the item is
+	DayID    int64
+	EventID  int64
+	Deadline time.Time
+	// Finish is when the work is forecast to be done, it is zero if the planned days run
+	// out before
+	Finish time.Time
+	// Late is how long after the deadline the work is done, Short is the work that is
+	// still left at the deadline
+	Late  time.Duration
+	Short time.Duration
+}
+
+// Unplanned reports whether the planned days have no room for all of the work
+func (r Risk) Unplanned() bool {
+	return r.Finish.IsZero()
+}
+
+// workPiece is a part of the remaining work of an event. Flexible events have a piece for
+// every open todo, so that todos with an earlier deadline are forecast on their own, or
+// one for the whole event if it has no todos. Fixed events are done when they end and
+// have a single piece for all of their todos.
+type workPiece struct {
+	event Event
+	// todo is the index of the todo in the event, -1 for the whole event
+	todo     int
+	fixed    bool
+	work     time.Duration
+	progress schedule.Progress
+}
+
+func (p workPiece) deadline() time.Time {
+	deadline := p.event.Deadline
+	if p.todo < 0 {
+		return deadline
+	}
+	if todo := p.event.TodoList[p.todo].Deadline; deadline.IsZero() || (!todo.IsZero() && todo.Before(deadline)) {
+		return todo
+	}
+	return deadline
+}
+
+// remainingWork splits the unfinished work of the days into pieces. pieces holds the
+// indexes of the pieces of every event and every open todo.
+func remainingWork(days []Day, now time.Time) (work []workPiece, pieces map[ItemKind]map[int64][]int) {
+	pieces = map[ItemKind]map[int64][]int{EventItem: {}, TodoItem: {}}
+	add := func(p workPiece, todos ...int) {
+		i := len(work)
+		work = append(work, p)
+		pieces[EventItem][p.event.ID] = append(pieces[EventItem][p.event.ID], i)
+		for _, todo := range todos {
+			id := p.event.TodoList[todo].ID
+			pieces[TodoItem][id] = append(pieces[TodoItem][id], i)
+		}
+	}
+
+	for _, day := range days {
+		for _, event := range day.Events {
+			if event.Finished(now) {
+				continue
+			}
+
+			var open []int
+			for i, todo := range event.TodoList {
+				if !todo.Done {
+					open = append(open, i)
+				}
+			}
+			share := event.Duration
+			if len(event.TodoList) > 0 {
+				share /= time.Duration(len(event.TodoList))
+			}
+
+			switch {
+			case event.Fixed() && !replaceable(event, now):
+				left := event.Duration
+				if len(event.TodoList) > 0 {
+					left = share * time.Duration(len(open))
+				}
+				add(workPiece{event: event, todo: -1, fixed: true, work: left}, open...)
+			case len(open) == 0:
+				add(workPiece{event: event, todo: -1, work: event.Duration})
+			default:
+				for _, todo := range open {
+					add(workPiece{event: event, todo: todo, work: share}, todo)
+				}
+			}
+		}
+	}
+	return work, pieces
+}
+
+// ForecastRisks finds the events and todos that won't be done before their deadline if
+// the remaining work is done in the free time of the days that aren't over, without
+// booking a day beyond maxLoad of its working window. The work is forecast by the
+// earliest deadline first and never before the work it depends on, fixed events are done
+// when they end. The days have to be ordered by date, the risks are ordered by deadline.
+func ForecastRisks(days []Day, maxLoad float64, now time.Time) []Risk {
+	var forecastDays []schedule.Day
+	for _, day := range days {
+		if dayOver(day, now) {
+			continue
+		}
+
+		window := openWindow(day, now)
+		for _, event := range day.Events {
+			if event.Fixed() && !replaceable(event, now) {
+				window.Busy = append(window.Busy, schedule.Span{Start: event.Start, End: event.End})
+			}
+		}
+		forecastDays = append(forecastDays, window)
+	}
+
+	// The items of the forecast are the indexes of the pieces, the ids of events and
+	// todos would collide
+	work, pieces := remainingWork(days, now)
+	events := eventsByID(days)
+	var items []schedule.Item
+	for i, p := range work {
+		if p.fixed {
+			// Fixed events that ended unfinished wait for a new plan
+			p.progress = schedule.Progress{Left: p.work}
+			if p.event.End.After(now) {
+				p.progress = schedule.Progress{Work: []schedule.Span{{Start: p.event.Start, End: p.event.End}}}
+			}
+			work[i] = p
+			continue
+		}
+
+		item := scheduleItem(p.event, schedule.AnyDay, events, now)
+		after := item.After
+		item.ID, item.Duration, item.Deadline, item.After = int64(i), p.work, p.deadline(), nil
+		for _, id := range after {
+			for _, j := range pieces[EventItem][id] {
+				item.After = append(item.After, int64(j))
+			}
+		}
+		if p.todo >= 0 {
+			for _, prerequisite := range p.event.TodoList[p.todo].WaitingFor() {
+				for _, j := range pieces[TodoItem][prerequisite.ID] {
+					if !work[j].fixed {
+						item.After = append(item.After, int64(j))
+					} else if end := work[j].event.End; end.After(item.NotBefore) {
+						item.NotBefore = end
+					}
+				}
+			}
+		}
+		items = append(items, item)
+	}
+	for _, progress := range schedule.Forecast(forecastDays, items, maxLoad) {
+		work[progress.Item.ID].progress = progress
+	}
+
+	progressOf := func(kind ItemKind, id int64) []schedule.Progress {
+		var progress []schedule.Progress
+		for _, i := range pieces[kind][id] {
+			progress = append(progress, work[i].progress)
+		}
+		return progress
+	}
+
+	var risks []Risk
+	for _, day := range days {
+		for _, event := range day.Events {
+			if risk, ok := newRisk(progressOf(EventItem, event.ID), event.Deadline); ok {
+				risk.Kind, risk.ID, risk.Name, risk.DayID, risk.EventID = EventItem, event.ID, event.Name, event.DayID, event.ID
+				risks = append(risks, risk)
+			}
+			for _, todo := range event.TodoList {
+				if risk, ok := newRisk(progressOf(TodoItem, todo.ID), todo.Deadline); ok {
+					risk.Kind, risk.ID, risk.Name, risk.DayID, risk.EventID = TodoItem, todo.ID, todo.Name, event.DayID, event.ID
+					risks = append(risks, risk)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(risks, func(i, j int) bool { return risks[i].Deadline.Before(risks[j].Deadline) })
+	return risks
+}
+
+// newRisk tells whether the work of the progress misses the deadline and by how much
+func newRisk(progress []schedule.Progress, deadline time.Time) (Risk, bool) {
+	if deadline.IsZero() {
+		return Risk{}, false
+	}
+
+	risk := Risk{Deadline: deadline}
+	unplanned := false
+	for _, p := range progress {
+		risk.Short += p.LeftAt(deadline)
+		unplanned = unplanned || p.Left > 0
+		if finish := p.Finish(); finish.After(risk.Finish) {
+			risk.Finish = finish
+		}
+	}
+	if risk.Short <= 0 {
+		return Risk{}, false
+	}
+
+	if unplanned {
+		risk.Finish = time.Time{}
+	} else {
+		risk.Late = risk.Finish.Sub(deadline)
+	}
+	return risk, true
+}
+
+// GetRisks forecasts the events and todos of a user that are at risk of missing their
+// deadline with the maximum load of their settings
+func GetRisks(ctx context.Context, userId int, now time.Time) ([]Risk, error) {
+	settings, err := GetUserSettings(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	days, err := GetDays(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return ForecastRisks(days, settings.Load(), now), nil
+}
@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"context"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/changes"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"github.com/Shu-AFK/TaskWeave/schedule"
+	"time"
+)
+
+// postponedRollovers is how often an event may roll over before it is shown with a warning
+const postponedRollovers = 3
+
+// Rollover is what happens to the unfinished work of days that are over
+type Rollover struct {
+	// Days are the days of the user, the moves of Plan refer to their indexes
+	Days []Day
+	Plan schedule.Balanced
+	// Missed are the fixed events that ended with open todos, they stay on their day
+	Missed []Event
+	// Stuck are the flexible events of days that are over that no later day has room for.
+	// They stay on their day and are missed as well until a later rollover can move them.
+	Stuck []Event
+}
+
+// dayOver reports whether the working window of the day has ended
+func dayOver(day Day, now time.Time) bool {
+	return !day.EndOfDay.After(now)
+}
+
+// PlanRollover moves the unfinished flexible events of days that are over to the first
+// later day that has room for them without being booked beyond maxLoad. Unfinished fixed
+// events stay where they are and are missed instead, like the flexible ones that don't fit
+// anywhere. Events that are missed already aren't listed again. The days have to be
+// ordered by date.
+func PlanRollover(days []Day, maxLoad float64, now time.Time) Rollover {
+	var rollover Rollover
+	var rollDays []schedule.Day
+	var items []schedule.Item
+	events := eventsByID(days)
+	open := 0
+	for i, day := range days {
+		rollover.Days = append(rollover.Days, day)
+
+		if dayOver(day, now) {
+			open = i + 1
+			rollDays = append(rollDays, schedule.Day{Start: day.StartOfDay, End: day.EndOfDay})
+			for _, event := range day.Events {
+				switch {
+				case event.Finished(now):
+				case !event.Fixed() || event.Scheduled:
+					items = append(items, scheduleItem(event, i, events, now))
+				case !event.Missed:
+					rollover.Missed = append(rollover.Missed, event)
+				}
+			}
+			continue
+		}
+
+		window := openWindow(day, now)
+		for _, event := range day.Events {
+			switch {
+			case replaceable(event, now):
+				items = append(items, scheduleItem(event, i, events, now))
+			case event.Fixed():
+				window.Busy = append(window.Busy, schedule.Span{Start: event.Start, End: event.End})
+			}
+		}
+		rollDays = append(rollDays, window)
+	}
+
+	rollover.Plan = schedule.Rollover(rollDays, items, maxLoad, open)
+	for _, u := range rollover.Plan.Unplaced {
+		if event := events[u.Item.ID]; u.Item.Day < open && !event.Missed {
+			rollover.Stuck = append(rollover.Stuck, event)
+		}
+	}
+	return rollover
+}
+
+// RollOver applies PlanRollover to the days of a user with the maximum load of their
+// settings. Events that roll over lose the times the scheduler gave them and count the
+// rollover. The plan is made before the transaction, so every change only happens if the
+// event is still where the plan found it. Changes that were overtaken by an edit or
+// another rollover are left out of the result.
+func RollOver(ctx context.Context, userId int, now time.Time) (Rollover, error) {
+	settings, err := GetUserSettings(ctx, userId)
+	if err != nil {
+		return Rollover{}, err
+	}
+	days, err := GetDays(ctx, userId)
+	if err != nil {
+		return Rollover{}, err
+	}
+
+	rollover := PlanRollover(days, settings.Load(), now)
+	if len(rollover.Plan.Moves) == 0 && len(rollover.Missed) == 0 && len(rollover.Stuck) == 0 {
+		return rollover, nil
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return Rollover{}, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Rollover{}, err
+	}
+	defer tx.Rollback()
+
+	events := eventsByID(days)
+	changed := map[int64]bool{}
+	var moves []schedule.Move
+	for _, move := range rollover.Plan.Moves {
+		fromDay, toDay := rollover.Days[move.From].ID, rollover.Days[move.To].ID
+
+		// Like a rebalanced event it goes to the end of its new day and an occurrence of a
+		// series is detached. It has to be flexible and on its day yet, and the rollover
+		// count guards against another rollover that moved it in the meantime.
+		res, err := tx.ExecContext(ctx, `
+			UPDATE Events SET start='', end='', scheduled=0, detached=(seriesId != 0), rollovers=rollovers + 1, missed=0, position=(
+				SELECT COALESCE(MAX(e.position), 0) + 1 FROM Events e JOIN DayEvents de ON de.eventId = e.id
+				WHERE de.dayId=?
+			) WHERE id=? AND rollovers=? AND (start='' OR scheduled=1)
+			AND id IN (SELECT eventId FROM DayEvents WHERE dayId=?)
+		`, toDay, move.Item.ID, events[move.Item.ID].Rollovers, fromDay)
+		if err != nil {
+			return Rollover{}, err
+		}
+		updated, err := res.RowsAffected()
+		if err != nil {
+			return Rollover{}, err
+		}
+		if updated == 0 {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE DayEvents SET dayId=? WHERE eventId=? AND dayId=?", toDay, move.Item.ID, fromDay)
+		if err != nil {
+			return Rollover{}, err
+		}
+		moves = append(moves, move)
+		changed[fromDay], changed[toDay] = true, true
+	}
+
+	markMissed := func(planned []Event) ([]Event, error) {
+		var missed []Event
+		for _, event := range planned {
+			res, err := tx.ExecContext(ctx, `
+				UPDATE Events SET missed=1 WHERE id=? AND missed=0
+				AND id IN (SELECT eventId FROM DayEvents WHERE dayId=?)
+			`, event.ID, event.DayID)
+			if err != nil {
+				return nil, err
+			}
+			updated, err := res.RowsAffected()
+			if err != nil {
+				return nil, err
+			}
+			if updated == 0 {
+				continue
+			}
+			event.Missed = true
+			missed = append(missed, event)
+			changed[event.DayID] = true
+		}
+		return missed, nil
+	}
+	missed, err := markMissed(rollover.Missed)
+	if err != nil {
+		return Rollover{}, err
+	}
+	stuck, err := markMissed(rollover.Stuck)
+	if err != nil {
+		return Rollover{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Rollover{}, err
+	}
+	rollover.Plan.Moves, rollover.Missed, rollover.Stuck = moves, missed, stuck
+
+	logging.FromContext(ctx).Debug("Rolling over unfinished work", "user_id", userId,
+		"moved", len(moves), "missed", len(missed), "no_room", len(stuck))
+	for dayId := range changed {
+		notify(userId, changes.Updated, changes.Day, dayId, dayId, 0)
+	}
+	return rollover, nil
+}
+
+// RollOverAll rolls over the unfinished work of every user and returns how many events
+// moved, how many fixed ones were missed and how many flexible ones had no room
+func RollOverAll(ctx context.Context, now time.Time) (moved, missed, stuck int, err error) {
+	db, err := openDB()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id FROM Users")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	var users []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, 0, 0, err
+		}
+		users = append(users, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, 0, 0, err
+	}
+
+	for _, userId := range users {
+		rollover, err := RollOver(ctx, userId, now)
+		if err != nil {
+			return moved, missed, stuck, err
+		}
+		moved += len(rollover.Plan.Moves)
+		missed += len(rollover.Missed)
+		stuck += len(rollover.Stuck)
+	}
+	return moved, missed, stuck, nil
+}
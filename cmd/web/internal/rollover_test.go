@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlanRollover(t *testing.T) {
+	at := func(d, h int) time.Time {
+		return time.Date(2026, 10, 19+d, h, 0, 0, 0, time.UTC)
+	}
+	flexible := func(id int64, dayId int64, hours int, missed bool) Event {
+		return Event{ID: id, DayID: dayId, Name: "event", Duration: time.Duration(hours) * time.Hour, Missed: missed}
+	}
+	// Yesterday is over, tomorrow has room for 6.4 hours at a maximum load of 80%
+	days := []Day{
+		{ID: 1, StartOfDay: at(-1, 9), EndOfDay: at(-1, 17), Events: []Event{
+			flexible(1, 1, 2, false),
+			flexible(2, 1, 7, false),
+			flexible(3, 1, 7, true),
+			{ID: 4, DayID: 1, Name: "meeting", Duration: time.Hour, Start: at(-1, 10), End: at(-1, 11),
+				TodoList: []Todo{{ID: 1, Name: "minutes"}}},
+		}},
+		{ID: 2, StartOfDay: at(1, 9), EndOfDay: at(1, 17)},
+	}
+
+	rollover := PlanRollover(days, 0.8, at(0, 12))
+
+	if len(rollover.Plan.Moves) != 1 || rollover.Plan.Moves[0].Item.ID != 1 || rollover.Plan.Moves[0].To != 1 {
+		t.Errorf("moves = %+v, want event 1 moved to tomorrow", rollover.Plan.Moves)
+	}
+	if len(rollover.Plan.Unplaced) != 2 {
+		t.Errorf("%d events are unplaced, want the 2 that are too long", len(rollover.Plan.Unplaced))
+	}
+	if len(rollover.Stuck) != 1 || rollover.Stuck[0].ID != 2 {
+		t.Errorf("stuck = %+v, want only event 2, event 3 is missed already", rollover.Stuck)
+	}
+	if len(rollover.Missed) != 1 || rollover.Missed[0].ID != 4 {
+		t.Errorf("missed = %+v, want the fixed event 4", rollover.Missed)
+	}
+}
+
+func TestPlanRolloverDone(t *testing.T) {
+	at := func(d, h int) time.Time {
+		return time.Date(2026, 10, 19+d, h, 0, 0, 0, time.UTC)
+	}
+	// Neither event has todos or an end, only the one marked as done is finished
+	days := []Day{
+		{ID: 1, StartOfDay: at(-1, 9), EndOfDay: at(-1, 17), Events: []Event{
+			{ID: 1, DayID: 1, Name: "done", Duration: time.Hour, Done: true},
+			{ID: 2, DayID: 1, Name: "open", Duration: time.Hour},
+		}},
+		{ID: 2, StartOfDay: at(1, 9), EndOfDay: at(1, 17)},
+	}
+
+	rollover := PlanRollover(days, 0.8, at(0, 12))
+
+	if len(rollover.Plan.Moves) != 1 || rollover.Plan.Moves[0].Item.ID != 2 {
+		t.Errorf("moves = %+v, want only the open event 2 moved", rollover.Plan.Moves)
+	}
+	if len(rollover.Plan.Unplaced) != 0 || len(rollover.Stuck) != 0 || len(rollover.Missed) != 0 {
+		t.Errorf("unplaced = %+v, stuck = %+v, missed = %+v, want none", rollover.Plan.Unplaced, rollover.Stuck, rollover.Missed)
+	}
+}
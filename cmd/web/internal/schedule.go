@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"context"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/changes"
+	"github.com/Shu-AFK/TaskWeave/schedule"
+	"time"
+)
+
+// scheduleStep rounds the start of the free time of today, so that events placed into
+// it start at 10:05 rather than at 10:03:27
+const scheduleStep = 5 * time.Minute
+
+// replaceable reports whether the scheduler may place the event. Events it placed
+// before are placed again as long as they haven't started yet.
+func replaceable(event Event, now time.Time) bool {
+	if !event.Fixed() {
+		return true
+	}
+	return event.Scheduled && event.Start.After(now)
+}
+
+// openWindow returns the working window of the day without the time before now
+func openWindow(day Day, now time.Time) schedule.Day {
+	earliest := now.Truncate(scheduleStep)
+	if earliest.Before(now) {
+		earliest = earliest.Add(scheduleStep)
+	}
+
+	window := schedule.Day{Start: day.StartOfDay, End: day.EndOfDay}
+	if window.Start.Before(earliest) {
+		window.Start = earliest
+	}
+	return window
+}
+
+// eventsByID returns the events of the days by their id
+func eventsByID(days []Day) map[int64]Event {
+	events := map[int64]Event{}
+	for _, day := range days {
+		for _, event := range day.Events {
+			events[event.ID] = event
+		}
+	}
+	return events
+}
+
+// scheduleItem turns a flexible event into an item of the scheduler. Flexible events it
+// waits for are scheduled along with it, fixed ones let it start once they have ended.
+func scheduleItem(event Event, day int, events map[int64]Event, now time.Time) schedule.Item {
+	item := schedule.Item{
+		ID:       event.ID,
+		Name:     event.Name,
+		Duration: event.Duration,
+		Deadline: event.Deadline,
+		Day:      day,
+		Priority: int(event.Priority.Quadrant()),
+	}
+	for _, p := range event.Prerequisites {
+		prerequisite, ok := events[p.ID]
+		switch {
+		case p.Done || !ok:
+		case replaceable(prerequisite, now):
+			item.After = append(item.After, p.ID)
+		case prerequisite.End.After(item.NotBefore):
+			item.NotBefore = prerequisite.End
+		}
+	}
+	return item
+}
+
+// PlanEvents places the flexible events of the days into the free time of their own day.
+// Time before now is never used and events never start before the events they depend on
+// have ended.
+func PlanEvents(days []Day, now time.Time) schedule.Plan {
+	var scheduleDays []schedule.Day
+	var items []schedule.Item
+	events := eventsByID(days)
+	for i, day := range days {
+		window := openWindow(day, now)
+
+		for _, event := range day.Events {
+			switch {
+			case replaceable(event, now):
+				items = append(items, scheduleItem(event, i, events, now))
+			case event.Fixed():
+				window.Busy = append(window.Busy, schedule.Span{Start: event.Start, End: event.End})
+			}
+		}
+		scheduleDays = append(scheduleDays, window)
+	}
+
+	return schedule.Schedule(scheduleDays, items)
+}
+
+// ScheduleEvents places the flexible events of a user and stores their times. Events that
+// were placed before but don't fit anymore lose their times again.
+func ScheduleEvents(ctx context.Context, userId int, now time.Time) (schedule.Plan, error) {
+	days, err := GetDays(ctx, userId)
+	if err != nil {
+		return schedule.Plan{}, err
+	}
+
+	plan := PlanEvents(days, now)
+	return plan, applyPlan(ctx, userId, days, plan)
+}
+
+func applyPlan(ctx context.Context, userId int, days []Day, plan schedule.Plan) error {
+	events := eventsByID(days)
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var changed []Event
+	for _, placement := range plan.Placed {
+		event := events[placement.Item.ID]
+		if event.Scheduled && event.Start.Equal(placement.Start) && event.End.Equal(placement.End) {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE Events SET start=?, end=?, scheduled=1 WHERE id=?",
+			formatTime(placement.Start), formatTime(placement.End), event.ID)
+		if err != nil {
+			return err
+		}
+		changed = append(changed, event)
+	}
+
+	for _, unplaced := range plan.Unplaced {
+		event := events[unplaced.Item.ID]
+		if !event.Scheduled {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE Events SET start='', end='', scheduled=0 WHERE id=?", event.ID)
+		if err != nil {
+			return err
+		}
+		changed = append(changed, event)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	for _, event := range changed {
+		notify(userId, changes.Updated, changes.Event, event.ID, event.DayID, event.ID)
+	}
+	return nil
+}
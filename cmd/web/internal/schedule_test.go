@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOpenWindow(t *testing.T) {
+	at := func(h, m, s int) time.Time {
+		return time.Date(2026, 10, 19, h, m, s, 0, time.UTC)
+	}
+	day := Day{StartOfDay: at(9, 0, 0), EndOfDay: at(17, 0, 0)}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"before the day the whole window is open", at(7, 12, 0), at(9, 0, 0)},
+		{"the start is rounded up to the next 5 minutes", at(10, 3, 27), at(10, 5, 0)},
+		{"a start on the step stays", at(10, 5, 0), at(10, 5, 0)},
+		{"seconds after a step round up", at(10, 5, 1), at(10, 10, 0)},
+		{"the last step of the hour rounds to the full hour", at(10, 58, 0), at(11, 0, 0)},
+		{"after the day nothing is open", at(18, 0, 0), at(18, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window := openWindow(day, tt.now)
+			if !window.Start.Equal(tt.want) {
+				t.Errorf("the window starts at %s, want %s", window.Start.Format("15:04:05"), tt.want.Format("15:04:05"))
+			}
+			if !window.End.Equal(day.EndOfDay) {
+				t.Errorf("the window ends at %s, want %s", window.End.Format("15:04:05"), day.EndOfDay.Format("15:04:05"))
+			}
+		})
+	}
+}
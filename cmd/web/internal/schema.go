@@ -0,0 +1,248 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrations holds every schema change in order. The index of a migration + 1 is the
+// schema version it produces, which is stored in SQLite's user_version pragma.
+// Never edit a migration that has been released, append a new one instead.
+var migrations = []string{
+	// 1: initial schema
+	`
+	CREATE TABLE IF NOT EXISTS Users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT UNIQUE,
+		email TEXT UNIQUE,
+		password TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS Sessions (
+	    sessionId TEXT PRIMARY KEY,
+	    userId INTEGER,
+	    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	    FOREIGN KEY(userId) REFERENCES Users(userId)
+	);
+
+	CREATE TABLE IF NOT EXISTS Todos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, 
+		name TEXT, 
+		description TEXT, 
+		deadline TEXT,
+		done BOOLEAN CHECK(done IN (0, 1))
+	);
+
+	CREATE TABLE IF NOT EXISTS Events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT, 
+		duration TEXT,
+		deadline TEXT,
+		start TEXT,
+		end TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS Days (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		userId INTEGER,
+		date TEXT,
+		FOREIGN KEY(userId) REFERENCES Users(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS EventTodos (
+		eventId INTEGER,
+		todoId INTEGER,
+		FOREIGN KEY(eventId) REFERENCES Events(id),
+		FOREIGN KEY(todoId) REFERENCES Todos(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS DayEvents (
+		dayId INTEGER,
+		eventId INTEGER,
+		FOREIGN KEY(dayId) REFERENCES Days(id),
+		FOREIGN KEY(eventId) REFERENCES Events(id)
+	);
+	`,
+	// 2: working window of a day
+	`
+	ALTER TABLE Days ADD COLUMN startOfDay TEXT;
+	ALTER TABLE Days ADD COLUMN endOfDay TEXT;
+	`,
+	// 3: user defined order of events and todos
+	`
+	ALTER TABLE Events ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE Todos ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+	UPDATE Events SET position = id;
+	UPDATE Todos SET position = id;
+	`,
+	// 4: idle timeout of sessions
+	`
+	ALTER TABLE Sessions ADD COLUMN lastSeen TIMESTAMP;
+	UPDATE Sessions SET lastSeen = createdAt;
+	`,
+	// 5: timezone and locale of a user, empty means the server default
+	`
+	ALTER TABLE Users ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+	ALTER TABLE Users ADD COLUMN locale TEXT NOT NULL DEFAULT '';
+	`,
+	// 6: events placed by the scheduler
+	`
+	ALTER TABLE Events ADD COLUMN scheduled INTEGER NOT NULL DEFAULT 0;
+	`,
+	// 7: urgency and importance of events and todos
+	`
+	ALTER TABLE Events ADD COLUMN urgent INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE Events ADD COLUMN important INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE Todos ADD COLUMN urgent INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE Todos ADD COLUMN important INTEGER NOT NULL DEFAULT 0;
+	`,
+	// 8: events that may overlap others
+	`
+	ALTER TABLE Events ADD COLUMN allowOverlap INTEGER NOT NULL DEFAULT 0;
+	`,
+	// 9: maximum load per day for balancing in percent, 0 means the server default
+	`
+	ALTER TABLE Users ADD COLUMN maxLoad INTEGER NOT NULL DEFAULT 0;
+	`,
+	// 10: recurring events, their occurrences are stored as events on the days that exist
+	`
+	CREATE TABLE IF NOT EXISTS Series (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		userId INTEGER,
+		name TEXT,
+		timezone TEXT NOT NULL DEFAULT '',
+		start TEXT,
+		timed INTEGER NOT NULL DEFAULT 0,
+		duration TEXT,
+		deadline TEXT NOT NULL DEFAULT '',
+		rrule TEXT,
+		exdates TEXT NOT NULL DEFAULT '',
+		urgent INTEGER NOT NULL DEFAULT 0,
+		important INTEGER NOT NULL DEFAULT 0,
+		allowOverlap INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY(userId) REFERENCES Users(id)
+	);
+	CREATE TABLE IF NOT EXISTS SeriesTodos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		seriesId INTEGER,
+		name TEXT,
+		description TEXT,
+		urgent INTEGER NOT NULL DEFAULT 0,
+		important INTEGER NOT NULL DEFAULT 0,
+		position INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY(seriesId) REFERENCES Series(id)
+	);
+	ALTER TABLE Events ADD COLUMN seriesId INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE Events ADD COLUMN occurrence TEXT NOT NULL DEFAULT '';
+	ALTER TABLE Events ADD COLUMN detached INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE Todos ADD COLUMN seriesTodoId INTEGER NOT NULL DEFAULT 0;
+	`,
+	// 11: dependencies between events or between todos, kind is 'event' or 'todo'
+	`
+	CREATE TABLE IF NOT EXISTS Dependencies (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		userId INTEGER,
+		kind TEXT,
+		prerequisiteId INTEGER,
+		dependentId INTEGER,
+		FOREIGN KEY(userId) REFERENCES Users(id)
+	);
+	`,
+	// 12: tracked time of events and todos, kind is 'event' or 'todo', running entries
+	// have no stop
+	`
+	CREATE TABLE IF NOT EXISTS TimeEntries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		userId INTEGER,
+		kind TEXT,
+		itemId INTEGER,
+		start TEXT,
+		stop TEXT NOT NULL DEFAULT '',
+		note TEXT NOT NULL DEFAULT '',
+		FOREIGN KEY(userId) REFERENCES Users(id)
+	);
+	`,
+	// 13: how often unfinished events rolled over to a later day, fixed ones are marked
+	// as missed instead
+	`
+	ALTER TABLE Events ADD COLUMN rollovers INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE Events ADD COLUMN missed INTEGER NOT NULL DEFAULT 0;
+	`,
+	// 14: events are done once they are marked so, even without todos or an end
+	`
+	ALTER TABLE Events ADD COLUMN done INTEGER NOT NULL DEFAULT 0;
+	`,
+}
+
+// SchemaVersion is the version the database has after all migrations ran
+var SchemaVersion = len(migrations)
+
+func schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// migrate brings the database schema up to SchemaVersion
+func migrate(ctx context.Context, db *sql.DB) error {
+	version, err := schemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, migrations[i])
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+
+		// PRAGMA does not support placeholders
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1))
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckSchema returns an error if the database can't be reached or its schema isn't at
+// SchemaVersion
+func CheckSchema(ctx context.Context) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	version, err := schemaVersion(ctx, db.DB)
+	if err != nil {
+		return err
+	}
+	if version != SchemaVersion {
+		return fmt.Errorf("schema version is %d, expected %d", version, SchemaVersion)
+	}
+
+	return nil
+}
@@ -0,0 +1,1081 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/changes"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"github.com/Shu-AFK/TaskWeave/priority"
+	"github.com/Shu-AFK/TaskWeave/recur"
+	"strings"
+	"time"
+)
+
+// Scope tells which occurrences of a series a change applies to
+type Scope string
+
+const (
+	ThisOccurrence       Scope = "this"
+	FollowingOccurrences Scope = "following"
+	AllOccurrences       Scope = "all"
+)
+
+// ParseScope reads the scope of a form, an empty value means ThisOccurrence
+func ParseScope(s string) (Scope, bool) {
+	switch scope := Scope(s); scope {
+	case "":
+		return ThisOccurrence, true
+	case ThisOccurrence, FollowingOccurrences, AllOccurrences:
+		return scope, true
+	}
+	return "", false
+}
+
+// Series is an event that repeats by a recurrence rule. Its occurrences are only stored
+// as events on the days that exist, all others are computed when they are asked for.
+type Series struct {
+	ID   int64
+	Name string
+	// Start is the first occurrence, the rule is expanded in its location. Flexible series
+	// start at midnight.
+	Start    time.Time
+	Timed    bool
+	Duration time.Duration
+	// Deadline is the time from the start of the date of an occurrence to its deadline,
+	// 0 means no deadline
+	Deadline     time.Duration
+	Rule         recur.Rule
+	ExDates      []time.Time
+	Priority     priority.Priority
+	AllowOverlap bool
+	Todos        []SeriesTodo
+}
+
+// SeriesTodo is a todo every occurrence of a series gets a copy of
+type SeriesTodo struct {
+	ID          int64
+	Name        string
+	Description string
+	Priority    priority.Priority
+}
+
+// Occurrence is an occurrence of a series. Event is its stored event, which is nil if
+// the user has no day for its date.
+type Occurrence struct {
+	Start time.Time
+	Event *Event
+}
+
+// Set returns the recurrence of the series
+func (s Series) Set() recur.Set {
+	return recur.Set{Start: s.Start, Rule: s.Rule, ExDates: s.ExDates}
+}
+
+// midnight returns the start of the date of t in the location of the series
+func (s Series) midnight(t time.Time) time.Time {
+	year, month, day := t.In(s.Start.Location()).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, s.Start.Location())
+}
+
+// seriesFrom makes a series that starts with event, which lies on date
+func seriesFrom(event Event, date time.Time, rule recur.Rule, loc *time.Location) Series {
+	s := Series{Start: time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc), Rule: rule}
+	s.apply(event, s.Start)
+	return s
+}
+
+// apply takes the fields of the series from event, the occurrence at the given time.
+// The first occurrence keeps its date, exception dates follow a new time of day.
+func (s *Series) apply(event Event, at time.Time) {
+	s.Name = event.Name
+	s.Duration = event.Duration
+	s.Priority = event.Priority
+	s.AllowOverlap = event.AllowOverlap
+	s.Timed = event.Fixed() && !event.Scheduled
+
+	loc := s.Start.Location()
+	year, month, day := s.Start.Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	if s.Timed {
+		hour, min, sec := event.Start.In(loc).Clock()
+		start = time.Date(year, month, day, hour, min, sec, 0, loc)
+	}
+	hour, min, sec := start.Clock()
+	for i, ex := range s.ExDates {
+		year, month, day := ex.Date()
+		s.ExDates[i] = time.Date(year, month, day, hour, min, sec, 0, loc)
+	}
+	s.Start = start
+
+	s.Deadline = 0
+	if !event.Deadline.IsZero() {
+		s.Deadline = event.Deadline.Sub(s.midnight(at))
+	}
+}
+
+// endBefore ends the series before the occurrence at. It returns the rule for the
+// occurrences from at on, which only differs by its COUNT, and the exception dates
+// among them.
+func (s *Series) endBefore(at time.Time) (recur.Rule, []time.Time) {
+	rest := s.Rule
+	if s.Rule.Count > 0 {
+		before := s.Set().Before(at)
+		rest.Count = s.Rule.Count - before
+		s.Rule.Count = before
+	} else {
+		s.Rule.Until = at.Add(-time.Second)
+	}
+
+	var kept, later []time.Time
+	for _, ex := range s.ExDates {
+		if ex.Before(at) {
+			kept = append(kept, ex)
+		} else {
+			later = append(later, ex)
+		}
+	}
+	s.ExDates = kept
+	return rest, later
+}
+
+// occurrence returns the event of the occurrence at t
+func (s Series) occurrence(t time.Time) Event {
+	event := Event{
+		Name:         s.Name,
+		Duration:     s.Duration,
+		Priority:     s.Priority,
+		AllowOverlap: s.AllowOverlap,
+		SeriesID:     s.ID,
+		Occurrence:   t,
+	}
+	if s.Timed {
+		event.Start = t
+		event.End = t.Add(s.Duration)
+	}
+	if s.Deadline != 0 {
+		event.Deadline = s.midnight(t).Add(s.Deadline)
+	}
+	return event
+}
+
+// dayOccurrence is an occurrence that falls on a day of the user
+type dayOccurrence struct {
+	start time.Time
+	dayId int64
+}
+
+// occurrencesOn returns the occurrences of the series on the days by their date. Only the
+// range of the days is expanded.
+func (s Series) occurrencesOn(days []Day) map[string]dayOccurrence {
+	found := map[string]dayOccurrence{}
+	if len(days) == 0 {
+		return found
+	}
+
+	byDate := map[string]int64{}
+	first, last := days[0].Date, days[0].Date
+	for _, day := range days {
+		byDate[day.Date.Format(dateLayout)] = day.ID
+		if day.Date.Before(first) {
+			first = day.Date
+		}
+		if day.Date.After(last) {
+			last = day.Date
+		}
+	}
+
+	loc := s.Start.Location()
+	from := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc)
+	to := time.Date(last.Year(), last.Month(), last.Day()+1, 0, 0, 0, 0, loc)
+	for _, t := range s.Set().Between(from, to) {
+		date := t.Format(dateLayout)
+		if dayId, ok := byDate[date]; ok {
+			found[date] = dayOccurrence{t, dayId}
+		}
+	}
+	return found
+}
+
+const seriesColumns = "id, name, timezone, start, timed, duration, deadline, rrule, exdates, urgent, important, allowOverlap"
+
+func scanSeries(row scanner) (Series, error) {
+	var s Series
+	var timezone, rule, exdates string
+	var start, duration, deadline sql.NullString
+
+	err := row.Scan(&s.ID, &s.Name, &timezone, &start, &s.Timed, &duration, &deadline, &rule, &exdates,
+		&s.Priority.Urgent, &s.Priority.Important, &s.AllowOverlap)
+	if err != nil {
+		return Series{}, err
+	}
+
+	loc := time.Local
+	if timezone != "" {
+		loc, err = time.LoadLocation(timezone)
+		if err != nil {
+			return Series{}, err
+		}
+	}
+
+	s.Start, err = parseTime(start)
+	if err != nil {
+		return Series{}, err
+	}
+	s.Start = s.Start.In(loc)
+	s.Duration, err = parseDuration(duration)
+	if err != nil {
+		return Series{}, err
+	}
+	s.Deadline, err = parseDuration(deadline)
+	if err != nil {
+		return Series{}, err
+	}
+	s.Rule, err = recur.Parse(rule, loc)
+	if err != nil {
+		return Series{}, err
+	}
+
+	for _, ex := range strings.Split(exdates, ",") {
+		if ex == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, ex)
+		if err != nil {
+			return Series{}, err
+		}
+		s.ExDates = append(s.ExDates, t.In(loc))
+	}
+
+	return s, nil
+}
+
+func formatExDates(exdates []time.Time) string {
+	var list []string
+	for _, ex := range exdates {
+		list = append(list, formatTime(ex))
+	}
+	return strings.Join(list, ",")
+}
+
+// getSeries returns a series of a user including its todos
+func getSeries(ctx context.Context, db storeDB, userId int, seriesId int64) (Series, error) {
+	row := db.QueryRowContext(ctx, "SELECT "+seriesColumns+" FROM Series WHERE id=? AND userId=?", seriesId, userId)
+	s, err := scanSeries(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Series{}, ErrNotFound
+		}
+		return Series{}, err
+	}
+
+	s.Todos, err = getSeriesTodos(ctx, db, s.ID)
+	if err != nil {
+		return Series{}, err
+	}
+	return s, nil
+}
+
+// allSeries returns all series of a user including their todos
+func allSeries(ctx context.Context, db storeDB, userId int) ([]Series, error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+seriesColumns+" FROM Series WHERE userId=? ORDER BY id", userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []Series
+	for rows.Next() {
+		s, err := scanSeries(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		all = append(all, s)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range all {
+		all[i].Todos, err = getSeriesTodos(ctx, db, all[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return all, nil
+}
+
+func getSeriesTodos(ctx context.Context, db storeDB, seriesId int64) ([]SeriesTodo, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, name, description, urgent, important FROM SeriesTodos WHERE seriesId=? ORDER BY position, id
+	`, seriesId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var todos []SeriesTodo
+	for rows.Next() {
+		var todo SeriesTodo
+		var description sql.NullString
+		err = rows.Scan(&todo.ID, &todo.Name, &description, &todo.Priority.Urgent, &todo.Priority.Important)
+		if err != nil {
+			return nil, err
+		}
+		todo.Description = description.String
+		todos = append(todos, todo)
+	}
+
+	return todos, rows.Err()
+}
+
+// listDays returns the days of a user without their events
+func listDays(ctx context.Context, db storeDB, userId int) ([]Day, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, date, startOfDay, endOfDay FROM Days WHERE userId=? ORDER BY date", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []Day
+	for rows.Next() {
+		day, err := scanDay(rows)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
+
+func insertSeries(ctx context.Context, tx storeTx, userId int, s *Series) error {
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO Series (userId, name, timezone, start, timed, duration, deadline, rrule, exdates, urgent, important, allowOverlap)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, userId, s.Name, s.Start.Location().String(), formatTime(s.Start), boolToInt(s.Timed), s.Duration.String(),
+		s.Deadline.String(), s.Rule.String(), formatExDates(s.ExDates),
+		boolToInt(s.Priority.Urgent), boolToInt(s.Priority.Important), boolToInt(s.AllowOverlap))
+	if err != nil {
+		return err
+	}
+
+	s.ID, err = res.LastInsertId()
+	return err
+}
+
+func updateSeries(ctx context.Context, tx storeTx, s Series) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE Series SET name=?, start=?, timed=?, duration=?, deadline=?, rrule=?, exdates=?, urgent=?, important=?, allowOverlap=?
+		WHERE id=?
+	`, s.Name, formatTime(s.Start), boolToInt(s.Timed), s.Duration.String(), s.Deadline.String(), s.Rule.String(),
+		formatExDates(s.ExDates), boolToInt(s.Priority.Urgent), boolToInt(s.Priority.Important),
+		boolToInt(s.AllowOverlap), s.ID)
+	return err
+}
+
+func insertSeriesTodo(ctx context.Context, tx storeTx, seriesId int64, todo *SeriesTodo) error {
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO SeriesTodos (seriesId, name, description, urgent, important, position)
+		VALUES (?, ?, ?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM SeriesTodos WHERE seriesId=?))
+	`, seriesId, todo.Name, todo.Description, boolToInt(todo.Priority.Urgent), boolToInt(todo.Priority.Important), seriesId)
+	if err != nil {
+		return err
+	}
+
+	todo.ID, err = res.LastInsertId()
+	return err
+}
+
+// insertTodoCopy adds a copy of a todo of a series to an occurrence
+func insertTodoCopy(ctx context.Context, tx storeTx, eventId int64, todo SeriesTodo) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO Todos (name, description, deadline, done, urgent, important, seriesTodoId, position)
+		VALUES (?, ?, '', 0, ?, ?, ?, (SELECT COALESCE(MAX(t.position), 0) + 1 FROM Todos t JOIN EventTodos et ON et.todoId = t.id WHERE et.eventId=?))
+	`, todo.Name, todo.Description, boolToInt(todo.Priority.Urgent), boolToInt(todo.Priority.Important), todo.ID, eventId)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO EventTodos (eventId, todoId) VALUES (?, ?)", eventId, id)
+	return id, err
+}
+
+// insertOccurrence stores the occurrence at t with copies of the todos of the series
+func insertOccurrence(ctx context.Context, tx storeTx, s Series, dayId int64, t time.Time) error {
+	event := s.occurrence(t)
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO Events (name, duration, deadline, start, end, urgent, important, allowOverlap, seriesId, occurrence, position)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(e.position), 0) + 1 FROM Events e JOIN DayEvents de ON de.eventId = e.id WHERE de.dayId=?))
+	`, event.Name, event.Duration.String(), formatTime(event.Deadline), formatTime(event.Start), formatTime(event.End),
+		boolToInt(event.Priority.Urgent), boolToInt(event.Priority.Important), boolToInt(event.AllowOverlap),
+		s.ID, formatTime(t), dayId)
+	if err != nil {
+		return err
+	}
+
+	eventId, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO DayEvents (dayId, eventId) VALUES (?, ?)", dayId, eventId)
+	if err != nil {
+		return err
+	}
+
+	for _, todo := range s.Todos {
+		_, err = insertTodoCopy(ctx, tx, eventId, todo)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateOccurrence overwrites a stored occurrence with the fields of the series. Flexible
+// occurrences lose the times the scheduler gave them.
+func updateOccurrence(ctx context.Context, tx storeTx, eventId int64, event Event) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE Events SET name=?, duration=?, deadline=?, start=?, end=?, scheduled=0, urgent=?, important=?, allowOverlap=?,
+			occurrence=?, detached=0
+		WHERE id=?
+	`, event.Name, event.Duration.String(), formatTime(event.Deadline), formatTime(event.Start), formatTime(event.End),
+		boolToInt(event.Priority.Urgent), boolToInt(event.Priority.Important), boolToInt(event.AllowOverlap),
+		formatTime(event.Occurrence), eventId)
+	return err
+}
+
+// storedOccurrence is an event of a series as it is stored
+type storedOccurrence struct {
+	eventId    int64
+	dayId      int64
+	occurrence time.Time
+	detached   bool
+}
+
+func storedOccurrences(ctx context.Context, tx storeTx, seriesId int64) ([]storedOccurrence, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT e.id, de.dayId, e.occurrence, e.detached FROM Events e JOIN DayEvents de ON de.eventId = e.id
+		WHERE e.seriesId=?
+	`, seriesId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stored []storedOccurrence
+	for rows.Next() {
+		var row storedOccurrence
+		var occurrence sql.NullString
+		err = rows.Scan(&row.eventId, &row.dayId, &occurrence, &row.detached)
+		if err != nil {
+			return nil, err
+		}
+		row.occurrence, err = parseTime(occurrence)
+		if err != nil {
+			return nil, err
+		}
+		stored = append(stored, row)
+	}
+
+	return stored, rows.Err()
+}
+
+// syncSeries brings the stored occurrences from the date of from on in line with the
+// series and returns the days that changed. Occurrences are matched by their date, so
+// detached ones that were moved to another day are still found. Detached occurrences
+// keep their changes, they only lose their series once it doesn't have them anymore.
+func syncSeries(ctx context.Context, tx storeTx, s Series, days []Day, from time.Time) (map[int64]bool, error) {
+	since := from.In(s.Start.Location()).Format(dateLayout)
+	var later []Day
+	for _, day := range days {
+		if day.Date.Format(dateLayout) >= since {
+			later = append(later, day)
+		}
+	}
+	expected := s.occurrencesOn(later)
+
+	stored, err := storedOccurrences(ctx, tx, s.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[int64]bool{}
+	for _, row := range stored {
+		date := row.occurrence.In(s.Start.Location()).Format(dateLayout)
+		if date < since {
+			continue
+		}
+		want, ok := expected[date]
+		delete(expected, date)
+
+		switch {
+		case !ok && row.detached:
+			_, err = tx.ExecContext(ctx, "UPDATE Events SET seriesId=0, occurrence='', detached=0 WHERE id=?", row.eventId)
+		case !ok:
+			err = deleteEventRows(ctx, tx, row.eventId)
+		case row.detached:
+			_, err = tx.ExecContext(ctx, "UPDATE Events SET occurrence=? WHERE id=?", formatTime(want.start), row.eventId)
+		default:
+			err = updateOccurrence(ctx, tx, row.eventId, s.occurrence(want.start))
+		}
+		if err != nil {
+			return nil, err
+		}
+		changed[row.dayId] = true
+	}
+
+	for _, want := range expected {
+		err = insertOccurrence(ctx, tx, s, want.dayId, want.start)
+		if err != nil {
+			return nil, err
+		}
+		changed[want.dayId] = true
+	}
+	return changed, nil
+}
+
+// notifyDays tells the clients of a user that the days changed
+func notifyDays(userId int, days map[int64]bool) {
+	for dayId := range days {
+		notify(userId, changes.Updated, changes.Day, dayId, dayId, 0)
+	}
+}
+
+// addOccurrences stores the occurrences of all series of a user that fall on a new day
+func addOccurrences(ctx context.Context, db storeDB, userId int, day Day) error {
+	all, err := allSeries(ctx, db, userId)
+	if err != nil || len(all) == 0 {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, s := range all {
+		if want, ok := s.occurrencesOn([]Day{day})[day.Date.Format(dateLayout)]; ok {
+			err = insertOccurrence(ctx, tx, s, day.ID, want.start)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetSeries returns a series of a user including its todos
+func GetSeries(ctx context.Context, userId int, seriesId int64) (Series, error) {
+	db, err := openDB()
+	if err != nil {
+		return Series{}, err
+	}
+
+	return getSeries(ctx, db, userId, seriesId)
+}
+
+// SeriesOccurrences returns the occurrences of a series that start from from up to but
+// not including to. Only this range of the rule is expanded, so series without an end
+// can be looked at as well.
+func SeriesOccurrences(ctx context.Context, userId int, seriesId int64, from, to time.Time) (Series, []Occurrence, error) {
+	db, err := openDB()
+	if err != nil {
+		return Series{}, nil, err
+	}
+
+	s, err := getSeries(ctx, db, userId, seriesId)
+	if err != nil {
+		return Series{}, nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, occurrence FROM Events WHERE seriesId=?", s.ID)
+	if err != nil {
+		return Series{}, nil, err
+	}
+	byDate := map[string]int64{}
+	for rows.Next() {
+		var eventId int64
+		var occurrence sql.NullString
+		err = rows.Scan(&eventId, &occurrence)
+		if err != nil {
+			rows.Close()
+			return Series{}, nil, err
+		}
+		t, err := parseTime(occurrence)
+		if err != nil {
+			rows.Close()
+			return Series{}, nil, err
+		}
+		byDate[t.In(s.Start.Location()).Format(dateLayout)] = eventId
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return Series{}, nil, err
+	}
+
+	var occurrences []Occurrence
+	for _, t := range s.Set().Between(from, to) {
+		occurrence := Occurrence{Start: t}
+		if eventId, ok := byDate[t.Format(dateLayout)]; ok {
+			event, err := GetEvent(ctx, userId, eventId)
+			if err != nil {
+				return Series{}, nil, err
+			}
+			occurrence.Event = &event
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+
+	return s, occurrences, nil
+}
+
+// AddSeries stores event as the first occurrence of a new series and adds the other
+// occurrences to the days that exist. Only the day of the event is checked for conflicts,
+// the other occurrences show theirs like any other event.
+func AddSeries(ctx context.Context, userId int, event *Event, rule recur.Rule, loc *time.Location) error {
+	err := AddEvent(ctx, userId, event)
+	if err != nil {
+		return err
+	}
+
+	return startSeries(ctx, userId, event, rule, loc)
+}
+
+// startSeries turns a stored event into the first occurrence of a new series
+func startSeries(ctx context.Context, userId int, event *Event, rule recur.Rule, loc *time.Location) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	days, err := listDays(ctx, db, userId)
+	if err != nil {
+		return err
+	}
+
+	var date time.Time
+	for _, day := range days {
+		if day.ID == event.DayID {
+			date = day.Date
+		}
+	}
+	if date.IsZero() {
+		return ErrNotFound
+	}
+	s := seriesFrom(*event, date, rule, loc)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertSeries(ctx, tx, userId, &s)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE Events SET seriesId=?, occurrence=?, detached=0 WHERE id=?",
+		s.ID, formatTime(s.Start), event.ID)
+	if err != nil {
+		return err
+	}
+
+	changed, err := syncSeries(ctx, tx, s, days, s.Start)
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Debug("Starting series", "series_id", s.ID, "rule", s.Rule.String())
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	event.SeriesID, event.Occurrence = s.ID, s.Start
+	notifyDays(userId, changed)
+	return nil
+}
+
+// UpdateOccurrences changes an event and, depending on the scope, the other occurrences
+// of its series. ThisOccurrence detaches the event from the series, FollowingOccurrences
+// ends the series before the event and continues it with a new one that has the changes
+// and AllOccurrences changes the whole series. Detached occurrences keep their changes.
+// A rule turns a single event into a series and replaces the rule of a series.
+func UpdateOccurrences(ctx context.Context, userId int, event Event, scope Scope, rule *recur.Rule, loc *time.Location) error {
+	if event.SeriesID == 0 {
+		err := UpdateEvent(ctx, userId, event)
+		if err != nil || rule == nil {
+			return err
+		}
+		return startSeries(ctx, userId, &event, *rule, loc)
+	}
+	if scope == ThisOccurrence {
+		if rule != nil {
+			return ValidationErrors{"scope": "The rule can only be changed for the following or all occurrences"}
+		}
+		return UpdateEvent(ctx, userId, event)
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	err = checkEvent(ctx, db, userId, &event)
+	if err != nil {
+		return err
+	}
+	s, err := getSeries(ctx, db, userId, event.SeriesID)
+	if err != nil {
+		return err
+	}
+	days, err := listDays(ctx, db, userId)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The edited occurrence takes the changes even if it was detached before
+	_, err = tx.ExecContext(ctx, "UPDATE Events SET detached=0 WHERE id=?", event.ID)
+	if err != nil {
+		return err
+	}
+
+	at := event.Occurrence.In(s.Start.Location())
+	var changed map[int64]bool
+	if scope == AllOccurrences || !at.After(s.Start) {
+		s.apply(event, at)
+		if rule != nil {
+			s.Rule = *rule
+		}
+		err = updateSeries(ctx, tx, s)
+		if err != nil {
+			return err
+		}
+		changed, err = syncSeries(ctx, tx, s, days, s.Start)
+	} else {
+		changed, err = splitSeries(ctx, tx, userId, s, event, rule, days)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	notifyDays(userId, changed)
+	return nil
+}
+
+// splitSeries ends s before the occurrence of event and continues it with a new series
+// that has the changes of event. The stored occurrences from event on and the copies of
+// the todos in them move to the new series.
+func splitSeries(ctx context.Context, tx storeTx, userId int, s Series, event Event, rule *recur.Rule, days []Day) (map[int64]bool, error) {
+	at := event.Occurrence.In(s.Start.Location())
+	next := s
+	next.ID, next.Start, next.Todos = 0, at, nil
+	next.Rule, next.ExDates = s.endBefore(at)
+	next.apply(event, at)
+	if rule != nil {
+		next.Rule = *rule
+	}
+
+	err := insertSeries(ctx, tx, userId, &next)
+	if err != nil {
+		return nil, err
+	}
+	err = updateSeries(ctx, tx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	moved := map[int64]int64{}
+	for _, todo := range s.Todos {
+		copied := todo
+		err = insertSeriesTodo(ctx, tx, next.ID, &copied)
+		if err != nil {
+			return nil, err
+		}
+		moved[todo.ID] = copied.ID
+		next.Todos = append(next.Todos, copied)
+	}
+
+	stored, err := storedOccurrences(ctx, tx, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range stored {
+		if row.occurrence.Before(at) {
+			continue
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE Events SET seriesId=? WHERE id=?", next.ID, row.eventId)
+		if err != nil {
+			return nil, err
+		}
+		for from, to := range moved {
+			_, err = tx.ExecContext(ctx, `
+				UPDATE Todos SET seriesTodoId=? WHERE seriesTodoId=? AND id IN (SELECT todoId FROM EventTodos WHERE eventId=?)
+			`, to, from, row.eventId)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	logging.FromContext(ctx).Debug("Splitting series", "series_id", s.ID, "new_series_id", next.ID)
+	return syncSeries(ctx, tx, next, days, at)
+}
+
+// DeleteOccurrences removes an event. For an occurrence of a series the scope tells which
+// occurrences go: ThisOccurrence adds an exception date to the series,
+// FollowingOccurrences ends the series before the event and AllOccurrences removes the
+// series with all of its occurrences.
+func DeleteOccurrences(ctx context.Context, userId int, eventId int64, scope Scope) error {
+	event, err := GetEvent(ctx, userId, eventId)
+	if err != nil {
+		return err
+	}
+	if event.SeriesID == 0 {
+		return DeleteEvent(ctx, userId, eventId)
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	s, err := getSeries(ctx, db, userId, event.SeriesID)
+	if err != nil {
+		return err
+	}
+
+	at := event.Occurrence.In(s.Start.Location())
+	if scope == FollowingOccurrences && !at.After(s.Start) {
+		scope = AllOccurrences
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stored, err := storedOccurrences(ctx, tx, s.ID)
+	if err != nil {
+		return err
+	}
+
+	changed := map[int64]bool{}
+	switch scope {
+	case ThisOccurrence:
+		s.ExDates = append(s.ExDates, at)
+		err = updateSeries(ctx, tx, s)
+		if err != nil {
+			return err
+		}
+		err = deleteEventRows(ctx, tx, eventId)
+		if err != nil {
+			return err
+		}
+		changed[event.DayID] = true
+	case FollowingOccurrences:
+		s.endBefore(at)
+		err = updateSeries(ctx, tx, s)
+		if err != nil {
+			return err
+		}
+		for _, row := range stored {
+			if !row.occurrence.Before(at) {
+				err = deleteEventRows(ctx, tx, row.eventId)
+				if err != nil {
+					return err
+				}
+				changed[row.dayId] = true
+			}
+		}
+	default:
+		for _, row := range stored {
+			err = deleteEventRows(ctx, tx, row.eventId)
+			if err != nil {
+				return err
+			}
+			changed[row.dayId] = true
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM SeriesTodos WHERE seriesId=?", s.ID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM Series WHERE id=?", s.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	logging.FromContext(ctx).Debug("Deleting occurrences", "series_id", s.ID, "event_id", eventId, "scope", string(scope))
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	notifyDays(userId, changed)
+	return nil
+}
+
+// AddRepeatingTodo adds a todo to an occurrence and to the occurrences of its series that
+// follow it, including the ones that are added later. For single events it is the same
+// as AddTodo.
+func AddRepeatingTodo(ctx context.Context, userId int, todo *Todo) error {
+	errs := ValidateTodo(*todo)
+	if !todo.Deadline.IsZero() {
+		errs["deadline"] = "Todos that repeat can't have a deadline"
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	event, err := GetEvent(ctx, userId, todo.EventID)
+	if err != nil {
+		return err
+	}
+	if event.SeriesID == 0 {
+		return AddTodo(ctx, userId, todo)
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	s, err := getSeries(ctx, db, userId, event.SeriesID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	template := SeriesTodo{Name: todo.Name, Description: todo.Description, Priority: todo.Priority}
+	err = insertSeriesTodo(ctx, tx, s.ID, &template)
+	if err != nil {
+		return err
+	}
+
+	stored, err := storedOccurrences(ctx, tx, s.ID)
+	if err != nil {
+		return err
+	}
+	changed := map[int64]bool{}
+	for _, row := range stored {
+		if row.occurrence.Before(event.Occurrence) {
+			continue
+		}
+		id, err := insertTodoCopy(ctx, tx, row.eventId, template)
+		if err != nil {
+			return err
+		}
+		if row.eventId == event.ID {
+			todo.ID, todo.SeriesTodoID = id, template.ID
+		}
+		changed[row.dayId] = true
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	notifyDays(userId, changed)
+	return nil
+}
+
+// DeleteRepeatingTodo removes a todo that repeats with a series from its occurrence and
+// the occurrences that follow it. Earlier occurrences keep their copy as a plain todo.
+func DeleteRepeatingTodo(ctx context.Context, userId int, todoId int64) error {
+	todo, err := GetTodo(ctx, userId, todoId)
+	if err != nil {
+		return err
+	}
+	if todo.SeriesTodoID == 0 {
+		return DeleteTodo(ctx, userId, todoId)
+	}
+
+	event, err := GetEvent(ctx, userId, todo.EventID)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stored, err := storedOccurrences(ctx, tx, event.SeriesID)
+	if err != nil {
+		return err
+	}
+	changed := map[int64]bool{}
+	for _, row := range stored {
+		if row.occurrence.Before(event.Occurrence) {
+			continue
+		}
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM EventTodos WHERE eventId=? AND todoId IN (SELECT id FROM Todos WHERE seriesTodoId=?)
+		`, row.eventId, todo.SeriesTodoID)
+		if err != nil {
+			return err
+		}
+		changed[row.dayId] = true
+	}
+
+	err = deleteItemRows(ctx, tx, TodoItem,
+		"SELECT id FROM Todos WHERE seriesTodoId=? AND id NOT IN (SELECT todoId FROM EventTodos)", todo.SeriesTodoID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM Todos WHERE seriesTodoId=? AND id NOT IN (SELECT todoId FROM EventTodos)
+	`, todo.SeriesTodoID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE Todos SET seriesTodoId=0 WHERE seriesTodoId=?", todo.SeriesTodoID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM SeriesTodos WHERE id=?", todo.SeriesTodoID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	notifyDays(userId, changed)
+	return nil
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SessionSettings control the session cookie and how long a session stays valid
+type SessionSettings struct {
+	CookieSecure   bool
+	CookieDomain   string
+	CookieSameSite http.SameSite
+	// Lifetime is the time after which a session expires, counted from the login
+	Lifetime time.Duration
+	// IdleTimeout ends sessions which weren't used for that long, 0 disables it
+	IdleTimeout time.Duration
+}
+
+// Sessions holds the settings used for all sessions, it is set once at startup
+var Sessions = SessionSettings{
+	CookieSecure:   true,
+	CookieSameSite: http.SameSiteLaxMode,
+	Lifetime:       30 * 24 * time.Hour,
+}
+
+// validSessionCondition returns an SQL condition on the Sessions table which only
+// matches sessions that didn't expire yet
+func validSessionCondition() string {
+	condition := fmt.Sprintf("createdAt > datetime('now', '-%d seconds')", int(Sessions.Lifetime.Seconds()))
+	if Sessions.IdleTimeout > 0 {
+		condition += fmt.Sprintf(" AND COALESCE(lastSeen, createdAt) > datetime('now', '-%d seconds')", int(Sessions.IdleTimeout.Seconds()))
+	}
+	return condition
+}
+
+// DeleteExpiredSessions removes all sessions that can't be used anymore and returns
+// how many were removed
+func DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	db, err := openDB()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := db.ExecContext(ctx, "DELETE FROM Sessions WHERE NOT ("+validSessionCondition()+")")
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
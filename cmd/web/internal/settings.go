@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/locale"
+	"github.com/Shu-AFK/TaskWeave/schedule"
+	"time"
+)
+
+// UserSettings are the preferences of a user. Empty fields fall back to the defaults of
+// the server.
+type UserSettings struct {
+	Timezone string
+	Locale   string
+	// MaxLoad is the share of the working window the balancer may book on a day in percent
+	MaxLoad int
+}
+
+// Location returns the timezone of the user or fallback if none is set
+func (s UserSettings) Location(fallback *time.Location) *time.Location {
+	if s.Timezone == "" {
+		return fallback
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
+
+// Load returns the maximum load per day as a fraction
+func (s UserSettings) Load() float64 {
+	if s.MaxLoad == 0 {
+		return schedule.DefaultMaxLoad
+	}
+	return float64(s.MaxLoad) / 100
+}
+
+// ValidateSettings checks that the timezone is a known IANA zone, the locale is supported
+// and the maximum load is a percentage
+func ValidateSettings(settings UserSettings) ValidationErrors {
+	errs := ValidationErrors{}
+
+	if settings.Timezone != "" {
+		if _, err := time.LoadLocation(settings.Timezone); err != nil {
+			errs["timezone"] = "Unknown timezone, use a name like Europe/Berlin or America/New_York"
+		}
+	}
+	if settings.Locale != "" {
+		if _, ok := locale.Get(settings.Locale); !ok {
+			errs["locale"] = "This language is not supported"
+		}
+	}
+	if settings.MaxLoad < 0 || settings.MaxLoad > 100 {
+		errs["max_load"] = "The maximum load has to be between 1 and 100 percent"
+	}
+
+	return errs
+}
+
+// GetUserSettings returns the preferences of a user
+func GetUserSettings(ctx context.Context, userId int) (UserSettings, error) {
+	db, err := openDB()
+	if err != nil {
+		return UserSettings{}, err
+	}
+
+	var settings UserSettings
+	err = db.QueryRowContext(ctx, "SELECT timezone, locale, maxLoad FROM Users WHERE id=?", userId).
+		Scan(&settings.Timezone, &settings.Locale, &settings.MaxLoad)
+	if errors.Is(err, sql.ErrNoRows) {
+		return UserSettings{}, ErrNotFound
+	}
+	return settings, err
+}
+
+// UpdateUserSettings stores the preferences of a user
+func UpdateUserSettings(ctx context.Context, userId int, settings UserSettings) error {
+	errs := ValidateSettings(settings)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE Users SET timezone=?, locale=?, maxLoad=? WHERE id=?",
+		settings.Timezone, settings.Locale, settings.MaxLoad, userId)
+	return err
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"context"
+)
+
+func count(ctx context.Context, query string) (float64, error) {
+	db, err := openDB()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err = db.QueryRowContext(ctx, query).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(n), nil
+}
+
+// CountActiveSessions returns the number of sessions which didn't expire yet
+func CountActiveSessions(ctx context.Context) (float64, error) {
+	return count(ctx, "SELECT COUNT(*) FROM Sessions WHERE "+validSessionCondition())
+}
+
+// CountOpenTodos returns the number of todos of all users which aren't done
+func CountOpenTodos(ctx context.Context) (float64, error) {
+	return count(ctx, "SELECT COUNT(*) FROM Todos WHERE NOT COALESCE(done, 0)")
+}
+
+// CountOverdueTodos returns the number of open todos whose deadline passed
+func CountOverdueTodos(ctx context.Context) (float64, error) {
+	return count(ctx, `
+		SELECT COUNT(*) FROM Todos
+		WHERE NOT COALESCE(done, 0) AND deadline != '' AND datetime(deadline) < datetime('now')
+	`)
+}
+
+// CountOverdueEvents returns the number of events whose deadline passed while they
+// still have open todos or, without todos, end after the deadline
+func CountOverdueEvents(ctx context.Context) (float64, error) {
+	return count(ctx, `
+		SELECT COUNT(*) FROM Events e
+		WHERE e.deadline != '' AND datetime(e.deadline) < datetime('now') AND (
+			EXISTS(
+				SELECT * FROM EventTodos et JOIN Todos t ON t.id = et.todoId
+				WHERE et.eventId = e.id AND NOT COALESCE(t.done, 0)
+			)
+			OR (
+				NOT EXISTS(SELECT * FROM EventTodos et WHERE et.eventId = e.id)
+				AND (e.end = '' OR datetime(e.end) > datetime(e.deadline))
+			)
+		)
+	`)
+}
@@ -0,0 +1,793 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/changes"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"time"
+)
+
+// ErrNotFound is returned when a day, event or todo does not exist or belongs to another user
+var ErrNotFound = apperr.New(apperr.NotFound, "The requested item does not exist")
+
+const dateLayout = "2006-01-02"
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func parseTime(s sql.NullString) (time.Time, error) {
+	if !s.Valid || s.String == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s.String)
+}
+
+func parseDuration(s sql.NullString) (time.Duration, error) {
+	if !s.Valid || s.String == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s.String)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// GetDays returns all days of a user ordered by date, including their events and todos
+func GetDays(ctx context.Context, userId int) ([]Day, error) {
+	db, err := openDB()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, date, startOfDay, endOfDay FROM Days WHERE userId=? ORDER BY date", userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []Day
+	for rows.Next() {
+		day, err := scanDay(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	prerequisites, err := getPrerequisites(ctx, db, userId, now)
+	if err != nil {
+		return nil, err
+	}
+	tracked, err := getTracked(ctx, db, userId, now)
+	if err != nil {
+		return nil, err
+	}
+	for i := range days {
+		days[i].Events, err = getEvents(ctx, db, days[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		markConflicts(&days[i])
+		prerequisites.attach(days[i].Events)
+		tracked.attach(days[i].Events)
+	}
+
+	return days, nil
+}
+
+// GetDay returns a single day of a user including its events and todos
+func GetDay(ctx context.Context, userId int, dayId int64) (Day, error) {
+	db, err := openDB()
+	if err != nil {
+		return Day{}, err
+	}
+
+	row := db.QueryRowContext(ctx, "SELECT id, date, startOfDay, endOfDay FROM Days WHERE id=? AND userId=?", dayId, userId)
+	day, err := scanDay(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Day{}, ErrNotFound
+		}
+		return Day{}, err
+	}
+
+	day.Events, err = getEvents(ctx, db, day.ID)
+	if err != nil {
+		return Day{}, err
+	}
+	markConflicts(&day)
+
+	now := time.Now()
+	prerequisites, err := getPrerequisites(ctx, db, userId, now)
+	if err != nil {
+		return Day{}, err
+	}
+	prerequisites.attach(day.Events)
+	tracked, err := getTracked(ctx, db, userId, now)
+	if err != nil {
+		return Day{}, err
+	}
+	tracked.attach(day.Events)
+
+	return day, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDay(row scanner) (Day, error) {
+	var day Day
+	var date string
+	var start, end sql.NullString
+
+	err := row.Scan(&day.ID, &date, &start, &end)
+	if err != nil {
+		return Day{}, err
+	}
+
+	day.Date, err = time.ParseInLocation(dateLayout, date, time.Local)
+	if err != nil {
+		return Day{}, err
+	}
+	day.StartOfDay, err = parseTime(start)
+	if err != nil {
+		return Day{}, err
+	}
+	day.EndOfDay, err = parseTime(end)
+	if err != nil {
+		return Day{}, err
+	}
+
+	return day, nil
+}
+
+func getEvents(ctx context.Context, db storeDB, dayId int64) ([]Event, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT e.id, e.name, e.duration, e.deadline, e.start, e.end, e.scheduled, e.urgent, e.important, e.allowOverlap,
+			e.seriesId, e.occurrence, e.detached, e.rollovers, e.missed, e.done
+		FROM Events e JOIN DayEvents de ON de.eventId = e.id
+		WHERE de.dayId=?
+		ORDER BY e.position, e.id
+	`, dayId)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		event.DayID = dayId
+		events = append(events, event)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range events {
+		events[i].TodoList, err = getTodos(ctx, db, events[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
+
+func scanEvent(row scanner) (Event, error) {
+	var event Event
+	var duration, deadline, start, end, occurrence sql.NullString
+
+	err := row.Scan(&event.ID, &event.Name, &duration, &deadline, &start, &end, &event.Scheduled,
+		&event.Priority.Urgent, &event.Priority.Important, &event.AllowOverlap,
+		&event.SeriesID, &occurrence, &event.Detached, &event.Rollovers, &event.Missed, &event.Done)
+	if err != nil {
+		return Event{}, err
+	}
+
+	event.Duration, err = parseDuration(duration)
+	if err != nil {
+		return Event{}, err
+	}
+	event.Deadline, err = parseTime(deadline)
+	if err != nil {
+		return Event{}, err
+	}
+	event.Start, err = parseTime(start)
+	if err != nil {
+		return Event{}, err
+	}
+	event.End, err = parseTime(end)
+	if err != nil {
+		return Event{}, err
+	}
+	event.Occurrence, err = parseTime(occurrence)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return event, nil
+}
+
+func getTodos(ctx context.Context, db storeDB, eventId int64) ([]Todo, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT t.id, t.name, t.description, t.deadline, t.done, t.urgent, t.important, t.seriesTodoId
+		FROM Todos t JOIN EventTodos et ON et.todoId = t.id
+		WHERE et.eventId=?
+		ORDER BY t.position, t.id
+	`, eventId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var todos []Todo
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		todo.EventID = eventId
+		todos = append(todos, todo)
+	}
+
+	return todos, rows.Err()
+}
+
+func scanTodo(row scanner) (Todo, error) {
+	var todo Todo
+	var description, deadline sql.NullString
+	var done sql.NullBool
+
+	err := row.Scan(&todo.ID, &todo.Name, &description, &deadline, &done, &todo.Priority.Urgent, &todo.Priority.Important, &todo.SeriesTodoID)
+	if err != nil {
+		return Todo{}, err
+	}
+
+	todo.Description = description.String
+	todo.Done = done.Bool
+	todo.Deadline, err = parseTime(deadline)
+	if err != nil {
+		return Todo{}, err
+	}
+
+	return todo, nil
+}
+
+// AddDay stores a new day for a user and sets its ID. The occurrences of the series of the
+// user that fall on it are added to it.
+func AddDay(ctx context.Context, userId int, day *Day) error {
+	errs := ValidateDay(*day)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	date := day.Date.Format(dateLayout)
+
+	var exists bool
+	err = db.QueryRowContext(ctx, "SELECT EXISTS(SELECT * FROM Days WHERE userId=? AND date=?)", userId, date).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ValidationErrors{"date": "This day already exists!"}
+	}
+
+	res, err := db.ExecContext(ctx, "INSERT INTO Days (userId, date, startOfDay, endOfDay) VALUES (?, ?, ?, ?)",
+		userId, date, formatTime(day.StartOfDay), formatTime(day.EndOfDay))
+	if err != nil {
+		return err
+	}
+
+	day.ID, err = res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	// Recurring events get their occurrence on the new day
+	err = addOccurrences(ctx, db, userId, *day)
+	if err != nil {
+		return err
+	}
+
+	notify(userId, changes.Created, changes.Day, day.ID, day.ID, 0)
+	return nil
+}
+
+// DeleteDay removes a day together with all of its events and todos
+func DeleteDay(ctx context.Context, userId int, dayId int64) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = db.QueryRowContext(ctx, "SELECT EXISTS(SELECT * FROM Days WHERE id=? AND userId=?)", dayId, userId).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = deleteItemRows(ctx, tx, TodoItem, `
+		SELECT et.todoId FROM EventTodos et JOIN DayEvents de ON de.eventId = et.eventId WHERE de.dayId=?`, dayId)
+	if err != nil {
+		return err
+	}
+	err = deleteItemRows(ctx, tx, EventItem, "SELECT eventId FROM DayEvents WHERE dayId=?", dayId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM Todos WHERE id IN (
+			SELECT et.todoId FROM EventTodos et JOIN DayEvents de ON de.eventId = et.eventId WHERE de.dayId=?
+		)`, dayId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM EventTodos WHERE eventId IN (SELECT eventId FROM DayEvents WHERE dayId=?)`, dayId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM Events WHERE id IN (SELECT eventId FROM DayEvents WHERE dayId=?)`, dayId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM DayEvents WHERE dayId=?", dayId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM Days WHERE id=?", dayId)
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Debug("Deleting day with all events and todos", "day_id", dayId)
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	notify(userId, changes.Deleted, changes.Day, dayId, dayId, 0)
+	return nil
+}
+
+// dayOfEvent returns the id of the day the event belongs to, if the day is owned by the user
+func dayOfEvent(ctx context.Context, db querier, userId int, eventId int64) (int64, error) {
+	var dayId int64
+	err := db.QueryRowContext(ctx, `
+		SELECT d.id FROM Days d JOIN DayEvents de ON de.dayId = d.id
+		WHERE de.eventId=? AND d.userId=?
+	`, eventId, userId).Scan(&dayId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	return dayId, nil
+}
+
+// eventOfTodo returns the id of the event the todo belongs to, if it is owned by the user
+func eventOfTodo(ctx context.Context, db querier, userId int, todoId int64) (int64, error) {
+	var eventId int64
+	err := db.QueryRowContext(ctx, `
+		SELECT et.eventId FROM EventTodos et
+		JOIN DayEvents de ON de.eventId = et.eventId
+		JOIN Days d ON d.id = de.dayId
+		WHERE et.todoId=? AND d.userId=?
+	`, todoId, userId).Scan(&eventId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	return eventId, nil
+}
+
+// itemOwner checks that the item belongs to the user and returns the ids of its day
+// and event
+func itemOwner(ctx context.Context, db querier, userId int, kind ItemKind, id int64) (dayId, eventId int64, err error) {
+	eventId = id
+	if kind == TodoItem {
+		eventId, err = eventOfTodo(ctx, db, userId, id)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	dayId, err = dayOfEvent(ctx, db, userId, eventId)
+	return dayId, eventId, err
+}
+
+// itemName returns the name of an event or a todo
+func itemName(ctx context.Context, db querier, kind ItemKind, id int64) (string, error) {
+	query := "SELECT name FROM Events WHERE id=?"
+	if kind == TodoItem {
+		query = "SELECT name FROM Todos WHERE id=?"
+	}
+
+	var name string
+	err := db.QueryRowContext(ctx, query, id).Scan(&name)
+	return name, err
+}
+
+// GetEvent returns a single event of a user including its todos. It is taken from its
+// day, which is needed to find its conflicts anyway.
+func GetEvent(ctx context.Context, userId int, eventId int64) (Event, error) {
+	db, err := openDB()
+	if err != nil {
+		return Event{}, err
+	}
+
+	dayId, err := dayOfEvent(ctx, db, userId, eventId)
+	if err != nil {
+		return Event{}, err
+	}
+
+	day, err := GetDay(ctx, userId, dayId)
+	if err != nil {
+		return Event{}, err
+	}
+
+	for _, event := range day.Events {
+		if event.ID == eventId {
+			return event, nil
+		}
+	}
+	return Event{}, ErrNotFound
+}
+
+// AddEvent stores a new event in the day given by event.DayID and sets its ID
+func AddEvent(ctx context.Context, userId int, event *Event) error {
+	day, err := GetDay(ctx, userId, event.DayID)
+	if err != nil {
+		return err
+	}
+
+	errs := ValidateEvent(day, *event)
+	if len(errs) > 0 {
+		return errs
+	}
+	err = checkConflicts(day, *event)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO Events (name, duration, deadline, start, end, urgent, important, allowOverlap, position)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(e.position), 0) + 1 FROM Events e JOIN DayEvents de ON de.eventId = e.id WHERE de.dayId=?))
+	`, event.Name, event.Duration.String(), formatTime(event.Deadline), formatTime(event.Start), formatTime(event.End),
+		boolToInt(event.Priority.Urgent), boolToInt(event.Priority.Important), boolToInt(event.AllowOverlap), event.DayID)
+	if err != nil {
+		return err
+	}
+
+	event.ID, err = res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO DayEvents (dayId, eventId) VALUES (?, ?)", event.DayID, event.ID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	notify(userId, changes.Created, changes.Event, event.ID, event.DayID, event.ID)
+	return nil
+}
+
+// checkEvent sets the day of an existing event and validates the event against it
+func checkEvent(ctx context.Context, db storeDB, userId int, event *Event) error {
+	var err error
+	event.DayID, err = dayOfEvent(ctx, db, userId, event.ID)
+	if err != nil {
+		return err
+	}
+
+	day, err := GetDay(ctx, userId, event.DayID)
+	if err != nil {
+		return err
+	}
+
+	errs := ValidateEvent(day, *event)
+	if len(errs) > 0 {
+		return errs
+	}
+	return checkConflicts(day, *event)
+}
+
+// UpdateEvent overwrites the fields of an existing event. The day of an event can't be
+// changed. An occurrence of a series is detached from it, later changes to the series
+// leave it alone.
+func UpdateEvent(ctx context.Context, userId int, event Event) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	err = checkEvent(ctx, db, userId, &event)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `
+		UPDATE Events SET name=?, duration=?, deadline=?, start=?, end=?, scheduled=?, urgent=?, important=?, allowOverlap=?,
+			detached=(seriesId != 0)
+		WHERE id=?
+	`, event.Name, event.Duration.String(), formatTime(event.Deadline), formatTime(event.Start), formatTime(event.End),
+		boolToInt(event.Scheduled), boolToInt(event.Priority.Urgent), boolToInt(event.Priority.Important),
+		boolToInt(event.AllowOverlap), event.ID)
+	if err != nil {
+		return err
+	}
+
+	notify(userId, changes.Updated, changes.Event, event.ID, event.DayID, event.ID)
+	return nil
+}
+
+// DeleteEvent removes an event together with its todos
+func DeleteEvent(ctx context.Context, userId int, eventId int64) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	dayId, err := dayOfEvent(ctx, db, userId, eventId)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = deleteEventRows(ctx, tx, eventId)
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Debug("Deleting event with all todos", "event_id", eventId)
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	notify(userId, changes.Deleted, changes.Event, eventId, dayId, eventId)
+	return nil
+}
+
+func deleteEventRows(ctx context.Context, tx storeTx, eventId int64) error {
+	err := deleteItemRows(ctx, tx, TodoItem, "SELECT todoId FROM EventTodos WHERE eventId=?", eventId)
+	if err != nil {
+		return err
+	}
+	err = deleteItemRows(ctx, tx, EventItem, "?", eventId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM Todos WHERE id IN (SELECT todoId FROM EventTodos WHERE eventId=?)", eventId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM EventTodos WHERE eventId=?", eventId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM DayEvents WHERE eventId=?", eventId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM Events WHERE id=?", eventId)
+	return err
+}
+
+// deleteItemRows removes the rows that refer to the events or todos the query selects:
+// their dependencies on both ends and their time entries
+func deleteItemRows(ctx context.Context, tx storeTx, kind ItemKind, ids string, args ...any) error {
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM Dependencies WHERE kind=? AND (prerequisiteId IN (`+ids+`) OR dependentId IN (`+ids+`))
+	`, append(append([]any{kind}, args...), args...)...)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM TimeEntries WHERE kind=? AND itemId IN ("+ids+")", append([]any{kind}, args...)...)
+	return err
+}
+
+// GetTodo returns a single todo of a user
+func GetTodo(ctx context.Context, userId int, todoId int64) (Todo, error) {
+	db, err := openDB()
+	if err != nil {
+		return Todo{}, err
+	}
+
+	eventId, err := eventOfTodo(ctx, db, userId, todoId)
+	if err != nil {
+		return Todo{}, err
+	}
+
+	row := db.QueryRowContext(ctx, "SELECT id, name, description, deadline, done, urgent, important, seriesTodoId FROM Todos WHERE id=?", todoId)
+	todo, err := scanTodo(row)
+	if err != nil {
+		return Todo{}, err
+	}
+	todo.EventID = eventId
+
+	return todo, nil
+}
+
+// AddTodo attaches a new todo to the event given by todo.EventID and sets its ID
+func AddTodo(ctx context.Context, userId int, todo *Todo) error {
+	errs := ValidateTodo(*todo)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	dayId, err := dayOfEvent(ctx, db, userId, todo.EventID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO Todos (name, description, deadline, done, urgent, important, position)
+		VALUES (?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(t.position), 0) + 1 FROM Todos t JOIN EventTodos et ON et.todoId = t.id WHERE et.eventId=?))
+	`, todo.Name, todo.Description, formatTime(todo.Deadline), boolToInt(todo.Done),
+		boolToInt(todo.Priority.Urgent), boolToInt(todo.Priority.Important), todo.EventID)
+	if err != nil {
+		return err
+	}
+
+	todo.ID, err = res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO EventTodos (eventId, todoId) VALUES (?, ?)", todo.EventID, todo.ID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	notify(userId, changes.Created, changes.Todo, todo.ID, dayId, todo.EventID)
+	return nil
+}
+
+// UpdateTodo overwrites the fields of an existing todo
+func UpdateTodo(ctx context.Context, userId int, todo Todo) error {
+	errs := ValidateTodo(todo)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	eventId, err := eventOfTodo(ctx, db, userId, todo.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE Todos SET name=?, description=?, deadline=?, done=?, urgent=?, important=? WHERE id=?",
+		todo.Name, todo.Description, formatTime(todo.Deadline), boolToInt(todo.Done),
+		boolToInt(todo.Priority.Urgent), boolToInt(todo.Priority.Important), todo.ID)
+	if err != nil {
+		return err
+	}
+
+	notify(userId, changes.Updated, changes.Todo, todo.ID, 0, eventId)
+	return nil
+}
+
+// DeleteTodo removes a todo from its event
+func DeleteTodo(ctx context.Context, userId int, todoId int64) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	eventId, err := eventOfTodo(ctx, db, userId, todoId)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = deleteItemRows(ctx, tx, TodoItem, "?", todoId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM EventTodos WHERE todoId=?", todoId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM Todos WHERE id=?", todoId)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	notify(userId, changes.Deleted, changes.Todo, todoId, 0, eventId)
+	return nil
+}
@@ -0,0 +1,340 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"github.com/Shu-AFK/TaskWeave/track"
+	"time"
+)
+
+// ErrNoTimer is returned when the timer is stopped while none is running
+var ErrNoTimer = apperr.New(apperr.NotFound, "No timer is running")
+
+// TimeEntry is time tracked for an event or a todo. A user has at most one running
+// entry, the timer.
+type TimeEntry struct {
+	ID     int64
+	Kind   ItemKind
+	ItemID int64
+	// Name is the name of the event or todo, DayID and EventID tell where it is
+	Name    string
+	DayID   int64
+	EventID int64
+	track.Entry
+}
+
+// tracked holds the tracked time of the events and todos of a user
+type tracked struct {
+	events map[int64]time.Duration
+	todos  map[int64]time.Duration
+	// running is the item the timer runs for
+	running struct {
+		kind ItemKind
+		id   int64
+	}
+}
+
+// getTracked adds up the time entries of a user, running entries count up to now
+func getTracked(ctx context.Context, db storeDB, userId int, now time.Time) (tracked, error) {
+	t := tracked{events: map[int64]time.Duration{}, todos: map[int64]time.Duration{}}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, kind, itemId, start, stop, note FROM TimeEntries WHERE userId=?", userId)
+	if err != nil {
+		return t, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanTimeEntry(rows)
+		if err != nil {
+			return t, err
+		}
+
+		if entry.Running() {
+			t.running.kind, t.running.id = entry.Kind, entry.ItemID
+		}
+		if entry.Kind == TodoItem {
+			t.todos[entry.ItemID] += entry.Duration(now)
+		} else {
+			t.events[entry.ItemID] += entry.Duration(now)
+		}
+	}
+
+	return t, rows.Err()
+}
+
+// attach sets the tracked time of the events and their todos
+func (t tracked) attach(events []Event) {
+	for i := range events {
+		event := &events[i]
+		event.Tracked = t.events[event.ID]
+		event.Timing = t.running.kind == EventItem && t.running.id == event.ID
+		for j := range event.TodoList {
+			todo := &event.TodoList[j]
+			todo.Tracked = t.todos[todo.ID]
+			todo.Timing = t.running.kind == TodoItem && t.running.id == todo.ID
+			event.Tracked += todo.Tracked
+		}
+	}
+}
+
+func scanTimeEntry(row scanner) (TimeEntry, error) {
+	var entry TimeEntry
+	var start, stop sql.NullString
+
+	err := row.Scan(&entry.ID, &entry.Kind, &entry.ItemID, &start, &stop, &entry.Note)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	entry.Start, err = parseTime(start)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	entry.Stop, err = parseTime(stop)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// locate fills in the name, day and event of the item of an entry
+func locate(ctx context.Context, db storeDB, userId int, entry *TimeEntry) error {
+	var err error
+	entry.DayID, entry.EventID, err = itemOwner(ctx, db, userId, entry.Kind, entry.ItemID)
+	if err != nil {
+		return err
+	}
+
+	entry.Name, err = itemName(ctx, db, entry.Kind, entry.ItemID)
+	return err
+}
+
+// runningTimer returns the running entry of a user, ok is false if there is none
+func runningTimer(ctx context.Context, db storeDB, userId int) (entry TimeEntry, ok bool, err error) {
+	row := db.QueryRowContext(ctx, "SELECT id, kind, itemId, start, stop, note FROM TimeEntries WHERE userId=? AND stop=''", userId)
+	entry, err = scanTimeEntry(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return TimeEntry{}, false, nil
+		}
+		return TimeEntry{}, false, err
+	}
+
+	err = locate(ctx, db, userId, &entry)
+	return entry, err == nil, err
+}
+
+// RunningTimer returns the timer of a user, ok is false if none is running
+func RunningTimer(ctx context.Context, userId int) (entry TimeEntry, ok bool, err error) {
+	db, err := openDB()
+	if err != nil {
+		return TimeEntry{}, false, err
+	}
+
+	return runningTimer(ctx, db, userId)
+}
+
+// StartTimer starts tracking time for an event or a todo. A timer that is still running
+// for something else is stopped first, one that runs for the item already keeps running.
+func StartTimer(ctx context.Context, userId int, kind ItemKind, itemId int64, note string, now time.Time) (TimeEntry, error) {
+	db, err := openDB()
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	entry := TimeEntry{Kind: kind, ItemID: itemId, Entry: track.Entry{Start: now, Note: note}}
+	err = locate(ctx, db, userId, &entry)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	running, ok, err := runningTimer(ctx, db, userId)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	if ok && running.Kind == kind && running.ItemID == itemId {
+		return running, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE TimeEntries SET stop=? WHERE userId=? AND stop=''", formatTime(now), userId)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	res, err := tx.ExecContext(ctx, "INSERT INTO TimeEntries (userId, kind, itemId, start, note) VALUES (?, ?, ?, ?, ?)",
+		userId, kind, itemId, formatTime(now), note)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	entry.ID, err = res.LastInsertId()
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	logging.FromContext(ctx).Debug("Starting timer", "kind", kind, "item_id", itemId)
+	if ok {
+		notifyItem(userId, running.Kind, running.ItemID, running.DayID, running.EventID)
+	}
+	notifyItem(userId, kind, itemId, entry.DayID, entry.EventID)
+	return entry, nil
+}
+
+// StopTimer stops the running timer of a user. A note replaces the one given at the
+// start, an empty one keeps it.
+func StopTimer(ctx context.Context, userId int, note string, now time.Time) (TimeEntry, error) {
+	db, err := openDB()
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	entry, ok, err := runningTimer(ctx, db, userId)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	if !ok {
+		return TimeEntry{}, ErrNoTimer
+	}
+
+	entry.Stop = now
+	if note != "" {
+		entry.Note = note
+	}
+	_, err = db.ExecContext(ctx, "UPDATE TimeEntries SET stop=?, note=? WHERE id=?", formatTime(entry.Stop), entry.Note, entry.ID)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	logging.FromContext(ctx).Debug("Stopping timer", "kind", entry.Kind, "item_id", entry.ItemID)
+	notifyItem(userId, entry.Kind, entry.ItemID, entry.DayID, entry.EventID)
+	return entry, nil
+}
+
+// GetTimeEntries returns the time entries of an event or a todo ordered by their start.
+// The entries of the todos of an event are not included.
+func GetTimeEntries(ctx context.Context, userId int, kind ItemKind, itemId int64) ([]TimeEntry, error) {
+	db, err := openDB()
+	if err != nil {
+		return nil, err
+	}
+
+	item := TimeEntry{Kind: kind, ItemID: itemId}
+	err = locate(ctx, db, userId, &item)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, kind, itemId, start, stop, note FROM TimeEntries
+		WHERE userId=? AND kind=? AND itemId=?
+		ORDER BY start, id
+	`, userId, kind, itemId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []TimeEntry
+	for rows.Next() {
+		entry, err := scanTimeEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entry.Name, entry.DayID, entry.EventID = item.Name, item.DayID, item.EventID
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// AddTimeEntry stores time that was tracked without the timer and sets the ID of the
+// entry
+func AddTimeEntry(ctx context.Context, userId int, entry *TimeEntry, now time.Time) error {
+	errs := ValidateTimeEntry(*entry, now)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	err = locate(ctx, db, userId, entry)
+	if err != nil {
+		return err
+	}
+
+	res, err := db.ExecContext(ctx, "INSERT INTO TimeEntries (userId, kind, itemId, start, stop, note) VALUES (?, ?, ?, ?, ?, ?)",
+		userId, entry.Kind, entry.ItemID, formatTime(entry.Start), formatTime(entry.Stop), entry.Note)
+	if err != nil {
+		return err
+	}
+	entry.ID, err = res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	notifyItem(userId, entry.Kind, entry.ItemID, entry.DayID, entry.EventID)
+	return nil
+}
+
+// DeleteTimeEntry removes a time entry of an event or a todo
+func DeleteTimeEntry(ctx context.Context, userId int, kind ItemKind, itemId, entryId int64) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+
+	dayId, eventId, err := itemOwner(ctx, db, userId, kind, itemId)
+	if err != nil {
+		return err
+	}
+
+	res, err := db.ExecContext(ctx, "DELETE FROM TimeEntries WHERE id=? AND userId=? AND kind=? AND itemId=?",
+		entryId, userId, kind, itemId)
+	if err != nil {
+		return err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+
+	notifyItem(userId, kind, itemId, dayId, eventId)
+	return nil
+}
+
+// GetTimeReport returns the days of a user from the date of from up to but not including
+// the date of to, their events and todos carry the time tracked for them
+func GetTimeReport(ctx context.Context, userId int, from, to time.Time) ([]Day, error) {
+	days, err := GetDays(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var report []Day
+	for _, day := range days {
+		if inRange(day, from, to) {
+			report = append(report, day)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"github.com/Shu-AFK/TaskWeave/cmd/web/apperr"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValidationErrors maps a form field to the message that should be shown next to it
+type ValidationErrors map[string]string
+
+func (v ValidationErrors) Error() string {
+	fields := make([]string, 0, len(v))
+	for field := range v {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	msgs := make([]string, 0, len(v))
+	for _, field := range fields {
+		msgs = append(msgs, field+": "+v[field])
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+func (v ValidationErrors) AppErrorKind() apperr.Kind {
+	return apperr.Validation
+}
+
+func sameDate(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// ValidateDay checks that the working window of a day is valid
+func ValidateDay(day Day) ValidationErrors {
+	errs := ValidationErrors{}
+
+	if day.Date.IsZero() {
+		errs["date"] = "Please enter a date"
+	}
+	if day.StartOfDay.IsZero() {
+		errs["start"] = "Please enter the start time of the day"
+	}
+	if day.EndOfDay.IsZero() {
+		errs["end"] = "Please enter the end time of the day"
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if !day.EndOfDay.After(day.StartOfDay) {
+		errs["end"] = "The end time of the day is not allowed to be less than or equal to the start time!"
+	} else if !sameDate(day.StartOfDay, day.Date) || !sameDate(day.EndOfDay, day.Date) {
+		errs["end"] = "Start time and end time have to be in the same day!"
+	}
+
+	return errs
+}
+
+// ValidateEvent checks an event that belongs to the given day. An event either has a start
+// and end time or only a duration.
+func ValidateEvent(day Day, event Event) ValidationErrors {
+	errs := ValidationErrors{}
+
+	if strings.TrimSpace(event.Name) == "" {
+		errs["name"] = "Please enter a title"
+	}
+
+	switch {
+	case event.Start.IsZero() && event.End.IsZero():
+		if event.Duration <= 0 {
+			errs["duration"] = "Please enter a duration or a start and end time"
+		}
+	case event.Start.IsZero():
+		errs["start"] = "Please enter the start time of the event"
+	case event.End.IsZero():
+		errs["end"] = "Please enter the end time of the event"
+	case !event.End.After(event.Start):
+		errs["end"] = "The end time of the event is not allowed to be less than or equal to the start time!"
+	case !sameDate(event.Start, day.Date) || !sameDate(event.End, day.Date):
+		errs["end"] = "Start time and end time have to be in the same day!"
+	}
+
+	return errs
+}
+
+// ValidateTodo checks a todo before it is stored
+func ValidateTodo(todo Todo) ValidationErrors {
+	errs := ValidationErrors{}
+
+	if strings.TrimSpace(todo.Name) == "" {
+		errs["name"] = "Please enter a name"
+	}
+
+	return errs
+}
+
+// ValidateTimeEntry checks a time entry that is entered afterwards, it has to be over by
+// now
+func ValidateTimeEntry(entry TimeEntry, now time.Time) ValidationErrors {
+	errs := ValidationErrors{}
+
+	switch {
+	case entry.Start.IsZero():
+		errs["start"] = "Please enter when you started"
+	case entry.Stop.IsZero():
+		errs["stop"] = "Please enter when you stopped"
+	case !entry.Stop.After(entry.Start):
+		errs["stop"] = "The entry has to stop after it started"
+	case entry.Stop.After(now):
+		errs["stop"] = "The entry can't stop in the future"
+	}
+
+	return errs
+}
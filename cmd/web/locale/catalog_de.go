@@ -0,0 +1,315 @@
+package locale
+
+// german is the catalog of the German locale
+var german = map[string]string{
+	// Navigation and shared buttons
+	"About":              "Über",
+	"Tasks":              "Aufgaben",
+	"Settings":           "Einstellungen",
+	"Login":              "Anmelden",
+	"Signup":             "Registrieren",
+	"Save":               "Speichern",
+	"Cancel":             "Abbrechen",
+	"Edit":               "Bearbeiten",
+	"Delete":             "Löschen",
+	"Rename":             "Umbenennen",
+	"Move up":            "Nach oben",
+	"Move down":          "Nach unten",
+	"Back to your tasks": "Zurück zu deinen Aufgaben",
+
+	// Homepage
+	"Welcome to TaskWeave":                               "Willkommen bei TaskWeave",
+	"Your efficient assistant for managing daily tasks.": "Dein effizienter Assistent für die täglichen Aufgaben.",
+	"A Work-In-Progress App Designed with You in Mind":   "Eine App in Entwicklung, die für dich gemacht ist",
+	"TaskWeave is a work-in-progress app, designed for individual users who want to streamline and balance their workload. Though it is currently tailored for single users, we hope to expand our services to teams in the future.": "TaskWeave ist eine App in Entwicklung für alle, die ihre Arbeit besser organisieren und gleichmäßiger verteilen wollen. Aktuell ist sie auf einzelne Nutzer zugeschnitten, in Zukunft möchten wir sie auch für Teams anbieten.",
+	"Features":            "Funktionen",
+	"Workload Balancing:": "Ausgeglichene Auslastung:",
+	"TaskWeave helps you distribute your work evenly across your day.": "TaskWeave hilft dir, deine Arbeit gleichmäßig über den Tag zu verteilen.",
+	"Prioritization:": "Priorisierung:",
+	"Assign priorities according to your tasks' urgency and importance.": "Vergib Prioritäten nach Dringlichkeit und Wichtigkeit deiner Aufgaben.",
+	"Task Tracking:": "Aufgaben im Blick:",
+	"Helps you stay on track with all tasks, avoiding missing due dates.": "Behalte alle Aufgaben im Blick und verpasse keine Fristen mehr.",
+	"Here":                             "Hier",
+	"you can view your current tasks.": "findest du deine aktuellen Aufgaben.",
+
+	// Login and signup
+	"Username:":                    "Benutzername:",
+	"Email:":                       "E-Mail:",
+	"Password:":                    "Passwort:",
+	"Retype your Password:":        "Passwort wiederholen:",
+	"Forgot password?":             "Passwort vergessen?",
+	"Don't have an account?":       "Noch kein Konto?",
+	"Register":                     "Registrieren",
+	"Already have an account?":     "Du hast schon ein Konto?",
+	"Invalid username or password": "Benutzername oder Passwort ist falsch",
+	"Passwords do not match":       "Die Passwörter stimmen nicht überein",
+	"Username, email and password are required":         "Benutzername, E-Mail und Passwort werden benötigt",
+	"The email address is not valid":                    "Die E-Mail-Adresse ist ungültig",
+	"This username is already taken":                    "Dieser Benutzername ist bereits vergeben",
+	"An account with this email address already exists": "Es gibt bereits ein Konto mit dieser E-Mail-Adresse",
+	"Registration is closed":                            "Die Registrierung ist geschlossen",
+	"You need to log in":                                "Bitte melde dich an",
+
+	// Tasks and calendar
+	"New day":                           "Neuer Tag",
+	"You haven't planned any days yet.": "Du hast noch keine Tage geplant.",
+	"Working window: %s - %s":           "Arbeitszeit: %s - %s",
+	"Add event":                         "Termin hinzufügen",
+	"Delete day":                        "Tag löschen",
+	"Add todo":                          "Aufgabe hinzufügen",
+	"Title":                             "Titel",
+	"Name":                              "Name",
+	"Duration: %s":                      "Dauer: %s",
+	"Deadline: %s":                      "Frist: %s",
+	"Mark as done":                      "Als erledigt markieren",
+	"Mark as not done":                  "Als offen markieren",
+	"List":                              "Liste",
+	"Day":                               "Tag",
+	"Week":                              "Woche",
+	"Month":                             "Monat",
+	"Week %d, %d":                       "KW %d, %d",
+	"Wk":                                "KW",
+	"Previous":                          "Zurück",
+	"Today":                             "Heute",
+	"Next":                              "Weiter",
+	"Due: %s":                           "Fällig: %s",
+	"Due %s: %s":                        "Fällig %s: %s",
+
+	// Priorities
+	"Priority":                       "Priorität",
+	"Priority:":                      "Priorität:",
+	"Urgent":                         "Dringend",
+	"Not urgent":                     "Nicht dringend",
+	"Important":                      "Wichtig",
+	"Not important":                  "Nicht wichtig",
+	"Do first":                       "Sofort erledigen",
+	"Delegate":                       "Delegieren",
+	"Eliminate":                      "Streichen",
+	"Matrix":                         "Matrix",
+	"Priority matrix":                "Prioritätenmatrix",
+	"All":                            "Alle",
+	"Sort by:":                       "Sortieren nach:",
+	"Position":                       "Reihenfolge",
+	"Apply":                          "Anwenden",
+	"Nothing matches this priority.": "Nichts passt zu dieser Priorität.",
+	"Nothing here.":                  "Hier ist nichts.",
+
+	// Scheduler
+	"Schedule":                                "Einplanen",
+	"Placed events":                           "Eingeplante Termine",
+	"Events that could not be placed":         "Termine, die nicht eingeplant werden konnten",
+	"There were no flexible events to place.": "Es gab keine flexiblen Termine zum Einplanen.",
+	"Place the events without a fixed time into the free time of their day": "Termine ohne feste Uhrzeit in die freie Zeit ihres Tages einplanen",
+	"placed by the scheduler":                               "automatisch eingeplant",
+	"It has no duration":                                    "Der Termin hat keine Dauer",
+	"Its day is not part of the schedule":                   "Sein Tag ist nicht Teil der Planung",
+	"It is longer than every free slot":                     "Er ist länger als jede freie Lücke",
+	"There is no free slot long enough before its deadline": "Vor seiner Frist gibt es keine ausreichend lange Lücke",
+	"The free time is taken by other items":                 "Die freie Zeit ist schon von anderen Terminen belegt",
+
+	// Balancing
+	"Rebalance":           "Ausgleichen",
+	"Rebalance next week": "Nächste Woche ausgleichen",
+	"Spread the flexible events of next week so that no day is overbooked": "Die flexiblen Termine der nächsten Woche so verteilen, dass kein Tag überbucht ist",
+	"From:":             "Von:",
+	"To:":               "Bis:",
+	"Maximum load (%):": "Maximale Auslastung (%):",
+	"Preview":           "Vorschau",
+	"The events have been moved. Schedule them to give them a time on their new day.": "Die Termine wurden verschoben. Plane sie ein, um ihnen eine Uhrzeit an ihrem neuen Tag zu geben.",
+	"Moved events":      "Verschobene Termine",
+	"Proposed moves":    "Vorgeschlagene Verschiebungen",
+	"Apply these moves": "Diese Verschiebungen übernehmen",
+	"No event has to move, every day stays within its maximum load.": "Kein Termin muss verschoben werden, jeder Tag bleibt unter seiner maximalen Auslastung.",
+	"Load per day":                                                            "Auslastung pro Tag",
+	"%d%% booked, %s left":                                                    "%d%% gebucht, %s frei",
+	"There are no days in this range.":                                        "In diesem Zeitraum gibt es keine Tage.",
+	"Every day it fits on already has its maximum load":                       "Jeder Tag, an den er passt, ist schon maximal ausgelastet",
+	"It has to wait for an item it depends on":                                "Er muss auf etwas warten, von dem er abhängt",
+	"The range has to end on or after its first day":                          "Der Zeitraum muss an oder nach seinem ersten Tag enden",
+	"The events have changed since the preview, please check the moves again": "Die Termine haben sich seit der Vorschau geändert, bitte prüfe die Verschiebungen erneut",
+	"Maximum load per day (%):":                                               "Maximale Auslastung pro Tag (%):",
+	"Rebalancing books at most this share of a day's working window, fixed events included.": "Beim Ausgleichen wird höchstens dieser Anteil des Arbeitszeitfensters eines Tages gebucht, feste Termine eingeschlossen.",
+	"The maximum load has to be between 1 and 100 percent":                                   "Die maximale Auslastung muss zwischen 1 und 100 Prozent liegen",
+	"Please enter a number between 1 and 100":                                                "Bitte gib eine Zahl zwischen 1 und 100 ein",
+
+	// Recurring events
+	"Repeat:":                   "Wiederholen:",
+	"Every day":                 "Jeden Tag",
+	"Every weekday":             "Jeden Werktag",
+	"Every week":                "Jede Woche",
+	"Every month":               "Jeden Monat",
+	"Last weekday of the month": "Letzter Werktag des Monats",
+	"Every year":                "Jedes Jahr",
+	"A recurrence rule as in RFC 5545, leave it empty for a single event": "Eine Wiederholungsregel nach RFC 5545, leer lassen für einen einzelnen Termin",
+	"This event repeats":                             "Dieser Termin wiederholt sich",
+	"Show all occurrences":                           "Alle Wiederholungen anzeigen",
+	"Change only this occurrence":                    "Nur diesen Termin ändern",
+	"Change this and the following occurrences":      "Diesen und alle folgenden Termine ändern",
+	"Change all occurrences":                         "Alle Termine der Serie ändern",
+	"repeats":                                        "wiederholt sich",
+	"repeats, changed on its own":                    "wiederholt sich, einzeln geändert",
+	"Occurrences":                                    "Termine der Serie",
+	"This occurrence":                                "Nur dieser",
+	"This and following":                             "Dieser und folgende",
+	"All occurrences":                                "Alle",
+	"Repeat with this and the following occurrences": "Bei diesem und allen folgenden Terminen wiederholen",
+	"Repeats by %s since %s":                         "Wiederholt sich nach %s seit %s",
+	"Show":                                           "Anzeigen",
+	"changed on its own":                             "einzeln geändert",
+	"add the day to plan it":                         "lege den Tag an, um ihn zu planen",
+	"The series has no occurrences in this range.":   "Die Serie hat in diesem Zeitraum keine Termine.",
+	"Skipped occurrences":                            "Ausgelassene Termine",
+	"Please enter a rule like FREQ=WEEKLY;BYDAY=MO, see RFC 5545":       "Bitte gib eine Regel wie FREQ=WEEKLY;BYDAY=MO ein, siehe RFC 5545",
+	"Please choose which occurrences to change":                         "Bitte wähle aus, welche Termine der Serie geändert werden",
+	"The rule can only be changed for the following or all occurrences": "Die Regel kann nur für die folgenden oder alle Termine geändert werden",
+	"Todos that repeat can't have a deadline":                           "Wiederkehrende Todos können keine Frist haben",
+	"The range can't be longer than a year":                             "Der Zeitraum kann höchstens ein Jahr lang sein",
+
+	// Dependencies
+	"Dependencies":                   "Abhängigkeiten",
+	"Depends on":                     "Hängt ab von",
+	"Needed by":                      "Wird gebraucht von",
+	"blocked":                        "blockiert",
+	"Waiting for:":                   "Wartet auf:",
+	"done":                           "erledigt",
+	"Remove":                         "Entfernen",
+	"Has to wait for:":               "Muss warten auf:",
+	"Add dependency":                 "Abhängigkeit hinzufügen",
+	"It doesn't depend on anything.": "Es hängt von nichts ab.",
+	"It can't start before everything it depends on is done.": "Es kann erst beginnen, wenn alles erledigt ist, wovon es abhängt.",
+	"The dependency would create a cycle":                     "Die Abhängigkeit würde einen Kreis bilden",
+	"An item can't depend on itself":                          "Nichts kann von sich selbst abhängen",
+	"Please choose what it depends on":                        "Bitte wähle aus, wovon es abhängt",
+	"Ready to start":                                          "Startklar",
+	"Open events and todos that don't wait for anything.":     "Offene Termine und Aufgaben, die auf nichts warten.",
+	"Nothing is ready to start.":                              "Nichts ist startklar.",
+
+	// Time tracking
+	"Start timer":                   "Zeit starten",
+	"Stop timer":                    "Zeit stoppen",
+	"timer running":                 "Zeit läuft",
+	"running":                       "läuft",
+	"%s since %s":                   "%s seit %s",
+	"Note":                          "Notiz",
+	"Note:":                         "Notiz:",
+	"Time":                          "Zeit",
+	"Tracked time":                  "Erfasste Zeit",
+	"Tracked: %s":                   "Erfasst: %s",
+	"tracked: %s":                   "erfasst: %s",
+	"No time has been tracked yet.": "Es wurde noch keine Zeit erfasst.",
+	"Add time":                      "Zeit nachtragen",
+	"Started at:":                   "Begonnen um:",
+	"Stopped at:":                   "Beendet um:",
+	"Time report":                   "Zeitbericht",
+	"Compare the planned with the tracked time of this week": "Vergleiche die geplante mit der erfassten Zeit dieser Woche",
+	"Planned and tracked time":                               "Geplante und erfasste Zeit",
+	"Event":                                                  "Termin",
+	"Planned":                                                "Geplant",
+	"Tracked":                                                "Erfasst",
+	"Difference":                                             "Abweichung",
+	"Hours":                                                  "Stunden",
+	"Total":                                                  "Summe",
+	"Planned: %s, tracked: %s (%s hours)":                    "Geplant: %s, erfasst: %s (%s Stunden)",
+	"You haven't planned any days in this range.": "Du hast in diesem Zeitraum keine Tage geplant.",
+	"No timer is running":                         "Es läuft keine Zeit",
+	"Please enter when you started":               "Bitte gib ein, wann du begonnen hast",
+	"Please enter when you stopped":               "Bitte gib ein, wann du aufgehört hast",
+	"The entry has to stop after it started":      "Der Eintrag muss nach seinem Beginn enden",
+	"The entry can't stop in the future":          "Der Eintrag kann nicht in der Zukunft enden",
+
+	// Rollover
+	"Roll over": "Übertragen",
+	"Move the unfinished work of days that are over to the next day with room": "Unerledigtes von vergangenen Tagen auf den nächsten Tag mit Platz verschieben",
+	"Missed events":                                      "Verpasste Termine",
+	"Events that could not be moved":                     "Termine, die nicht verschoben werden konnten",
+	"There is no unfinished work on days that are over.": "Auf vergangenen Tagen ist nichts unerledigt geblieben.",
+	"rolled over %d×":                                    "%d× übertragen",
+	"missed":                                             "verpasst",
+	"Its day ended before its todos were done":           "Sein Tag endete, bevor seine Aufgaben erledigt waren",
+	"Postponed again and again":                          "Immer wieder verschoben",
+	"No later day has room for it":                       "Kein späterer Tag hat Platz dafür",
+	"No room to roll over":                               "Kein Platz zum Übertragen",
+	"No later day has room for this work within the maximum load. Add a day or make room and it rolls over with the next run.": "Kein späterer Tag hat innerhalb der maximalen Auslastung Platz für diese Arbeit. Lege einen Tag an oder schaffe Platz, dann wird sie beim nächsten Durchlauf übertragen.",
+	"There is no later day to move it to": "Es gibt keinen späteren Tag, auf den es verschoben werden kann",
+
+	// Deadline risks
+	"At risk":                         "Gefährdet",
+	"%s late":                         "%s zu spät",
+	"done by %s":                      "fertig am %s",
+	"no room":                         "kein Platz",
+	"%s of work left at the deadline": "%s Arbeit bleibt zur Frist übrig",
+	"The remaining work won't be done before these deadlines.": "Die verbleibende Arbeit wird vor diesen Fristen nicht fertig.",
+	"Every deadline can be met.":                               "Alle Fristen können eingehalten werden.",
+
+	// Conflicts
+	"The event overlaps other events or lies outside of the working window": "Der Termin überschneidet sich mit anderen Terminen oder liegt außerhalb des Arbeitszeitfensters",
+	"Overlaps with %s (%s - %s)":              "Überschneidet sich mit %s (%s - %s)",
+	"Outside of the working window (%s - %s)": "Außerhalb des Arbeitszeitfensters (%s - %s)",
+	"Allow double-booking":                    "Doppelbuchung erlauben",
+	"may be double-booked":                    "darf doppelt gebucht sein",
+
+	// Forms
+	"New event":                      "Neuer Termin",
+	"Edit event":                     "Termin bearbeiten",
+	"New todo":                       "Neue Aufgabe",
+	"Edit todo":                      "Aufgabe bearbeiten",
+	"Date:":                          "Datum:",
+	"Start of day:":                  "Arbeitsbeginn:",
+	"End of day:":                    "Arbeitsende:",
+	"%s, working window %s - %s":     "%s, Arbeitszeit %s - %s",
+	"Title:":                         "Titel:",
+	"Fixed time":                     "Feste Uhrzeit",
+	"Start:":                         "Beginn:",
+	"End:":                           "Ende:",
+	"Or just a duration":             "Oder nur eine Dauer",
+	"Duration (minutes):":            "Dauer (Minuten):",
+	"Deadline:":                      "Frist:",
+	"Event: %s":                      "Termin: %s",
+	"Name:":                          "Name:",
+	"Description:":                   "Beschreibung:",
+	"Done":                           "Erledigt",
+	"Timezone:":                      "Zeitzone:",
+	"Language:":                      "Sprache:",
+	"Language of the browser":        "Sprache des Browsers",
+	"Your settings have been saved.": "Deine Einstellungen wurden gespeichert.",
+	"An IANA name like Europe/Berlin, leave it empty to use the server's timezone (%s).": "Ein IANA-Name wie Europe/Berlin, leer lassen für die Zeitzone des Servers (%s).",
+	"Used for the pages, dates, times and durations.":                                    "Gilt für die Seiten, Datumsangaben, Uhrzeiten und Dauern.",
+
+	// Validation
+	"Invalid date format! Please try again.":                                               "Ungültiges Datum! Bitte versuche es erneut.",
+	"Invalid time format! Please try again.":                                               "Ungültige Uhrzeit! Bitte versuche es erneut.",
+	"Please enter a positive number of minutes":                                            "Bitte gib eine positive Anzahl Minuten ein",
+	"Please enter a date":                                                                  "Bitte gib ein Datum ein",
+	"Please enter the start time of the day":                                               "Bitte gib den Arbeitsbeginn ein",
+	"Please enter the end time of the day":                                                 "Bitte gib das Arbeitsende ein",
+	"The end time of the day is not allowed to be less than or equal to the start time!":   "Das Arbeitsende muss nach dem Arbeitsbeginn liegen!",
+	"Start time and end time have to be in the same day!":                                  "Beginn und Ende müssen am selben Tag liegen!",
+	"Please enter a title":                                                                 "Bitte gib einen Titel ein",
+	"Please enter a duration or a start and end time":                                      "Bitte gib eine Dauer oder Beginn und Ende ein",
+	"Please enter the start time of the event":                                             "Bitte gib den Beginn des Termins ein",
+	"Please enter the end time of the event":                                               "Bitte gib das Ende des Termins ein",
+	"The end time of the event is not allowed to be less than or equal to the start time!": "Das Ende des Termins muss nach seinem Beginn liegen!",
+	"Please enter a name":                                                                  "Bitte gib einen Namen ein",
+	"This day already exists!":                                                             "Diesen Tag gibt es bereits!",
+	"Unknown timezone, use a name like Europe/Berlin or America/New_York":                  "Unbekannte Zeitzone, nutze einen Namen wie Europe/Berlin oder America/New_York",
+	"This language is not supported":                                                       "Diese Sprache wird nicht unterstützt",
+	"The direction has to be up or down":                                                   "Die Richtung muss up oder down sein",
+	"The view has to be one of list, day, week, month or matrix":                           "Die Ansicht muss list, day, week, month oder matrix sein",
+	"The priority has to be one of do, schedule, delegate or eliminate":                    "Die Priorität muss do, schedule, delegate oder eliminate sein",
+	"The list can only be sorted by position or priority":                                  "Die Liste kann nur nach position oder priority sortiert werden",
+	"Invalid date, expected YYYY-MM-DD":                                                    "Ungültiges Datum, erwartet wird JJJJ-MM-TT",
+
+	// Errors
+	"Unprocessable Entity":  "Ungültige Eingabe",
+	"Not Found":             "Nicht gefunden",
+	"Unauthorized":          "Nicht angemeldet",
+	"Conflict":              "Konflikt",
+	"Internal Server Error": "Serverfehler",
+	"Something went wrong on our side. Please try again later.": "Bei uns ist etwas schiefgelaufen. Bitte versuche es später erneut.",
+	"The requested item does not exist":                         "Den gewünschten Eintrag gibt es nicht",
+	"This page does not exist":                                  "Diese Seite gibt es nicht",
+	"Reference:":                                                "Referenz:",
+	"Back to the homepage":                                      "Zurück zur Startseite",
+	"or":                                                        "oder",
+	"your tasks":                                                "zu deinen Aufgaben",
+}
@@ -0,0 +1,256 @@
+// Package locale translates the texts of the web interface and formats dates, times and
+// durations the way the user's language does
+package locale
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Locale holds the names and formats of one language
+type Locale struct {
+	Tag  string
+	Name string
+
+	weekdays      [7]string
+	shortWeekdays [7]string
+	months        [12]string
+	shortMonths   [12]string
+
+	// formats use the names above, they get the locale and the time
+	date     func(l *Locale, t time.Time) string
+	longDate func(l *Locale, t time.Time) string
+	dateTime func(l *Locale, t time.Time) string
+	// span returns an amount of time as used in the phrases
+	span     func(n int, unit unit) string
+	duration func(hours, minutes int) string
+	phrases  phrases
+
+	// messages is the catalog of the texts, English is the source language and needs none
+	messages map[string]string
+}
+
+type unit int
+
+const (
+	minutes unit = iota
+	hours
+	days
+)
+
+// phrases are the fmt formats wrapped around a span of time
+type phrases struct {
+	now     string
+	future  string
+	past    string
+	dueIn   string
+	overdue string
+}
+
+// Default is used when the user didn't pick a locale
+var Default = locales["en"]
+
+// Get returns the locale with the given tag
+func Get(tag string) (*Locale, bool) {
+	l, ok := locales[tag]
+	return l, ok
+}
+
+// All returns every supported locale ordered by tag
+func All() []*Locale {
+	all := make([]*Locale, 0, len(locales))
+	for _, l := range locales {
+		all = append(all, l)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Tag < all[j].Tag })
+	return all
+}
+
+func (l *Locale) Weekday(t time.Time) string {
+	return l.weekdays[t.Weekday()]
+}
+
+func (l *Locale) ShortWeekday(t time.Time) string {
+	return l.shortWeekdays[t.Weekday()]
+}
+
+func (l *Locale) Month(t time.Time) string {
+	return l.months[t.Month()-1]
+}
+
+func (l *Locale) ShortMonth(t time.Time) string {
+	return l.shortMonths[t.Month()-1]
+}
+
+// Date formats the date of t, e.g. "Mon, Jan 2 2006"
+func (l *Locale) Date(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return l.date(l, t)
+}
+
+// LongDate formats the date of t with the full weekday, e.g. "Monday, Jan 2 2006"
+func (l *Locale) LongDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return l.longDate(l, t)
+}
+
+// DateTime formats a point in time without the year, e.g. "Jan 2 15:04"
+func (l *Locale) DateTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return l.dateTime(l, t)
+}
+
+// Clock formats the time of day, all supported locales use the 24-hour clock
+func (l *Locale) Clock(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("15:04")
+}
+
+// MonthYear formats the month of t, e.g. "January 2006"
+func (l *Locale) MonthYear(t time.Time) string {
+	return fmt.Sprintf("%s %d", l.Month(t), t.Year())
+}
+
+// Duration humanizes d like the CLI does, e.g. "1h 30min"
+func (l *Locale) Duration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return l.duration(int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
+
+// roughSpan reduces d to its largest sensible unit
+func roughSpan(d time.Duration) (int, unit) {
+	if d < 0 {
+		d = -d
+	}
+	switch {
+	case d < time.Hour:
+		return int(d / time.Minute), minutes
+	case d < 48*time.Hour:
+		return int(d / time.Hour), hours
+	}
+	return int(d / (24 * time.Hour)), days
+}
+
+// Relative describes t as seen from now, e.g. "in 3h" or "2 days ago"
+func (l *Locale) Relative(t time.Time, now time.Time) string {
+	d := t.Sub(now)
+	if d > -time.Minute && d < time.Minute {
+		return l.phrases.now
+	}
+
+	n, unit := roughSpan(d)
+	if d > 0 {
+		return fmt.Sprintf(l.phrases.future, l.span(n, unit))
+	}
+	return fmt.Sprintf(l.phrases.past, l.span(n, unit))
+}
+
+// Due describes a deadline as seen from now, e.g. "due in 3h" or "overdue by 2 days"
+func (l *Locale) Due(deadline time.Time, now time.Time) string {
+	d := deadline.Sub(now)
+	n, unit := roughSpan(d)
+	if d >= 0 {
+		return fmt.Sprintf(l.phrases.dueIn, l.span(n, unit))
+	}
+	return fmt.Sprintf(l.phrases.overdue, l.span(n, unit))
+}
+
+var locales = map[string]*Locale{
+	"en": {
+		Tag:           "en",
+		Name:          "English",
+		weekdays:      [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		shortWeekdays: [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+		months: [12]string{"January", "February", "March", "April", "May", "June", "July", "August",
+			"September", "October", "November", "December"},
+		shortMonths: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		date: func(l *Locale, t time.Time) string {
+			return fmt.Sprintf("%s, %s %d %d", l.ShortWeekday(t), l.ShortMonth(t), t.Day(), t.Year())
+		},
+		longDate: func(l *Locale, t time.Time) string {
+			return fmt.Sprintf("%s, %s %d %d", l.Weekday(t), l.ShortMonth(t), t.Day(), t.Year())
+		},
+		dateTime: func(l *Locale, t time.Time) string {
+			return fmt.Sprintf("%s %d %s", l.ShortMonth(t), t.Day(), t.Format("15:04"))
+		},
+		span: func(n int, unit unit) string {
+			switch unit {
+			case minutes:
+				return fmt.Sprintf("%dmin", n)
+			case hours:
+				return fmt.Sprintf("%dh", n)
+			}
+			if n == 1 {
+				return "1 day"
+			}
+			return fmt.Sprintf("%d days", n)
+		},
+		duration: func(hours, minutes int) string {
+			if minutes > 0 {
+				return fmt.Sprintf("%dh %dmin", hours, minutes)
+			}
+			return fmt.Sprintf("%dh", hours)
+		},
+		phrases: phrases{
+			now:     "now",
+			future:  "in %s",
+			past:    "%s ago",
+			dueIn:   "due in %s",
+			overdue: "overdue by %s",
+		},
+	},
+	"de": {
+		Tag:           "de",
+		Name:          "Deutsch",
+		weekdays:      [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		shortWeekdays: [7]string{"So.", "Mo.", "Di.", "Mi.", "Do.", "Fr.", "Sa."},
+		months: [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August",
+			"September", "Oktober", "November", "Dezember"},
+		shortMonths: [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		date: func(l *Locale, t time.Time) string {
+			return fmt.Sprintf("%s, %d. %s %d", l.ShortWeekday(t), t.Day(), l.ShortMonth(t), t.Year())
+		},
+		longDate: func(l *Locale, t time.Time) string {
+			return fmt.Sprintf("%s, %d. %s %d", l.Weekday(t), t.Day(), l.ShortMonth(t), t.Year())
+		},
+		dateTime: func(l *Locale, t time.Time) string {
+			return fmt.Sprintf("%d. %s %s", t.Day(), l.ShortMonth(t), t.Format("15:04"))
+		},
+		// Every phrase puts the span after a preposition, so days are in the dative
+		span: func(n int, unit unit) string {
+			switch unit {
+			case minutes:
+				return fmt.Sprintf("%d Min.", n)
+			case hours:
+				return fmt.Sprintf("%d Std.", n)
+			}
+			if n == 1 {
+				return "1 Tag"
+			}
+			return fmt.Sprintf("%d Tagen", n)
+		},
+		duration: func(hours, minutes int) string {
+			if minutes > 0 {
+				return fmt.Sprintf("%d Std. %d Min.", hours, minutes)
+			}
+			return fmt.Sprintf("%d Std.", hours)
+		},
+		phrases: phrases{
+			now:     "jetzt",
+			future:  "in %s",
+			past:    "vor %s",
+			dueIn:   "fällig in %s",
+			overdue: "seit %s überfällig",
+		},
+		messages: german,
+	},
+}
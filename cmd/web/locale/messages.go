@@ -0,0 +1,62 @@
+package locale
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The texts of the pages and the messages of the handlers are written in English and
+// used as the keys of the catalogs. A catalog maps them to the text of its language,
+// missing entries fall back to the English text.
+
+// T translates msg. With args the translation is used as a fmt format.
+func (l *Locale) T(msg string, args ...any) string {
+	if translated, ok := l.messages[msg]; ok {
+		msg = translated
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Negotiate picks the supported locale the client prefers the most from an
+// Accept-Language header, e.g. "de-AT,de;q=0.9,en;q=0.8". Regional variants match
+// their language.
+func Negotiate(header string) *Locale {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag == "" || tag == "*" || q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{strings.ToLower(tag), q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if l, ok := locales[c.tag]; ok {
+			return l
+		}
+		base, _, _ := strings.Cut(c.tag, "-")
+		if l, ok := locales[base]; ok {
+			return l
+		}
+	}
+	return Default
+}
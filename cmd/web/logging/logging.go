@@ -0,0 +1,117 @@
+// Package logging sets up the structured logger of the web server and carries a
+// request scoped logger through the context.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// redactedKeys holds attribute keys whose values must never show up in the log
+var redactedKeys = map[string]bool{
+	"password":         true,
+	"password_retyped": true,
+	"session_id":       true,
+	"sessionid":        true,
+	"cookie":           true,
+	"set-cookie":       true,
+	"authorization":    true,
+}
+
+const redacted = "[REDACTED]"
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if redactedKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+// New creates a logger writing text or JSON lines to w
+func New(w io.Writer, level slog.Level, format string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+// RequestInfo is filled while a request is handled and logged once it finished
+type RequestInfo struct {
+	ID string
+
+	mu     sync.Mutex
+	route  string
+	userId int
+}
+
+func (i *RequestInfo) SetRoute(route string) {
+	i.mu.Lock()
+	i.route = route
+	i.mu.Unlock()
+}
+
+func (i *RequestInfo) Route() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.route
+}
+
+func (i *RequestInfo) SetUserID(userId int) {
+	i.mu.Lock()
+	i.userId = userId
+	i.mu.Unlock()
+}
+
+// UserID returns the id of the logged-in user or 0 if there is none
+func (i *RequestInfo) UserID() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.userId
+}
+
+type loggerKey struct{}
+type infoKey struct{}
+
+// NewContext returns a context carrying the logger and the request info
+func NewContext(ctx context.Context, logger *slog.Logger, info *RequestInfo) context.Context {
+	ctx = context.WithValue(ctx, loggerKey{}, logger)
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+// FromContext returns the request logger or the default logger outside of requests
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// Info returns the request info, it is nil outside of requests
+func Info(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(infoKey{}).(*RequestInfo)
+	return info
+}
+
+// SetUserID records the logged-in user of the request
+func SetUserID(ctx context.Context, userId int) {
+	if info := Info(ctx); info != nil {
+		info.SetUserID(userId)
+	}
+}
+
+// RequestID returns the id of the request or an empty string outside of requests
+func RequestID(ctx context.Context) string {
+	if info := Info(ctx); info != nil {
+		return info.ID
+	}
+	return ""
+}
@@ -1,32 +1,209 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	taskweave "github.com/Shu-AFK/TaskWeave"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/certs"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/config"
 	"github.com/Shu-AFK/TaskWeave/cmd/web/handler"
 	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/metrics"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/middleware"
 	"github.com/gorilla/mux"
+	"io/fs"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	_ "time/tzdata"
 )
 
+// webFiles returns the templates, static files and assets. In dev mode they are read
+// from the working directory so that changes show up without a rebuild.
+func webFiles(dev bool) (templates fs.FS, static fs.FS, assets fs.FS) {
+	if dev {
+		return os.DirFS("templates"), os.DirFS("static"), os.DirFS("assets")
+	}
+
+	templates, _ = fs.Sub(taskweave.Templates, "templates")
+	static, _ = fs.Sub(taskweave.Static, "static")
+	assets, _ = fs.Sub(taskweave.Assets, "assets")
+	return templates, static, assets
+}
+
+// noListing hides directories so that the file server answers them with a 404 instead
+// of listing their content
+type noListing struct {
+	fs.FS
+}
+
+func (n noListing) Open(name string) (fs.File, error) {
+	f, err := n.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		return nil, fs.ErrNotExist
+	}
+	return f, nil
+}
+
+// fileServer serves the files of fsys below prefix
+func fileServer(prefix string, fsys fs.FS) http.Handler {
+	return http.StripPrefix(prefix, http.FileServer(http.FS(noListing{fsys})))
+}
+
+// applyConfig hands the settings over to the packages that use them
+func applyConfig(cfg config.Config) *slog.Logger {
+	level, _ := cfg.Level()
+	logger, _ := logging.New(os.Stderr, level, cfg.LogFormat)
+	slog.SetDefault(logger)
+
+	sameSite, _ := cfg.SameSite()
+	internal.DBPath = cfg.DBPath
+	internal.Sessions = internal.SessionSettings{
+		CookieSecure:   cfg.CookieSecure(),
+		CookieDomain:   cfg.Cookie.Domain,
+		CookieSameSite: sameSite,
+		Lifetime:       cfg.Session.Lifetime,
+		IdleTimeout:    cfg.Session.IdleTimeout,
+	}
+	handler.RegistrationOpen = cfg.Registration == config.RegistrationOpen
+
+	return logger
+}
+
+// registerGauges adds the metrics which are read from the database on every scrape
+func registerGauges() {
+	metrics.NewGaugeFunc("taskweave_active_sessions", "Number of sessions that didn't expire.", internal.CountActiveSessions)
+	metrics.NewGaugeFunc("taskweave_open_todos", "Number of todos which aren't done.", internal.CountOpenTodos)
+	metrics.NewGaugeFunc("taskweave_overdue_todos", "Number of open todos whose deadline passed.", internal.CountOverdueTodos)
+	metrics.NewGaugeFunc("taskweave_overdue_events", "Number of events whose deadline passed with work left.", internal.CountOverdueEvents)
+}
+
 func main() {
-	// Creates the DB if it doesn't exist already
+	if len(os.Args) > 1 && os.Args[1] == "dev-cert" {
+		err := devCert(os.Args[2:])
+		if err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "Creating the certificate failed:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, "Invalid configuration:", err)
+		os.Exit(2)
+	}
+	logger := applyConfig(cfg)
+
+	templates, static, assets := webFiles(cfg.Dev)
+	err = handler.LoadTemplates(templates, cfg.Dev)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Creates the DB if it doesn't exist already, it is closed when the server shuts down
 	internal.CreateDB()
 
 	// Use Gorilla Mux for routing
 	r := mux.NewRouter()
+	r.Use(middleware.RecordRoute)
+	r.Use(handler.UserPrefs)
+	r.NotFoundHandler = http.HandlerFunc(handler.NotFoundHandler)
 	r.HandleFunc("/", handler.Index)
 	r.HandleFunc("/tasks", handler.TasksHandler)
 	r.HandleFunc("/login", handler.LoginHandler)
 	r.HandleFunc("/signup", handler.SignupHandler)
+	r.HandleFunc("/tasks/settings", handler.SettingsHandler).Methods(http.MethodGet, http.MethodPost)
+
+	r.HandleFunc("/tasks/schedule", handler.ScheduleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tasks/balance", handler.BalanceHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/tasks/series/{series:[0-9]+}", handler.SeriesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/tasks/unblocked", handler.UnblockedHandler).Methods(http.MethodGet)
+	r.HandleFunc("/tasks/report", handler.ReportHandler).Methods(http.MethodGet)
+	r.HandleFunc("/tasks/rollover", handler.RolloverHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tasks/risks", handler.RisksHandler).Methods(http.MethodGet)
+
+	// Create, edit and delete days, events and todos
+	r.HandleFunc("/tasks/days/new", handler.NewDayHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/tasks/days/{day:[0-9]+}/delete", handler.DeleteDayHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tasks/days/{day:[0-9]+}/events/new", handler.NewEventHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/tasks/events/{event:[0-9]+}/edit", handler.EditEventHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/tasks/events/{event:[0-9]+}/delete", handler.DeleteEventHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tasks/events/{event:[0-9]+}/todos/new", handler.NewTodoHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/tasks/todos/{todo:[0-9]+}/edit", handler.EditTodoHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/tasks/todos/{todo:[0-9]+}/delete", handler.DeleteTodoHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tasks/events/{event:[0-9]+}/dependencies", handler.EventDependenciesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/tasks/events/{event:[0-9]+}/dependencies/{dependency:[0-9]+}/delete", handler.DeleteEventDependencyHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tasks/todos/{todo:[0-9]+}/dependencies", handler.TodoDependenciesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/tasks/todos/{todo:[0-9]+}/dependencies/{dependency:[0-9]+}/delete", handler.DeleteTodoDependencyHandler).Methods(http.MethodPost)
+
+	// Time tracking
+	r.HandleFunc("/tasks/timer", handler.TimerHandler).Methods(http.MethodGet)
+	r.HandleFunc("/tasks/timer/stop", handler.StopTimerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tasks/events/{event:[0-9]+}/timer/start", handler.StartEventTimerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tasks/todos/{todo:[0-9]+}/timer/start", handler.StartTodoTimerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tasks/events/{event:[0-9]+}/time", handler.EventTimeHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/tasks/events/{event:[0-9]+}/time/{entry:[0-9]+}/delete", handler.DeleteEventTimeEntryHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tasks/todos/{todo:[0-9]+}/time", handler.TodoTimeHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/tasks/todos/{todo:[0-9]+}/time/{entry:[0-9]+}/delete", handler.DeleteTodoTimeEntryHandler).Methods(http.MethodPost)
+
+	// Small updates that answer with an HTML fragment
+	r.HandleFunc("/tasks/todos/{todo:[0-9]+}/toggle", handler.ToggleTodoHandler).Methods(http.MethodPost, http.MethodPatch)
+	r.HandleFunc("/tasks/todos/{todo:[0-9]+}/rename", handler.RenameTodoHandler).Methods(http.MethodPost, http.MethodPatch)
+	r.HandleFunc("/tasks/todos/{todo:[0-9]+}/move", handler.MoveTodoHandler).Methods(http.MethodPost, http.MethodPatch)
+	r.HandleFunc("/tasks/events/{event:[0-9]+}/rename", handler.RenameEventHandler).Methods(http.MethodPost, http.MethodPatch)
+	r.HandleFunc("/tasks/events/{event:[0-9]+}/toggle", handler.ToggleEventHandler).Methods(http.MethodPost, http.MethodPatch)
+	r.HandleFunc("/tasks/events/{event:[0-9]+}/move", handler.MoveEventHandler).Methods(http.MethodPost, http.MethodPatch)
+
+	// Live updates of the tasks page
+	r.HandleFunc("/tasks/changes", handler.ChangesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/tasks/days/{day:[0-9]+}", handler.DayCardHandler).Methods(http.MethodGet)
+	r.HandleFunc("/tasks/events/{event:[0-9]+}", handler.EventCardHandler).Methods(http.MethodGet)
+
+	// Health checks for process supervisors and load balancers
+	r.HandleFunc("/healthz", handler.HealthzHandler).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", handler.ReadyzHandler).Methods(http.MethodGet)
+
+	// Prometheus metrics
+	registerGauges()
+	r.Handle("/metrics", metrics.Default.Handler()).Methods(http.MethodGet)
 
 	// Serve assets
-	r.PathPrefix("/assets/").Handler(http.StripPrefix("/assets/", http.FileServer(http.Dir("assets/"))))
+	r.PathPrefix("/assets/").Handler(fileServer("/assets/", assets))
+
+	// Serve static files (CSS, JavaScript and icons)
+	r.PathPrefix("/static/").Handler(fileServer("/static/", static))
 
-	// Serve static files (CSS)
-	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("static/"))))
+	// Load the TLS certificate, it is reloaded on SIGHUP and when the files change
+	var reloader *certs.Reloader
+	if cfg.TLSEnabled() {
+		reloader, err = certs.NewReloader(cfg.TLS.Cert, cfg.TLS.Key)
+		if err != nil {
+			log.Fatal("Loading TLS certificate: ", err)
+		}
+	}
 
 	// Start the server
-	fmt.Println("Server is listening on port 8080")
-	log.Fatal(http.ListenAndServe(":8080", r))
+	workers := startWorkers(reloader)
+	handler.WorkerStatus = workers.Status
+	root := middleware.SecurityHeaders(cfg.HTTPS())(middleware.Recover(handler.RenderError)(r))
+	srv := newServer(cfg.Listen, middleware.RequestLogger(logger)(middleware.Metrics(root)), reloader)
+	err = serve(cfg, srv, workers, reloader)
+	if err != nil {
+		logger.Error("Server stopped with an error", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("Server stopped")
 }
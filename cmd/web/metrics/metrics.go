@@ -0,0 +1,264 @@
+// Package metrics keeps counters, histograms and gauges of the web server and exposes
+// them in the Prometheus text format.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds in seconds used for latency histograms
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(ctx context.Context, w io.Writer)
+}
+
+// Registry holds every metric that is exported
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// Default is the registry all metrics of this package are added to
+var Default = &Registry{}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	r.collectors = append(r.collectors, c)
+	r.mu.Unlock()
+}
+
+// Handler serves all metrics of the registry
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		collectors := append([]collector(nil), r.collectors...)
+		r.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, c := range collectors {
+			c.write(req.Context(), w)
+		}
+	})
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names []string, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(names)+len(extra)/2)
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(values[i])))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[i], labelEscaper.Replace(extra[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// series stores one value per combination of label values
+type series[T any] struct {
+	mu     sync.Mutex
+	values map[string]*T
+	labels map[string][]string
+}
+
+func (s *series[T]) get(labelValues []string, create func() *T) *T {
+	key := strings.Join(labelValues, "\xff")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.values == nil {
+		s.values = map[string]*T{}
+		s.labels = map[string][]string{}
+	}
+	v, ok := s.values[key]
+	if !ok {
+		v = create()
+		s.values[key] = v
+		s.labels[key] = append([]string(nil), labelValues...)
+	}
+	return v
+}
+
+// each calls fn for every series ordered by label values so the output is stable
+func (s *series[T]) each(fn func(labelValues []string, v *T)) {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]*T, len(keys))
+	labels := make([][]string, len(keys))
+	for i, key := range keys {
+		values[i] = s.values[key]
+		labels[i] = s.labels[key]
+	}
+	s.mu.Unlock()
+
+	for i := range keys {
+		fn(labels[i], values[i])
+	}
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+	series series[counter]
+}
+
+type counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels}
+	Default.register(c)
+	return c
+}
+
+// Add increases the counter of the label values by v
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	ctr := c.series.get(labelValues, func() *counter { return &counter{} })
+	ctr.mu.Lock()
+	ctr.value += v
+	ctr.mu.Unlock()
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) write(_ context.Context, w io.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	c.series.each(func(labelValues []string, ctr *counter) {
+		ctr.mu.Lock()
+		value := ctr.value
+		ctr.mu.Unlock()
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, labelValues), formatFloat(value))
+	})
+}
+
+// HistogramVec counts observations into buckets, partitioned by labels
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	series  series[histogram]
+}
+
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets}
+	Default.register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	hist := h.series.get(labelValues, func() *histogram {
+		return &histogram{counts: make([]uint64, len(h.buckets))}
+	})
+
+	hist.mu.Lock()
+	for i, bound := range h.buckets {
+		if v <= bound {
+			hist.counts[i]++
+		}
+	}
+	hist.count++
+	hist.sum += v
+	hist.mu.Unlock()
+}
+
+func (h *HistogramVec) write(_ context.Context, w io.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+	h.series.each(func(labelValues []string, hist *histogram) {
+		hist.mu.Lock()
+		counts := append([]uint64(nil), hist.counts...)
+		count, sum := hist.count, hist.sum
+		hist.mu.Unlock()
+
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, labelValues, "le", formatFloat(bound)), counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, labelValues, "le", "+Inf"), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, labelValues), formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, labelValues), count)
+	})
+}
+
+// GaugeFunc is a gauge whose value is read when the metrics are scraped
+type GaugeFunc struct {
+	name string
+	help string
+	fn   func(ctx context.Context) (float64, error)
+}
+
+func NewGaugeFunc(name, help string, fn func(ctx context.Context) (float64, error)) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, fn: fn}
+	Default.register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(ctx context.Context, w io.Writer) {
+	value, err := g.fn(ctx)
+	if err != nil {
+		slog.Error("Reading gauge failed", "metric", g.name, "error", err)
+		return
+	}
+
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(value))
+}
+
+// Metrics of the web server
+var (
+	HTTPRequests = NewCounterVec("taskweave_http_requests_total",
+		"Number of HTTP requests by route and status.", "method", "route", "status")
+	HTTPDuration = NewHistogramVec("taskweave_http_request_duration_seconds",
+		"Latency of HTTP requests by route.", DefaultBuckets, "method", "route")
+	Logins = NewCounterVec("taskweave_logins_total",
+		"Number of login attempts by result.", "result")
+	DBQueryDuration = NewHistogramVec("taskweave_db_query_duration_seconds",
+		"Latency of database queries by statement type.", DefaultBuckets, "op")
+	DBErrors = NewCounterVec("taskweave_db_errors_total",
+		"Number of failed database queries by statement type.", "op")
+)
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics counts requests and their latency per route. It has to run inside of
+// RequestLogger, which provides the matched route.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		route := "unmatched"
+		if info := logging.Info(r.Context()); info != nil && info.Route() != "" {
+			route = info.Route()
+		}
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		metrics.HTTPRequests.Inc(r.Method, route, strconv.Itoa(status))
+		metrics.HTTPDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
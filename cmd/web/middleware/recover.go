@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/logging"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover turns a panicking handler into an internal error. The panic and its stack
+// are passed on to onError, which is expected to log them and answer the client. If the
+// handler already started the response only the log line is written.
+func Recover(onError func(http.ResponseWriter, *http.Request, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w}
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+
+				err := fmt.Errorf("panic: %v\n%s", v, debug.Stack())
+				if rec.status != 0 {
+					logging.FromContext(r.Context()).Error("Handler panicked after writing the response", "error", err)
+					return
+				}
+				onError(rec, r, err)
+			}()
+
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/certs"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/config"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/handler"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/internal"
+	"github.com/Shu-AFK/TaskWeave/cmd/web/worker"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 15 * time.Second
+	writeTimeout      = 30 * time.Second
+	idleTimeout       = 2 * time.Minute
+	maxHeaderBytes    = 64 << 10
+
+	sessionSweepInterval = time.Hour
+	rolloverInterval     = 15 * time.Minute
+	certWatchInterval    = 30 * time.Second
+)
+
+// newServer creates the server for addr. With a certificate reloader the server speaks
+// HTTPS and always presents the latest certificate.
+func newServer(addr string, h http.Handler, reloader *certs.Reloader) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+	if reloader != nil {
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+	}
+	return srv
+}
+
+// redirectToHTTPS sends every request to the same path on the HTTPS listener. The host
+// of an https base URL is used if there is one, otherwise the host of the request.
+func redirectToHTTPS(cfg config.Config) http.Handler {
+	base, _ := url.Parse(cfg.BaseURL)
+	_, port, _ := net.SplitHostPort(cfg.Listen)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := url.URL{Scheme: "https", Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		if base != nil && base.Scheme == "https" {
+			target.Host = base.Host
+		} else {
+			host := r.Host
+			if h, _, err := net.SplitHostPort(r.Host); err == nil {
+				host = h
+			}
+			if port != "443" {
+				host = net.JoinHostPort(host, port)
+			}
+			target.Host = host
+		}
+
+		http.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+	})
+}
+
+// startWorkers starts the background jobs of the server
+func startWorkers(reloader *certs.Reloader) *worker.Group {
+	workers := worker.NewGroup()
+
+	if reloader != nil {
+		workers.Start("tls-cert-watcher", certWatchInterval, func(ctx context.Context) error {
+			reloaded, err := reloader.ReloadIfChanged()
+			if reloaded {
+				slog.Info("Reloaded TLS certificate after the files changed", "expires", reloader.NotAfter())
+			}
+			return err
+		})
+	}
+
+	workers.Start("session-sweeper", sessionSweepInterval, func(ctx context.Context) error {
+		n, err := internal.DeleteExpiredSessions(ctx)
+		if err == nil && n > 0 {
+			slog.Info("Removed expired sessions", "count", n)
+		}
+		return err
+	})
+
+	workers.Start("rollover", rolloverInterval, func(ctx context.Context) error {
+		moved, missed, stuck, err := internal.RollOverAll(ctx, time.Now())
+		if moved > 0 || missed > 0 {
+			slog.Info("Rolled over unfinished work", "moved", moved, "missed", missed)
+		}
+		if stuck > 0 {
+			slog.Warn("Unfinished work has no later day with room and stays behind", "events", stuck)
+		}
+		return err
+	})
+
+	return workers
+}
+
+// serve runs the server until SIGINT or SIGTERM arrives. It then stops accepting
+// connections, lets in-flight requests and workers finish and closes the database last.
+// SIGHUP reloads the TLS certificate.
+func serve(cfg config.Config, srv *http.Server, workers *worker.Group, reloader *certs.Reloader) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if reloader != nil {
+		go reloadOnHangup(ctx, reloader)
+	}
+
+	serverErr := make(chan error, 2)
+	go func() {
+		slog.Info("Server is listening", "addr", cfg.Listen, "tls", cfg.TLSEnabled())
+		if cfg.TLSEnabled() {
+			// The certificate comes from srv.TLSConfig
+			serverErr <- srv.ListenAndServeTLS("", "")
+		} else {
+			serverErr <- srv.ListenAndServe()
+		}
+	}()
+
+	var redirect *http.Server
+	if cfg.TLS.RedirectListen != "" {
+		redirect = newServer(cfg.TLS.RedirectListen, redirectToHTTPS(cfg), nil)
+		go func() {
+			slog.Info("Redirecting HTTP to HTTPS", "addr", cfg.TLS.RedirectListen)
+			serverErr <- redirect.ListenAndServe()
+		}()
+	}
+
+	var err error
+	select {
+	case err = <-serverErr:
+		// The server couldn't start, still stop the workers and close the database
+	case <-ctx.Done():
+		slog.Info("Shutting down, waiting for in-flight requests", "timeout", cfg.ShutdownTimeout)
+	}
+	stop()
+
+	// Fail the readiness check first and give load balancers time to notice before the
+	// listener is closed
+	handler.SetShuttingDown()
+	if err == nil && cfg.ShutdownDelay > 0 {
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	errs := []error{}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, err)
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("shutting down server: %w", err))
+	}
+	if redirect != nil {
+		if err := redirect.Shutdown(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("shutting down redirect server: %w", err))
+		}
+	}
+	if err := workers.Stop(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("stopping workers: %w", err))
+	}
+	if err := internal.CloseDB(); err != nil {
+		errs = append(errs, fmt.Errorf("closing database: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// reloadOnHangup loads the certificate again whenever SIGHUP arrives until ctx is done
+func reloadOnHangup(ctx context.Context, reloader *certs.Reloader) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			err := reloader.Reload()
+			if err != nil {
+				slog.Error("Reloading TLS certificate failed, keeping the old one", "error", err)
+				continue
+			}
+			slog.Info("Reloaded TLS certificate", "expires", reloader.NotAfter())
+		}
+	}
+}
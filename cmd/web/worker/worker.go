@@ -0,0 +1,121 @@
+// Package worker runs periodic background jobs of the web server, such as removing
+// expired sessions, and stops them in an orderly way on shutdown.
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Status describes the state of a worker at one point in time
+type Status struct {
+	Name      string    `json:"name"`
+	Running   bool      `json:"running"`
+	Runs      int       `json:"runs"`
+	LastRun   time.Time `json:"last_run"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+type worker struct {
+	interval time.Duration
+	run      func(ctx context.Context) error
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Group owns a set of workers which share one lifetime
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	workers []*worker
+}
+
+func NewGroup() *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{ctx: ctx, cancel: cancel}
+}
+
+// Start runs fn right away and then every interval until the group is stopped. A run
+// that is in progress when the group stops gets its context cancelled.
+func (g *Group) Start(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	w := &worker{interval: interval, run: fn, status: Status{Name: name, Running: true}}
+
+	g.mu.Lock()
+	g.workers = append(g.workers, w)
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			w.mu.Lock()
+			w.status.Running = false
+			w.mu.Unlock()
+		}()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			err := w.run(g.ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				slog.Error("Worker failed", "worker", name, "error", err)
+			}
+
+			w.mu.Lock()
+			w.status.Runs++
+			w.status.LastRun = time.Now()
+			w.status.LastError = ""
+			if err != nil {
+				w.status.LastError = err.Error()
+			}
+			w.mu.Unlock()
+
+			select {
+			case <-g.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels all workers and waits until they returned or ctx is done
+func (g *Group) Stop(ctx context.Context) error {
+	g.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Status returns the state of every worker in the order they were started
+func (g *Group) Status() []Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	statuses := make([]Status, 0, len(g.workers))
+	for _, w := range g.workers {
+		w.mu.Lock()
+		statuses = append(statuses, w.status)
+		w.mu.Unlock()
+	}
+
+	return statuses
+}
@@ -0,0 +1,145 @@
+// Package conflict finds events that overlap each other or lie outside of the working
+// window of their day. It is shared by the CLI and the web server.
+package conflict
+
+import (
+	"sort"
+	"time"
+)
+
+// Kind is the type of a conflict
+type Kind int
+
+const (
+	// Overlap is time that is booked by two events
+	Overlap Kind = iota + 1
+	// OutsideWindow is time of an event before the start or after the end of its day
+	OutsideWindow
+)
+
+var kinds = map[Kind]struct{ code, text string }{
+	Overlap:       {"overlap", "Overlaps another event"},
+	OutsideWindow: {"outside_window", "Lies outside of the working window"},
+}
+
+// Code identifies the kind in APIs, e.g. "overlap"
+func (k Kind) Code() string {
+	return kinds[k].code
+}
+
+// String describes the kind in English
+func (k Kind) String() string {
+	return kinds[k].text
+}
+
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.Code()), nil
+}
+
+// Event is an event with a fixed time
+type Event struct {
+	ID    int64     `json:"id"`
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// AllowOverlap accepts double-booking the event with any other
+	AllowOverlap bool `json:"allow_overlap"`
+}
+
+// Window is the working window of a day, a zero window is not checked
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Conflict is one problem of an event
+type Conflict struct {
+	Kind Kind `json:"kind"`
+	// With is the other event of an overlap
+	With *Event `json:"with,omitempty"`
+	// Start and End are the time booked twice or the time outside of the window
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Check returns the conflicts of event with the working window and the other events of
+// its day. Others may contain the event itself, it is skipped by its ID.
+func Check(window Window, others []Event, event Event) []Conflict {
+	var conflicts []Conflict
+
+	if !window.Start.IsZero() && event.Start.Before(window.Start) {
+		conflicts = append(conflicts, Conflict{Kind: OutsideWindow, Start: event.Start, End: earlier(event.End, window.Start)})
+	}
+	if !window.End.IsZero() && event.End.After(window.End) {
+		conflicts = append(conflicts, Conflict{Kind: OutsideWindow, Start: later(event.Start, window.End), End: event.End})
+	}
+
+	for _, other := range others {
+		if other.ID == event.ID {
+			continue
+		}
+		start, end := later(event.Start, other.Start), earlier(event.End, other.End)
+		if start.Before(end) {
+			other := other
+			conflicts = append(conflicts, Conflict{Kind: Overlap, With: &other, Start: start, End: end})
+		}
+	}
+
+	sort.SliceStable(conflicts, func(i, j int) bool { return conflicts[i].Start.Before(conflicts[j].Start) })
+	return conflicts
+}
+
+// ForDay checks every event of a day against the others and the window. The result
+// holds the conflicts of each event by its ID, events without any are left out.
+func ForDay(window Window, events []Event) map[int64][]Conflict {
+	found := map[int64][]Conflict{}
+	for _, event := range events {
+		if conflicts := Check(window, events, event); len(conflicts) > 0 {
+			found[event.ID] = conflicts
+		}
+	}
+	return found
+}
+
+// Allowed reports whether the user accepted the conflict of event, which is the case for
+// overlaps if one of the two events may be double-booked
+func (c Conflict) Allowed(event Event) bool {
+	return c.Kind == Overlap && (event.AllowOverlap || c.With.AllowOverlap)
+}
+
+// Unallowed returns the conflicts of event that weren't accepted
+func Unallowed(event Event, conflicts []Conflict) []Conflict {
+	var rest []Conflict
+	for _, c := range conflicts {
+		if !c.Allowed(event) {
+			rest = append(rest, c)
+		}
+	}
+	return rest
+}
+
+// Without returns the conflicts that are not of the given kind, e.g. to allow
+// double-booking
+func Without(conflicts []Conflict, kind Kind) []Conflict {
+	var rest []Conflict
+	for _, c := range conflicts {
+		if c.Kind != kind {
+			rest = append(rest, c)
+		}
+	}
+	return rest
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,206 @@
+package conflict
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// at returns the time h:m of Oct 19 2026
+func at(h, m int) time.Time {
+	return time.Date(2026, 10, 19, h, m, 0, 0, time.UTC)
+}
+
+func event(id int64, start, end time.Time) Event {
+	return Event{ID: id, Name: string(rune('A' + id - 1)), Start: start, End: end}
+}
+
+// span is the part of a conflict that the tests compare, With is reduced to its ID
+type span struct {
+	Kind       Kind
+	With       int64
+	Start, End time.Time
+}
+
+func spans(conflicts []Conflict) []span {
+	var result []span
+	for _, c := range conflicts {
+		s := span{Kind: c.Kind, Start: c.Start, End: c.End}
+		if c.With != nil {
+			s.With = c.With.ID
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func TestCheck(t *testing.T) {
+	workday := Window{Start: at(9, 0), End: at(17, 0)}
+
+	tests := []struct {
+		name   string
+		window Window
+		others []Event
+		event  Event
+		want   []span
+	}{
+		{
+			name:   "an event inside the window without others has no conflicts",
+			window: workday,
+			event:  event(1, at(10, 0), at(11, 0)),
+		},
+		{
+			name:   "the event itself is skipped",
+			window: workday,
+			others: []Event{event(1, at(10, 0), at(11, 0))},
+			event:  event(1, at(10, 0), at(11, 0)),
+		},
+		{
+			name:   "overlapping events conflict for the time they share",
+			window: workday,
+			others: []Event{event(2, at(10, 30), at(12, 0))},
+			event:  event(1, at(10, 0), at(11, 0)),
+			want:   []span{{Overlap, 2, at(10, 30), at(11, 0)}},
+		},
+		{
+			name:   "an event inside another conflicts for all of its time",
+			window: workday,
+			others: []Event{event(2, at(10, 0), at(12, 0))},
+			event:  event(1, at(10, 30), at(11, 0)),
+			want:   []span{{Overlap, 2, at(10, 30), at(11, 0)}},
+		},
+		{
+			name:   "events that touch don't overlap",
+			window: workday,
+			others: []Event{event(2, at(9, 0), at(10, 0)), event(3, at(11, 0), at(12, 0))},
+			event:  event(1, at(10, 0), at(11, 0)),
+		},
+		{
+			name:   "an event may start with the window and end with it",
+			window: workday,
+			event:  event(1, at(9, 0), at(17, 0)),
+		},
+		{
+			name:   "time before the window is outside of it",
+			window: workday,
+			event:  event(1, at(8, 30), at(9, 30)),
+			want:   []span{{OutsideWindow, 0, at(8, 30), at(9, 0)}},
+		},
+		{
+			name:   "time after the window is outside of it",
+			window: workday,
+			event:  event(1, at(16, 30), at(17, 30)),
+			want:   []span{{OutsideWindow, 0, at(17, 0), at(17, 30)}},
+		},
+		{
+			name:   "an event completely before the window is outside for all of its time",
+			window: workday,
+			event:  event(1, at(7, 0), at(8, 0)),
+			want:   []span{{OutsideWindow, 0, at(7, 0), at(8, 0)}},
+		},
+		{
+			name:   "an event longer than the window is outside on both ends",
+			window: workday,
+			event:  event(1, at(8, 0), at(18, 0)),
+			want:   []span{{OutsideWindow, 0, at(8, 0), at(9, 0)}, {OutsideWindow, 0, at(17, 0), at(18, 0)}},
+		},
+		{
+			name:  "a zero window is not checked",
+			event: event(1, at(6, 0), at(7, 0)),
+		},
+		{
+			name:   "conflicts are ordered by their start",
+			window: workday,
+			others: []Event{event(3, at(10, 0), at(11, 0)), event(2, at(9, 0), at(9, 30))},
+			event:  event(1, at(8, 0), at(10, 30)),
+			want: []span{
+				{OutsideWindow, 0, at(8, 0), at(9, 0)},
+				{Overlap, 2, at(9, 0), at(9, 30)},
+				{Overlap, 3, at(10, 0), at(10, 30)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := spans(Check(tt.window, tt.others, tt.event))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForDay(t *testing.T) {
+	window := Window{Start: at(9, 0), End: at(17, 0)}
+	events := []Event{
+		event(1, at(9, 0), at(10, 0)),
+		event(2, at(9, 30), at(10, 30)),
+		event(3, at(12, 0), at(13, 0)),
+		event(4, at(16, 0), at(18, 0)),
+	}
+
+	found := ForDay(window, events)
+
+	want := map[int64][]span{
+		1: {{Overlap, 2, at(9, 30), at(10, 0)}},
+		2: {{Overlap, 1, at(9, 30), at(10, 0)}},
+		4: {{OutsideWindow, 0, at(17, 0), at(18, 0)}},
+	}
+	if len(found) != len(want) {
+		t.Errorf("conflicts for %d events, want %d: %+v", len(found), len(want), found)
+	}
+	for id, spansOf := range want {
+		if got := spans(found[id]); !reflect.DeepEqual(got, spansOf) {
+			t.Errorf("conflicts of event %d = %+v, want %+v", id, got, spansOf)
+		}
+	}
+}
+
+func TestUnallowed(t *testing.T) {
+	allowing := func(e Event) Event {
+		e.AllowOverlap = true
+		return e
+	}
+	window := Window{Start: at(9, 0), End: at(17, 0)}
+
+	tests := []struct {
+		name  string
+		event Event
+		other Event
+		want  []span
+	}{
+		{
+			name:  "overlaps are not allowed by default",
+			event: event(1, at(10, 0), at(11, 0)),
+			other: event(2, at(10, 30), at(11, 30)),
+			want:  []span{{Overlap, 2, at(10, 30), at(11, 0)}},
+		},
+		{
+			name:  "the event allows the overlap",
+			event: allowing(event(1, at(10, 0), at(11, 0))),
+			other: event(2, at(10, 30), at(11, 30)),
+		},
+		{
+			name:  "the other event allows the overlap",
+			event: event(1, at(10, 0), at(11, 0)),
+			other: allowing(event(2, at(10, 30), at(11, 30))),
+		},
+		{
+			name:  "allowing overlaps doesn't allow leaving the window",
+			event: allowing(event(1, at(16, 0), at(17, 30))),
+			other: event(2, at(16, 30), at(17, 0)),
+			want:  []span{{OutsideWindow, 0, at(17, 0), at(17, 30)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts := Check(window, []Event{tt.other}, tt.event)
+			got := spans(Unallowed(tt.event, conflicts))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unallowed() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Package depend keeps track of items that have to be finished before others can start
+// and finds the cycles a new dependency would close. It is shared by the CLI and the web
+// server and knows nothing about what the items are.
+package depend
+
+// Graph maps an item to its prerequisites, the items it depends on
+type Graph map[int64][]int64
+
+// Path returns a chain of prerequisites that leads from from to to, both included. It is
+// nil if from doesn't depend on to, not even through other items.
+func (g Graph) Path(from, to int64) []int64 {
+	seen := map[int64]bool{}
+	var walk func(id int64) []int64
+	walk = func(id int64) []int64 {
+		if id == to {
+			return []int64{id}
+		}
+		if seen[id] {
+			return nil
+		}
+		seen[id] = true
+		for _, prerequisite := range g[id] {
+			if path := walk(prerequisite); path != nil {
+				return append([]int64{id}, path...)
+			}
+		}
+		return nil
+	}
+	return walk(from)
+}
+
+// Cycle returns the cycle that making dependent depend on prerequisite would close,
+// starting and ending with dependent. It is nil if the dependency can be added.
+func (g Graph) Cycle(dependent, prerequisite int64) []int64 {
+	if dependent == prerequisite {
+		return []int64{dependent, dependent}
+	}
+	path := g.Path(prerequisite, dependent)
+	if path == nil {
+		return nil
+	}
+	return append([]int64{dependent}, path...)
+}
+
+// Order sorts the ids so that prerequisites come before their dependents. Apart from
+// that the given order is kept, each step takes the first id whose prerequisites among
+// the ids are done. Ids that are caught in a cycle can't be ordered and are returned in
+// stuck.
+func (g Graph) Order(ids []int64) (ordered, stuck []int64) {
+	included := map[int64]bool{}
+	for _, id := range ids {
+		included[id] = true
+	}
+
+	done := map[int64]bool{}
+	remaining := append([]int64(nil), ids...)
+	for len(remaining) > 0 {
+		next := -1
+		for i, id := range remaining {
+			if g.ready(id, included, done) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return ordered, remaining
+		}
+
+		id := remaining[next]
+		ordered = append(ordered, id)
+		done[id] = true
+		remaining = append(remaining[:next], remaining[next+1:]...)
+	}
+	return ordered, nil
+}
+
+func (g Graph) ready(id int64, included, done map[int64]bool) bool {
+	for _, prerequisite := range g[id] {
+		if included[prerequisite] && !done[prerequisite] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package depend
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	// 1 waits for 2, which waits for 3 and 4, 4 waits for itself and 5 for 6 and 5
+	graph := Graph{1: {2}, 2: {3, 4}, 4: {4}, 5: {6, 5}}
+
+	tests := []struct {
+		name     string
+		from, to int64
+		want     []int64
+	}{
+		{"a direct prerequisite", 1, 2, []int64{1, 2}},
+		{"through other items", 1, 3, []int64{1, 2, 3}},
+		{"the first chain that leads there", 1, 4, []int64{1, 2, 4}},
+		{"an item leads to itself", 3, 3, []int64{3}},
+		{"a self-edge leads to itself", 4, 4, []int64{4}},
+		{"dependencies only lead to prerequisites", 3, 1, nil},
+		{"unrelated items", 1, 6, nil},
+		{"a self-edge doesn't loop forever", 5, 1, nil},
+		{"unknown items", 7, 8, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := graph.Path(tt.from, tt.to); !slices.Equal(got, tt.want) {
+				t.Errorf("Path(%d, %d) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCycle(t *testing.T) {
+	// 1 waits for 2, which waits for 3
+	graph := Graph{1: {2}, 2: {3}}
+
+	tests := []struct {
+		name                    string
+		dependent, prerequisite int64
+		want                    []int64
+	}{
+		{"a self-edge", 1, 1, []int64{1, 1}},
+		{"a self-edge of an unknown item", 9, 9, []int64{9, 9}},
+		{"an edge back to a direct dependent", 2, 1, []int64{2, 1, 2}},
+		{"an edge that closes a longer cycle", 3, 1, []int64{3, 1, 2, 3}},
+		{"an edge along the chain", 1, 3, nil},
+		{"an edge that is already there", 1, 2, nil},
+		{"an edge to a new item", 3, 4, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := graph.Cycle(tt.dependent, tt.prerequisite); !slices.Equal(got, tt.want) {
+				t.Errorf("Cycle(%d, %d) = %v, want %v", tt.dependent, tt.prerequisite, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		graph       Graph
+		ids         []int64
+		wantOrdered []int64
+		wantStuck   []int64
+	}{
+		{"independent items keep their order", Graph{}, []int64{3, 1, 2}, []int64{3, 1, 2}, nil},
+		{"prerequisites come first", Graph{1: {2}, 2: {3}}, []int64{1, 2, 3}, []int64{3, 2, 1}, nil},
+		{
+			"independent items stay where they are around a chain",
+			Graph{2: {4}}, []int64{1, 2, 3, 4, 5},
+			[]int64{1, 3, 4, 2, 5}, nil,
+		},
+		{"prerequisites that aren't ordered are ignored", Graph{1: {9}}, []int64{1, 2}, []int64{1, 2}, nil},
+		{"a self-edge is stuck", Graph{2: {2}}, []int64{1, 2, 3}, []int64{1, 3}, []int64{2}},
+		{
+			"a cycle and what waits for it are stuck",
+			Graph{1: {2}, 2: {1}, 3: {1}}, []int64{3, 4, 1, 2},
+			[]int64{4}, []int64{3, 1, 2},
+		},
+		{"no ids", Graph{1: {2}}, nil, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := slices.Clone(tt.ids)
+			ordered, stuck := tt.graph.Order(ids)
+			if !slices.Equal(ordered, tt.wantOrdered) || !slices.Equal(stuck, tt.wantStuck) {
+				t.Errorf("Order(%v) = %v, %v, want %v, %v", tt.ids, ordered, stuck, tt.wantOrdered, tt.wantStuck)
+			}
+			if !slices.Equal(ids, tt.ids) {
+				t.Errorf("Order changed the given ids to %v", ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+// Package taskweave bundles the templates and web assets so that the server can be
+// shipped as a single binary.
+package taskweave
+
+import "embed"
+
+//go:embed templates
+var Templates embed.FS
+
+//go:embed static
+var Static embed.FS
+
+//go:embed assets
+var Assets embed.FS
@@ -0,0 +1,77 @@
+// Package priority sorts work into the quadrants of the Eisenhower matrix. It is shared by
+// the CLI and the web server.
+package priority
+
+// Priority tells how urgent and how important a piece of work is
+type Priority struct {
+	Urgent    bool
+	Important bool
+}
+
+// Quadrant is a cell of the Eisenhower matrix. A higher quadrant is worked on first.
+type Quadrant int
+
+const (
+	// Eliminate is neither urgent nor important
+	Eliminate Quadrant = iota + 1
+	// Delegate is urgent but not important
+	Delegate
+	// Schedule is important but not urgent
+	Schedule
+	// DoFirst is urgent and important
+	DoFirst
+)
+
+var quadrants = map[Quadrant]struct{ code, name string }{
+	DoFirst:   {"do", "Do first"},
+	Schedule:  {"schedule", "Schedule"},
+	Delegate:  {"delegate", "Delegate"},
+	Eliminate: {"eliminate", "Eliminate"},
+}
+
+// Quadrant returns the cell of the matrix the priority belongs to
+func (p Priority) Quadrant() Quadrant {
+	switch {
+	case p.Urgent && p.Important:
+		return DoFirst
+	case p.Important:
+		return Schedule
+	case p.Urgent:
+		return Delegate
+	}
+	return Eliminate
+}
+
+// Of returns the priority of a quadrant
+func Of(q Quadrant) Priority {
+	return Priority{Urgent: q == DoFirst || q == Delegate, Important: q == DoFirst || q == Schedule}
+}
+
+// Quadrants returns every quadrant in the order of the matrix, top left first
+func Quadrants() []Quadrant {
+	return []Quadrant{DoFirst, Schedule, Delegate, Eliminate}
+}
+
+// Parse returns the quadrant with the given code, e.g. "do"
+func Parse(code string) (Quadrant, bool) {
+	for q, names := range quadrants {
+		if names.code == code {
+			return q, true
+		}
+	}
+	return 0, false
+}
+
+// Code identifies the quadrant in URLs and APIs, e.g. "do"
+func (q Quadrant) Code() string {
+	return quadrants[q].code
+}
+
+// String is the English name of the quadrant, e.g. "Do first"
+func (q Quadrant) String() string {
+	return quadrants[q].name
+}
+
+func (q Quadrant) MarshalText() ([]byte, error) {
+	return []byte(q.Code()), nil
+}
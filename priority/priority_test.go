@@ -0,0 +1,74 @@
+package priority
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestQuadrant(t *testing.T) {
+	tests := []struct {
+		priority Priority
+		want     Quadrant
+		code     string
+	}{
+		{Priority{Urgent: true, Important: true}, DoFirst, "do"},
+		{Priority{Important: true}, Schedule, "schedule"},
+		{Priority{Urgent: true}, Delegate, "delegate"},
+		{Priority{}, Eliminate, "eliminate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got := tt.priority.Quadrant()
+			if got != tt.want {
+				t.Fatalf("%+v.Quadrant() = %v, want %v", tt.priority, got, tt.want)
+			}
+			if got.Code() != tt.code {
+				t.Errorf("Code() = %q, want %q", got.Code(), tt.code)
+			}
+			if p := Of(got); p != tt.priority {
+				t.Errorf("Of(%v) = %+v, want %+v", got, p, tt.priority)
+			}
+			if q, ok := Parse(tt.code); !ok || q != got {
+				t.Errorf("Parse(%q) = %v, %t, want %v", tt.code, q, ok, got)
+			}
+		})
+	}
+
+	if q, ok := Parse("urgent"); ok {
+		t.Errorf("Parse(\"urgent\") = %v, want no quadrant", q)
+	}
+}
+
+// The scheduler and the list view work on the higher quadrant first, important work
+// comes before urgent work
+func TestQuadrantOrder(t *testing.T) {
+	priorities := []Priority{
+		{},
+		{Urgent: true},
+		{Important: true},
+		{Urgent: true, Important: true},
+		{Urgent: true},
+		{},
+	}
+
+	sort.SliceStable(priorities, func(i, j int) bool { return priorities[i].Quadrant() > priorities[j].Quadrant() })
+
+	var got []Quadrant
+	for _, p := range priorities {
+		got = append(got, p.Quadrant())
+	}
+	want := []Quadrant{DoFirst, Schedule, Delegate, Delegate, Eliminate, Eliminate}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("order = %v, want %v", got, want)
+		}
+	}
+
+	matrix := Quadrants()
+	for i := 1; i < len(matrix); i++ {
+		if matrix[i-1] <= matrix[i] {
+			t.Errorf("Quadrants() = %v, want the highest quadrant first", matrix)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package recur
+
+import (
+	"sort"
+	"time"
+)
+
+// maxEmptyPeriods stops rules that never match again, like the 30th of February
+const maxEmptyPeriods = 1000
+
+// Iterator returns the occurrences of a rule one after another. The start always is the
+// first occurrence, as RFC 5545 demands, the others have the same time of day in the
+// location of the start.
+type Iterator struct {
+	rule    Rule
+	start   time.Time
+	period  time.Time
+	pending []time.Time
+	started bool
+	emitted int
+	empty   int
+	done    bool
+}
+
+// Iter starts iterating over the occurrences of the rule beginning at start
+func (r Rule) Iter(start time.Time) *Iterator {
+	it := &Iterator{rule: r, start: start}
+	if it.rule.Interval < 1 {
+		it.rule.Interval = 1
+	}
+
+	year, month, day := start.Date()
+	loc := start.Location()
+	switch r.Freq {
+	case Daily:
+		it.period = time.Date(year, month, day, 0, 0, 0, 0, loc)
+	case Weekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		it.period = time.Date(year, month, day-offset, 0, 0, 0, 0, loc)
+	case Monthly:
+		it.period = time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	case Yearly:
+		it.period = time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	default:
+		it.done = true
+	}
+	return it
+}
+
+// Next returns the next occurrence, ok is false once there are no more
+func (it *Iterator) Next() (t time.Time, ok bool) {
+	if !it.started {
+		it.started = true
+		t = it.start
+	} else {
+		for len(it.pending) == 0 {
+			if it.done {
+				return time.Time{}, false
+			}
+			it.pending = it.candidates()
+			it.advance()
+			if len(it.pending) > 0 {
+				it.empty = 0
+			} else if it.empty++; it.empty > maxEmptyPeriods {
+				it.done = true
+			}
+		}
+		t, it.pending = it.pending[0], it.pending[1:]
+	}
+
+	if !it.rule.Until.IsZero() && t.After(it.rule.Until) {
+		it.done, it.pending = true, nil
+		return time.Time{}, false
+	}
+	it.emitted++
+	if it.rule.Count > 0 && it.emitted > it.rule.Count {
+		it.done, it.pending = true, nil
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func (it *Iterator) advance() {
+	n := it.rule.Interval
+	switch it.rule.Freq {
+	case Daily:
+		it.period = it.period.AddDate(0, 0, n)
+	case Weekly:
+		it.period = it.period.AddDate(0, 0, 7*n)
+	case Monthly:
+		it.period = it.period.AddDate(0, n, 0)
+	case Yearly:
+		it.period = it.period.AddDate(n, 0, 0)
+	}
+}
+
+// candidates returns the occurrences of the current period after the start
+func (it *Iterator) candidates() []time.Time {
+	r := it.rule
+	var dates []time.Time
+	switch r.Freq {
+	case Daily:
+		if it.matchesMonth(it.period) && it.matchesMonthDay(it.period) && it.matchesWeekday(it.period) {
+			dates = []time.Time{it.period}
+		}
+	case Weekly:
+		for i := 0; i < 7; i++ {
+			date := it.period.AddDate(0, 0, i)
+			if !it.matchesMonth(date) {
+				continue
+			}
+			if len(r.ByDay) == 0 && date.Weekday() == it.start.Weekday() || len(r.ByDay) > 0 && it.matchesWeekday(date) {
+				dates = append(dates, date)
+			}
+		}
+	case Monthly:
+		if it.matchesMonth(it.period) {
+			dates = it.expand(daysOf(it.period, it.period.AddDate(0, 1, 0)))
+		}
+	case Yearly:
+		dates = it.expandYear()
+	}
+
+	dates = it.setPos(dates)
+	var occurrences []time.Time
+	for _, date := range dates {
+		t := it.at(date)
+		if t.After(it.start) {
+			occurrences = append(occurrences, t)
+		}
+	}
+	return occurrences
+}
+
+// expandYear applies the parts of a yearly rule, numbered weekdays count within the
+// month if BYMONTH is given and within the year otherwise
+func (it *Iterator) expandYear() []time.Time {
+	r := it.rule
+	year := it.period.Year()
+	loc := it.period.Location()
+	monthsOf := func(months []time.Month) []time.Time {
+		var dates []time.Time
+		for _, m := range months {
+			first := time.Date(year, m, 1, 0, 0, 0, 0, loc)
+			dates = append(dates, it.expand(daysOf(first, first.AddDate(0, 1, 0)))...)
+		}
+		return dates
+	}
+
+	switch {
+	case len(r.ByMonth) > 0:
+		return monthsOf(r.ByMonth)
+	case len(r.ByDay) > 0 && len(r.ByMonthDay) == 0:
+		return it.expand(daysOf(it.period, it.period.AddDate(1, 0, 0)))
+	case len(r.ByMonthDay) > 0:
+		var all []time.Month
+		for m := time.January; m <= time.December; m++ {
+			all = append(all, m)
+		}
+		return monthsOf(all)
+	}
+
+	date := time.Date(year, it.start.Month(), it.start.Day(), 0, 0, 0, 0, loc)
+	if date.Month() != it.start.Month() {
+		// The 29th of February only exists in leap years
+		return nil
+	}
+	return []time.Time{date}
+}
+
+// expand picks the days of a month or a year by BYMONTHDAY and BYDAY. Without either
+// the day of the month of the start is used.
+func (it *Iterator) expand(scope []time.Time) []time.Time {
+	r := it.rule
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		for _, date := range scope {
+			if date.Day() == it.start.Day() {
+				return []time.Time{date}
+			}
+		}
+		return nil
+	}
+
+	// picked holds the indexes of the days in scope that BYDAY selects
+	picked := map[int]bool{}
+	for _, d := range r.ByDay {
+		var matching []int
+		for i, date := range scope {
+			if date.Weekday() == d.Day {
+				matching = append(matching, i)
+			}
+		}
+		switch {
+		case d.N == 0:
+			for _, i := range matching {
+				picked[i] = true
+			}
+		case d.N > 0 && d.N <= len(matching):
+			picked[matching[d.N-1]] = true
+		case d.N < 0 && -d.N <= len(matching):
+			picked[matching[len(matching)+d.N]] = true
+		}
+	}
+
+	var dates []time.Time
+	for i, date := range scope {
+		if len(r.ByDay) > 0 && !picked[i] {
+			continue
+		}
+		if !it.matchesMonthDay(date) {
+			continue
+		}
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// setPos keeps the positions of BYSETPOS out of the dates of a period
+func (it *Iterator) setPos(dates []time.Time) []time.Time {
+	if len(it.rule.BySetPos) == 0 || len(dates) == 0 {
+		return dates
+	}
+
+	var kept []time.Time
+	seen := map[int]bool{}
+	for _, pos := range it.rule.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(dates) + pos
+		}
+		if i >= 0 && i < len(dates) && !seen[i] {
+			seen[i] = true
+			kept = append(kept, dates[i])
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Before(kept[j]) })
+	return kept
+}
+
+func (it *Iterator) matchesMonth(date time.Time) bool {
+	if len(it.rule.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range it.rule.ByMonth {
+		if date.Month() == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (it *Iterator) matchesMonthDay(date time.Time) bool {
+	if len(it.rule.ByMonthDay) == 0 {
+		return true
+	}
+	last := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
+	for _, d := range it.rule.ByMonthDay {
+		if d == date.Day() || d < 0 && last+d+1 == date.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func (it *Iterator) matchesWeekday(date time.Time) bool {
+	if len(it.rule.ByDay) == 0 {
+		return true
+	}
+	for _, d := range it.rule.ByDay {
+		if d.Day == date.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// at places the time of day of the start on the date
+func (it *Iterator) at(date time.Time) time.Time {
+	year, month, day := date.Date()
+	hour, min, sec := it.start.Clock()
+	return time.Date(year, month, day, hour, min, sec, 0, it.start.Location())
+}
+
+// daysOf returns every date from from up to but not including to
+func daysOf(from, to time.Time) []time.Time {
+	var days []time.Time
+	for date := from; date.Before(to); date = date.AddDate(0, 0, 1) {
+		days = append(days, date)
+	}
+	return days
+}
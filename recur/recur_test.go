@@ -0,0 +1,357 @@
+package recur
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// date returns the day at 09:00 in UTC
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 0, 0, 0, time.UTC)
+}
+
+// take returns up to n occurrences of the rule
+func take(t *testing.T, s string, start time.Time, n int) []time.Time {
+	t.Helper()
+	rule, err := Parse(s, start.Location())
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", s, err)
+	}
+
+	var occurrences []time.Time
+	it := rule.Iter(start)
+	for len(occurrences) < n {
+		next, ok := it.Next()
+		if !ok {
+			break
+		}
+		occurrences = append(occurrences, next)
+	}
+	return occurrences
+}
+
+func equalTimes(t *testing.T, got, want []time.Time) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d occurrences %v, want %d %v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("occurrence %d is %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"RRULE:freq=weekly;byday=mo,we", "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"},
+		{"FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", "FREQ=MONTHLY;COUNT=3;BYDAY=-1FR"},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1"},
+		{"FREQ=WEEKLY;INTERVAL=2;WKST=SU", "FREQ=WEEKLY;INTERVAL=2;WKST=SU"},
+		{"FREQ=DAILY;UNTIL=20261021T080000Z", "FREQ=DAILY;UNTIL=20261021T080000Z"},
+		{"FREQ=DAILY;UNTIL=20261021", "FREQ=DAILY;UNTIL=20261021T235959Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := Parse(tt.rule, time.UTC)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+	}{
+		{"empty", ""},
+		{"no FREQ", "BYDAY=MO"},
+		{"unknown FREQ", "FREQ=HOURLY"},
+		{"unsupported part", "FREQ=DAILY;BYHOUR=9"},
+		{"part given twice", "FREQ=DAILY;FREQ=WEEKLY"},
+		{"no value", "FREQ=DAILY;COUNT"},
+		{"interval of zero", "FREQ=DAILY;INTERVAL=0"},
+		{"COUNT and UNTIL", "FREQ=DAILY;COUNT=3;UNTIL=20261231"},
+		{"malformed UNTIL", "FREQ=DAILY;UNTIL=2026-12-31"},
+		{"unknown weekday", "FREQ=WEEKLY;BYDAY=XX"},
+		{"weekday out of range", "FREQ=YEARLY;BYDAY=54MO"},
+		{"day of the month out of range", "FREQ=MONTHLY;BYMONTHDAY=32"},
+		{"day of the month of zero", "FREQ=MONTHLY;BYMONTHDAY=0"},
+		{"month from the end", "FREQ=YEARLY;BYMONTH=-1"},
+		{"BYSETPOS alone", "FREQ=MONTHLY;BYSETPOS=1"},
+		{"numbered weekday in a weekly rule", "FREQ=WEEKLY;BYDAY=1MO"},
+		{"BYMONTHDAY in a weekly rule", "FREQ=WEEKLY;BYMONTHDAY=1"},
+		{"unknown WKST", "FREQ=WEEKLY;WKST=XX"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.rule, time.UTC); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Parse(%q) = %v, want ErrInvalid", tt.rule, err)
+			}
+		})
+	}
+}
+
+func TestIter(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		want  []time.Time
+	}{
+		{
+			"daily with an interval",
+			"FREQ=DAILY;INTERVAL=2", date(2026, 10, 19),
+			[]time.Time{date(2026, 10, 19), date(2026, 10, 21), date(2026, 10, 23)},
+		},
+		{
+			"weekly on several days",
+			"FREQ=WEEKLY;BYDAY=MO,WE,FR", date(2026, 10, 19),
+			[]time.Time{date(2026, 10, 19), date(2026, 10, 21), date(2026, 10, 23), date(2026, 10, 26)},
+		},
+		{
+			"every other week starting on Monday",
+			"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU", date(1997, 8, 5),
+			[]time.Time{date(1997, 8, 5), date(1997, 8, 10), date(1997, 8, 19), date(1997, 8, 24)},
+		},
+		{
+			"every other week starting on Sunday",
+			"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU;WKST=SU", date(1997, 8, 5),
+			[]time.Time{date(1997, 8, 5), date(1997, 8, 17), date(1997, 8, 19), date(1997, 8, 31)},
+		},
+		{
+			"last weekday of the month",
+			"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", date(2026, 10, 30),
+			[]time.Time{date(2026, 10, 30), date(2026, 11, 30), date(2026, 12, 31)},
+		},
+		{
+			"first and last weekday of the month",
+			"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=1,-1", date(2026, 10, 30),
+			[]time.Time{date(2026, 10, 30), date(2026, 11, 2), date(2026, 11, 30), date(2026, 12, 1)},
+		},
+		{
+			"last Friday of the month",
+			"FREQ=MONTHLY;BYDAY=-1FR", date(2026, 10, 30),
+			[]time.Time{date(2026, 10, 30), date(2026, 11, 27), date(2026, 12, 25)},
+		},
+		{
+			"last day of the month",
+			"FREQ=MONTHLY;BYMONTHDAY=-1", date(2026, 1, 31),
+			[]time.Time{date(2026, 1, 31), date(2026, 2, 28), date(2026, 3, 31), date(2026, 4, 30)},
+		},
+		{
+			"the 31st skips the shorter months",
+			"FREQ=MONTHLY", date(2026, 1, 31),
+			[]time.Time{date(2026, 1, 31), date(2026, 3, 31), date(2026, 5, 31), date(2026, 7, 31)},
+		},
+		{
+			"the 29th of February only in leap years",
+			"FREQ=YEARLY", date(2028, 2, 29),
+			[]time.Time{date(2028, 2, 29), date(2032, 2, 29), date(2036, 2, 29)},
+		},
+		{
+			"yearly in some months",
+			"FREQ=YEARLY;BYMONTH=1,7;BYMONTHDAY=1", date(2026, 1, 1),
+			[]time.Time{date(2026, 1, 1), date(2026, 7, 1), date(2027, 1, 1)},
+		},
+		{
+			"first Monday of the year",
+			"FREQ=YEARLY;BYDAY=1MO", date(2026, 1, 5),
+			[]time.Time{date(2026, 1, 5), date(2027, 1, 4), date(2028, 1, 3)},
+		},
+		{
+			"the start counts even if it doesn't match",
+			"FREQ=WEEKLY;BYDAY=FR", date(2026, 10, 19),
+			[]time.Time{date(2026, 10, 19), date(2026, 10, 23), date(2026, 10, 30)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			equalTimes(t, take(t, tt.rule, tt.start, len(tt.want)), tt.want)
+		})
+	}
+}
+
+func TestIterEnd(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		want []time.Time
+	}{
+		{
+			"COUNT includes the start",
+			"FREQ=DAILY;COUNT=3",
+			[]time.Time{date(2026, 10, 19), date(2026, 10, 20), date(2026, 10, 21)},
+		},
+		{
+			"an UNTIL date includes the whole day",
+			"FREQ=DAILY;UNTIL=20261021",
+			[]time.Time{date(2026, 10, 19), date(2026, 10, 20), date(2026, 10, 21)},
+		},
+		{
+			"UNTIL before the time of day of the last day",
+			"FREQ=DAILY;UNTIL=20261021T080000Z",
+			[]time.Time{date(2026, 10, 19), date(2026, 10, 20)},
+		},
+		{
+			"UNTIL on an occurrence includes it",
+			"FREQ=DAILY;UNTIL=20261021T090000Z",
+			[]time.Time{date(2026, 10, 19), date(2026, 10, 20), date(2026, 10, 21)},
+		},
+		{
+			"COUNT counts the occurrences, not the periods",
+			"FREQ=WEEKLY;BYDAY=MO,TU;COUNT=3",
+			[]time.Time{date(2026, 10, 19), date(2026, 10, 20), date(2026, 10, 26)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Ask for more than there are to see that the iterator stops
+			equalTimes(t, take(t, tt.rule, date(2026, 10, 19), 10), tt.want)
+		})
+	}
+}
+
+func TestIterEmptyPeriods(t *testing.T) {
+	t.Run("a rule that never matches again stops", func(t *testing.T) {
+		start := date(2026, 1, 30)
+		got := take(t, "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", start, 2)
+		equalTimes(t, got, []time.Time{start})
+	})
+
+	t.Run("the guard stops after maxEmptyPeriods periods", func(t *testing.T) {
+		rule, err := Parse("FREQ=DAILY;BYMONTH=2;BYMONTHDAY=30", time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		it := rule.Iter(date(2026, 1, 30))
+		it.Next()
+		if next, ok := it.Next(); ok {
+			t.Fatalf("got the occurrence %s, want none", next)
+		}
+		if it.empty != maxEmptyPeriods+1 {
+			t.Errorf("stopped after %d empty periods, want %d", it.empty, maxEmptyPeriods+1)
+		}
+		if _, ok := it.Next(); ok {
+			t.Error("a stopped iterator returned another occurrence")
+		}
+	})
+
+	t.Run("long gaps below the guard are kept", func(t *testing.T) {
+		// Almost two years of empty days lie between the start and the next leap day
+		got := take(t, "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=29", date(2026, 3, 1), 2)
+		equalTimes(t, got, []time.Time{date(2026, 3, 1), date(2028, 2, 29)})
+	})
+}
+
+func TestIterDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+
+	// The clocks in Berlin go forward on the 29th of March and back on the 25th of
+	// October 2026
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		want  []time.Time
+	}{
+		{
+			"daily across the start of summer time",
+			"FREQ=DAILY", time.Date(2026, 3, 28, 9, 0, 0, 0, berlin),
+			[]time.Time{
+				time.Date(2026, 3, 28, 8, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 29, 7, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 30, 7, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			"weekly across the end of summer time",
+			"FREQ=WEEKLY", time.Date(2026, 10, 19, 9, 0, 0, 0, berlin),
+			[]time.Time{
+				time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC),
+				time.Date(2026, 10, 26, 8, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := take(t, tt.rule, tt.start, len(tt.want))
+			equalTimes(t, got, tt.want)
+			for _, occurrence := range got {
+				if hour, min, _ := occurrence.Clock(); hour != 9 || min != 0 {
+					t.Errorf("%s is not at 09:00 in Berlin", occurrence)
+				}
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	rule, err := Parse("FREQ=DAILY", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := Set{Start: date(2026, 10, 19), Rule: rule, ExDates: []time.Time{date(2026, 10, 20)}}
+
+	t.Run("Between leaves out the excluded occurrences", func(t *testing.T) {
+		got := set.Between(date(2026, 10, 19), date(2026, 10, 23))
+		equalTimes(t, got, []time.Time{date(2026, 10, 19), date(2026, 10, 21), date(2026, 10, 22)})
+	})
+
+	t.Run("Between includes from and stops before to", func(t *testing.T) {
+		got := set.Between(date(2026, 10, 21), date(2026, 10, 22).Add(time.Second))
+		equalTimes(t, got, []time.Time{date(2026, 10, 21), date(2026, 10, 22)})
+	})
+
+	t.Run("Between before the start is empty", func(t *testing.T) {
+		equalTimes(t, set.Between(date(2026, 10, 1), date(2026, 10, 19)), nil)
+	})
+
+	tests := []struct {
+		name string
+		day  time.Time
+		want time.Time
+		ok   bool
+	}{
+		{"On finds the occurrence of a day", time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC), date(2026, 10, 21), true},
+		{"On ignores the time of day", time.Date(2026, 10, 21, 23, 0, 0, 0, time.UTC), date(2026, 10, 21), true},
+		{"On skips excluded occurrences", date(2026, 10, 20), time.Time{}, false},
+		{"On before the start finds nothing", date(2026, 10, 18), time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := set.On(tt.day)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("On(%s) = %s, %v, want %s, %v", tt.day, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	t.Run("Before counts the excluded occurrences", func(t *testing.T) {
+		if n := set.Before(date(2026, 10, 22)); n != 3 {
+			t.Errorf("Before = %d, want 3", n)
+		}
+	})
+
+	t.Run("Before stops at the end of a rule", func(t *testing.T) {
+		counted := set
+		counted.Rule.Count = 2
+		if n := counted.Before(date(2027, 1, 1)); n != 2 {
+			t.Errorf("Before = %d, want 2", n)
+		}
+	})
+}
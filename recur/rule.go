@@ -0,0 +1,284 @@
+// Package recur implements the recurrence rules of RFC 5545 (RRULE) together with
+// exception dates (EXDATE). Occurrences are expanded lazily, so that rules without an end
+// only cost as much as the range that is looked at. It is shared by the CLI and the web
+// server.
+package recur
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Freq is the FREQ part of a rule, the length of the period the rule repeats in
+type Freq int
+
+const (
+	Daily Freq = iota + 1
+	Weekly
+	Monthly
+	Yearly
+)
+
+var freqs = map[Freq]string{
+	Daily:   "DAILY",
+	Weekly:  "WEEKLY",
+	Monthly: "MONTHLY",
+	Yearly:  "YEARLY",
+}
+
+func (f Freq) String() string {
+	return freqs[f]
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+func weekdayCode(d time.Weekday) string {
+	for code, day := range weekdays {
+		if day == d {
+			return code
+		}
+	}
+	return ""
+}
+
+// WeekdayNum is an entry of BYDAY. N picks the nth weekday of the month or year, negative
+// values count from the end and 0 means every such weekday.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+func (w WeekdayNum) String() string {
+	if w.N == 0 {
+		return weekdayCode(w.Day)
+	}
+	return strconv.Itoa(w.N) + weekdayCode(w.Day)
+}
+
+// Rule is a parsed RRULE. Parts that aren't supported are rejected by Parse instead of
+// being ignored, BYHOUR and smaller parts don't make sense for events that last a day
+// at most.
+type Rule struct {
+	Freq     Freq
+	Interval int
+	// Count limits the number of occurrences, 0 means no limit
+	Count int
+	// Until is the last possible start of an occurrence, the zero time means no limit
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	// WeekStart is the first day of a week, it matters for weekly rules with an interval
+	WeekStart time.Weekday
+}
+
+// ErrInvalid is wrapped by all errors of Parse
+var ErrInvalid = errors.New("invalid recurrence rule")
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvalid}, args...)...)
+}
+
+// Parse reads a rule like "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10". A leading "RRULE:" is
+// accepted. UNTIL values without a timezone are read in loc.
+func Parse(s string, loc *time.Location) (Rule, error) {
+	rule := Rule{Interval: 1, WeekStart: time.Monday}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return rule, invalid("the rule is empty")
+	}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return rule, invalid("%q is not a NAME=VALUE pair", part)
+		}
+		if seen[name] {
+			return rule, invalid("%s is given twice", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			rule.Freq, err = parseFreq(value)
+		case "INTERVAL":
+			rule.Interval, err = parsePositive(value)
+		case "COUNT":
+			rule.Count, err = parsePositive(value)
+		case "UNTIL":
+			rule.Until, err = parseUntil(value, loc)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseList(value, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseList(value, 12)
+			for _, m := range months {
+				if m < 0 {
+					return rule, invalid("BYMONTH can't count from the end")
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			rule.BySetPos, err = parseList(value, 366)
+		case "WKST":
+			day, ok := weekdays[value]
+			if !ok {
+				err = invalid("unknown weekday %q", value)
+			}
+			rule.WeekStart = day
+		default:
+			err = invalid("%s is not supported", name)
+		}
+		if err != nil {
+			return rule, err
+		}
+	}
+
+	if rule.Freq == 0 {
+		return rule, invalid("FREQ is missing")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return rule, invalid("COUNT and UNTIL can't be used together")
+	}
+	if len(rule.BySetPos) > 0 && len(rule.ByDay) == 0 && len(rule.ByMonthDay) == 0 {
+		return rule, invalid("BYSETPOS needs BYDAY or BYMONTHDAY")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly && rule.Freq != Yearly {
+			return rule, invalid("numbered weekdays like %s need a monthly or yearly rule", day)
+		}
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq == Weekly {
+		return rule, invalid("BYMONTHDAY can't be used with a weekly rule")
+	}
+	return rule, nil
+}
+
+func parseFreq(value string) (Freq, error) {
+	for f, name := range freqs {
+		if name == value {
+			return f, nil
+		}
+	}
+	return 0, invalid("FREQ has to be DAILY, WEEKLY, MONTHLY or YEARLY")
+}
+
+func parsePositive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, invalid("%q is not a positive number", value)
+	}
+	return n, nil
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return t, nil
+	}
+	// A date includes the whole day
+	if t, err := time.ParseInLocation("20060102", value, loc); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, invalid("UNTIL has to look like 20261231 or 20261231T235959Z")
+}
+
+// parseList reads comma separated numbers between 1 and max, negative numbers count from
+// the end
+func parseList(value string, max int) ([]int, error) {
+	var list []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n == 0 || n < -max || n > max {
+			return nil, invalid("%q is out of range", item)
+		}
+		list = append(list, n)
+	}
+	return list, nil
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, invalid("unknown weekday %q", item)
+		}
+		day, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, invalid("unknown weekday %q", item)
+		}
+
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, invalid("%q is out of range", item)
+			}
+		}
+		days = append(days, WeekdayNum{N: n, Day: day})
+	}
+	return days, nil
+}
+
+// String formats the rule without the "RRULE:" prefix, the parts are always in the same
+// order
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq.String()}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByMonth) > 0 {
+		var months []string
+		for _, m := range r.ByMonth {
+			months = append(months, strconv.Itoa(int(m)))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, d := range r.ByDay {
+			days = append(days, d.String())
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayCode(r.WeekStart))
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(list []int) string {
+	var s []string
+	for _, n := range list {
+		s = append(s, strconv.Itoa(n))
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,64 @@
+package recur
+
+import "time"
+
+// Set is a recurrence as RFC 5545 describes it: the start, which is the first
+// occurrence, a rule and the occurrences that were taken out again
+type Set struct {
+	Start   time.Time
+	Rule    Rule
+	ExDates []time.Time
+}
+
+// Excluded reports whether the occurrence at t was taken out
+func (s Set) Excluded(t time.Time) bool {
+	for _, ex := range s.ExDates {
+		if ex.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Between returns the occurrences that start from from up to but not including to. Only
+// the occurrences up to to are computed.
+func (s Set) Between(from, to time.Time) []time.Time {
+	var occurrences []time.Time
+	it := s.Rule.Iter(s.Start)
+	for {
+		t, ok := it.Next()
+		if !ok || !t.Before(to) {
+			return occurrences
+		}
+		if t.Before(from) || s.Excluded(t) {
+			continue
+		}
+		occurrences = append(occurrences, t)
+	}
+}
+
+// On returns the occurrence on the date of day in the location of the start, if there
+// is one
+func (s Set) On(day time.Time) (time.Time, bool) {
+	year, month, date := day.Date()
+	from := time.Date(year, month, date, 0, 0, 0, 0, s.Start.Location())
+	occurrences := s.Between(from, from.AddDate(0, 0, 1))
+	if len(occurrences) == 0 {
+		return time.Time{}, false
+	}
+	return occurrences[0], true
+}
+
+// Before returns the number of occurrences before t, excluded ones included. It is used
+// to split a set with a COUNT.
+func (s Set) Before(t time.Time) int {
+	n := 0
+	it := s.Rule.Iter(s.Start)
+	for {
+		next, ok := it.Next()
+		if !ok || !next.Before(t) {
+			return n
+		}
+		n++
+	}
+}
@@ -0,0 +1,205 @@
+package schedule
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultMaxLoad is the share of a working window that may be booked if nothing else is
+// configured
+const DefaultMaxLoad = 0.8
+
+// Move takes an item from one day to another, From is AnyDay for items without a day
+type Move struct {
+	Item Item
+	From int
+	To   int
+}
+
+// Load tells how much of a day is booked once the moves are applied
+type Load struct {
+	Window time.Duration
+	// Fixed is the time of the busy spans inside of the window
+	Fixed time.Duration
+	// Capacity is the time left for flexible work: the window cut down to the maximum
+	// load minus the fixed time
+	Capacity time.Duration
+	Flexible time.Duration
+}
+
+// Share is the part of the window that is booked, 0.5 is half of it
+func (l Load) Share() float64 {
+	if l.Window <= 0 {
+		return 0
+	}
+	return float64(l.Fixed+l.Flexible) / float64(l.Window)
+}
+
+// Balanced is the result of Balance. Unplaced items fit nowhere and stay where they are.
+type Balanced struct {
+	Moves    []Move
+	Unplaced []Unplaced
+	Loads    []Load
+}
+
+// Balance spreads the items over the days so that no day is booked beyond maxLoad of its
+// window. Items stay on their day as long as it has room and lets them finish before
+// their deadline, the others move to the day that ends up with the lowest load. Like
+// Schedule it goes by the earliest deadline first and falls back to the highest priority
+// first when not everything fits. No item ends up on an earlier day than the items it
+// depends on.
+func Balance(days []Day, items []Item, maxLoad float64) Balanced {
+	order := append([]Item(nil), items...)
+	sort.SliceStable(order, func(i, j int) bool { return before(order[i], order[j]) })
+	balanced := spread(days, order, maxLoad)
+	if len(balanced.Unplaced) == 0 {
+		return balanced
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return order[i].Priority > order[j].Priority })
+	return spread(days, order, maxLoad)
+}
+
+// spread assigns the items to the days in the given order
+func spread(days []Day, order []Item, maxLoad float64) Balanced {
+	var balanced Balanced
+	var free [][]slot
+	free, balanced.Loads = capacities(days, maxLoad)
+
+	order, stuck := dependencyOrder(order)
+	for _, item := range stuck {
+		balanced.Unplaced = append(balanced.Unplaced, Unplaced{item, Blocked})
+	}
+
+	// dayOf holds the days items were given so far, dependents the reverse of After
+	dayOf := map[int64]int{}
+	dependents := map[int64][]int64{}
+	for _, item := range order {
+		for _, id := range item.After {
+			dependents[id] = append(dependents[id], item.ID)
+		}
+	}
+	inOrder := func(day int, item Item) bool {
+		for _, id := range item.After {
+			if d, ok := dayOf[id]; ok && d > day {
+				return false
+			}
+		}
+		for _, id := range dependents[item.ID] {
+			if d, ok := dayOf[id]; ok && d < day {
+				return false
+			}
+		}
+		return true
+	}
+	fits := func(day int, item Item) bool {
+		load := balanced.Loads[day]
+		i, _ := firstFit(free[day], anyDay(item), item.NotBefore)
+		return load.Flexible+item.Duration <= load.Capacity && i >= 0
+	}
+	room := func(day int, item Item) bool {
+		return fits(day, item) && inOrder(day, item)
+	}
+	assign := func(day int, item Item) {
+		balanced.Loads[day].Flexible += item.Duration
+		dayOf[item.ID] = day
+	}
+
+	var rest []Item
+	for _, item := range order {
+		if item.Duration <= 0 {
+			balanced.Unplaced = append(balanced.Unplaced, Unplaced{item, NoDuration})
+			continue
+		}
+		if item.Day >= 0 && item.Day < len(days) && room(item.Day, item) {
+			assign(item.Day, item)
+			continue
+		}
+		rest = append(rest, item)
+	}
+
+	for _, item := range rest {
+		best, blocked := -1, false
+		for i := range days {
+			if !room(i, item) {
+				blocked = blocked || fits(i, item)
+				continue
+			}
+			if best < 0 || lighter(balanced.Loads[i], balanced.Loads[best], item.Duration) {
+				best = i
+			}
+		}
+
+		if best < 0 {
+			reason := explainBalance(free, item)
+			if blocked {
+				reason = Blocked
+			}
+			balanced.Unplaced = append(balanced.Unplaced, Unplaced{item, reason})
+			// It stays where it is and still takes time of that day
+			if item.Day >= 0 && item.Day < len(days) {
+				assign(item.Day, item)
+			}
+			continue
+		}
+		assign(best, item)
+		if best != item.Day {
+			balanced.Moves = append(balanced.Moves, Move{Item: item, From: item.Day, To: best})
+		}
+	}
+
+	sort.SliceStable(balanced.Moves, func(i, j int) bool { return balanced.Moves[i].To < balanced.Moves[j].To })
+	return balanced
+}
+
+// capacities returns the free slots of every day and its load before any item is assigned
+func capacities(days []Day, maxLoad float64) ([][]slot, []Load) {
+	free := make([][]slot, len(days))
+	loads := make([]Load, 0, len(days))
+	for i, day := range days {
+		window := Span{day.Start, day.End}.Duration()
+		if window < 0 {
+			window = 0
+		}
+
+		var open time.Duration
+		for _, span := range subtract(Span{day.Start, day.End}, day.Busy) {
+			free[i] = append(free[i], slot{span, i})
+			open += span.Duration()
+		}
+
+		load := Load{Window: window, Fixed: window - open}
+		load.Capacity = time.Duration(maxLoad*float64(window)) - load.Fixed
+		if load.Capacity < 0 {
+			load.Capacity = 0
+		}
+		loads = append(loads, load)
+	}
+	return free, loads
+}
+
+// lighter reports whether day a ends up with a lower load than day b when it gets the
+// extra time. Days are compared by their share of the window, so that short days get
+// less work.
+func lighter(a, b Load, extra time.Duration) bool {
+	a.Flexible += extra
+	b.Flexible += extra
+	return a.Share() < b.Share()
+}
+
+func anyDay(item Item) Item {
+	item.Day = AnyDay
+	return item
+}
+
+// explainBalance finds out why an item fits on no day
+func explainBalance(free [][]slot, item Item) Reason {
+	var slots []slot
+	for _, day := range free {
+		slots = append(slots, day...)
+	}
+	if reason := explain(slots, anyDay(item), item.NotBefore); reason != Full {
+		return reason
+	}
+	return OverCapacity
+}
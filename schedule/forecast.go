@@ -0,0 +1,125 @@
+package schedule
+
+import (
+	"sort"
+	"time"
+)
+
+// Progress is the forecast of an item: the spans of free time it is worked on and the
+// work that is still left when the days run out
+type Progress struct {
+	Item Item
+	Work []Span
+	Left time.Duration
+}
+
+// Finish is the end of the last span of work. It is zero if work is left or there was
+// none.
+func (p Progress) Finish() time.Time {
+	if p.Left > 0 || len(p.Work) == 0 {
+		return time.Time{}
+	}
+	return p.Work[len(p.Work)-1].End
+}
+
+// LeftAt returns the work that isn't done by t
+func (p Progress) LeftAt(t time.Time) time.Duration {
+	left := p.Left
+	for _, span := range p.Work {
+		if !span.End.After(t) {
+			continue
+		}
+		start := span.Start
+		if t.After(start) {
+			start = t
+		}
+		left += span.End.Sub(start)
+	}
+	return left
+}
+
+// Forecast works off the items in the free time of the days without booking a day beyond
+// maxLoad of its window, like Balance. Unlike Schedule an item may be split over several
+// free spans and days, the forecast tells when the work can be done at the earliest
+// rather than where it goes. Items with the earliest deadline are worked on first and
+// never before the items they depend on have finished. The days have to be ordered by
+// date and the ids of the items unique, the progress is returned in the order of the
+// items.
+func Forecast(days []Day, items []Item, maxLoad float64) []Progress {
+	free, loads := capacities(days, maxLoad)
+
+	order := append([]Item(nil), items...)
+	sort.SliceStable(order, func(i, j int) bool { return before(order[i], order[j]) })
+	order, stuck := dependencyOrder(order)
+
+	progress := map[int64]Progress{}
+	for _, item := range stuck {
+		progress[item.ID] = Progress{Item: item, Left: item.Duration}
+	}
+	for _, item := range order {
+		p := Progress{Item: item, Left: item.Duration}
+
+		earliest, blocked := item.NotBefore, false
+		for _, id := range item.After {
+			prerequisite, ok := progress[id]
+			if !ok {
+				continue
+			}
+			if prerequisite.Left > 0 {
+				blocked = true
+			}
+			if end := prerequisite.Finish(); !end.IsZero() && (earliest.IsZero() || end.After(earliest)) {
+				earliest = end
+			}
+		}
+		if !blocked {
+			p.work(free, loads, earliest)
+		}
+		progress[item.ID] = p
+	}
+
+	forecast := make([]Progress, 0, len(items))
+	for _, item := range items {
+		forecast = append(forecast, progress[item.ID])
+	}
+	return forecast
+}
+
+// work books what is left of the item into the free time from earliest on, on every day
+// as much as its capacity allows
+func (p *Progress) work(free [][]slot, loads []Load, earliest time.Time) {
+	for day := range free {
+		for i := 0; i < len(free[day]) && p.Left > 0; i++ {
+			room := loads[day].Capacity - loads[day].Flexible
+			if room <= 0 {
+				break
+			}
+
+			s := free[day][i]
+			// The CLI works with times in year 0, which come before the zero time
+			start := s.Start
+			if !earliest.IsZero() && earliest.After(start) {
+				start = earliest
+			}
+			if !s.End.After(start) {
+				continue
+			}
+
+			length := min(p.Left, room, s.End.Sub(start))
+			end := start.Add(length)
+			p.Work = append(p.Work, Span{start, end})
+			p.Left -= length
+			loads[day].Flexible += length
+
+			// The time before a late start stays free for other items
+			free[day][i].Start = end
+			if start.After(s.Start) {
+				free[day] = append(free[day][:i], append([]slot{{Span{s.Start, start}, day}}, free[day][i:]...)...)
+				i++
+			}
+		}
+		if p.Left <= 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package schedule
+
+// Reason tells why an item couldn't be placed
+type Reason int
+
+const (
+	// NoDuration items have nothing to place
+	NoDuration Reason = iota + 1
+	// NoDay items have to stay on a day that isn't part of the schedule
+	NoDay
+	// TooLong items are longer than every free slot
+	TooLong
+	// MissesDeadline items only fit into slots that end after their deadline
+	MissesDeadline
+	// Full items would fit, but the free time was taken by other items
+	Full
+	// OverCapacity items would fit, but every such day already has its maximum load
+	OverCapacity
+	// Blocked items depend on items that didn't fit or end too late
+	Blocked
+	// NoLaterDay items can't roll over, no day after theirs is planned yet
+	NoLaterDay
+)
+
+var reasons = map[Reason]struct{ code, text string }{
+	NoDuration:     {"no_duration", "It has no duration"},
+	NoDay:          {"no_day", "Its day is not part of the schedule"},
+	TooLong:        {"too_long", "It is longer than every free slot"},
+	MissesDeadline: {"misses_deadline", "There is no free slot long enough before its deadline"},
+	Full:           {"full", "The free time is taken by other items"},
+	OverCapacity:   {"over_capacity", "Every day it fits on already has its maximum load"},
+	Blocked:        {"blocked", "It has to wait for an item it depends on"},
+	NoLaterDay:     {"no_later_day", "There is no later day to move it to"},
+}
+
+// String describes the reason in English, the web server uses it as the key of the
+// translation
+func (r Reason) String() string {
+	return reasons[r].text
+}
+
+// Code identifies the reason in APIs, e.g. "too_long"
+func (r Reason) Code() string {
+	return reasons[r].code
+}
+
+func (r Reason) MarshalText() ([]byte, error) {
+	return []byte(r.Code()), nil
+}
@@ -0,0 +1,80 @@
+package schedule
+
+import (
+	"sort"
+	"time"
+)
+
+// Rollover moves the items of days that are over to the first later day with room. The
+// days are ordered by date and the ones before open are over. Items on the other days
+// stay where they are and take their share of the capacity. A day has room for an item
+// when it keeps below maxLoad of its window like in Balance. Items that roll over never
+// land on an earlier day than the items they depend on, the ones without room stay on
+// their day and are unplaced.
+func Rollover(days []Day, items []Item, maxLoad float64, open int) Balanced {
+	var balanced Balanced
+	var free [][]slot
+	free, balanced.Loads = capacities(days, maxLoad)
+
+	order := append([]Item(nil), items...)
+	sort.SliceStable(order, func(i, j int) bool { return before(order[i], order[j]) })
+	order, stuck := dependencyOrder(order)
+	for _, item := range stuck {
+		balanced.Unplaced = append(balanced.Unplaced, Unplaced{item, Blocked})
+	}
+
+	dayOf := map[int64]int{}
+	assign := func(day int, item Item) {
+		balanced.Loads[day].Flexible += item.Duration
+		dayOf[item.ID] = day
+	}
+
+	var over []Item
+	for _, item := range order {
+		if item.Day >= open {
+			assign(item.Day, item)
+			continue
+		}
+		over = append(over, item)
+	}
+
+	for _, item := range over {
+		// A deadline doesn't keep work from rolling over, the first day with room is the
+		// best it can get anyway
+		loose := anyDay(item)
+		loose.Deadline = time.Time{}
+
+		earliest := max(open, item.Day+1)
+		for _, id := range item.After {
+			if d, ok := dayOf[id]; ok && d > earliest {
+				earliest = d
+			}
+		}
+
+		to := -1
+		for i := earliest; i < len(days) && to < 0; i++ {
+			load := balanced.Loads[i]
+			slot, _ := firstFit(free[i], loose, item.NotBefore)
+			if load.Flexible+item.Duration <= load.Capacity && slot >= 0 {
+				to = i
+			}
+		}
+
+		if to < 0 {
+			reason := NoLaterDay
+			if earliest < len(days) {
+				reason = explainBalance(free[earliest:], loose)
+			}
+			if item.Duration <= 0 {
+				reason = NoDuration
+			}
+			balanced.Unplaced = append(balanced.Unplaced, Unplaced{item, reason})
+			dayOf[item.ID] = item.Day
+			continue
+		}
+		assign(to, item)
+		balanced.Moves = append(balanced.Moves, Move{Item: item, From: item.Day, To: to})
+	}
+
+	return balanced
+}
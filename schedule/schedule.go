@@ -0,0 +1,300 @@
+// Package schedule places flexible work into the free time of working days. It is shared
+// by the CLI and the web server and knows nothing about how days and events are stored.
+package schedule
+
+import (
+	"github.com/Shu-AFK/TaskWeave/depend"
+	"sort"
+	"time"
+)
+
+// AnyDay lets the engine pick the day of an item
+const AnyDay = -1
+
+// Span is a stretch of time from Start to End
+type Span struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Day is a working window. Busy holds the fixed events, time outside of the window is
+// never used.
+type Day struct {
+	Start time.Time
+	End   time.Time
+	Busy  []Span
+}
+
+// Item is a piece of flexible work, it only has a duration and an optional deadline
+type Item struct {
+	ID       int64
+	Name     string
+	Duration time.Duration
+	Deadline time.Time
+	// Day is the index of the day the item has to stay on or AnyDay
+	Day int
+	// Priority decides which items get the free time when not all of them fit, higher
+	// is more important
+	Priority int
+	// After holds the items that have to end before this one starts. Ids of items that
+	// aren't scheduled are ignored.
+	After []int64
+	// NotBefore is the earliest start, e.g. the end of a fixed event the item depends on
+	NotBefore time.Time
+}
+
+// Placement is the time the engine assigned to an item
+type Placement struct {
+	Item  Item
+	Day   int
+	Start time.Time
+	End   time.Time
+}
+
+// Unplaced is an item that didn't fit together with the reason why
+type Unplaced struct {
+	Item   Item
+	Reason Reason
+}
+
+// Plan is the result of Schedule, placements are ordered by their start
+type Plan struct {
+	Placed   []Placement
+	Unplaced []Unplaced
+}
+
+// slot is a free span on a day
+type slot struct {
+	Span
+	day int
+}
+
+// freeSlots returns the free time of every day ordered by start
+func freeSlots(days []Day) []slot {
+	var slots []slot
+	for i, day := range days {
+		for _, span := range subtract(Span{day.Start, day.End}, day.Busy) {
+			slots = append(slots, slot{span, i})
+		}
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].Start.Before(slots[j].Start) })
+	return slots
+}
+
+// subtract cuts the busy spans out of the window
+func subtract(window Span, busy []Span) []Span {
+	if !window.End.After(window.Start) {
+		return nil
+	}
+
+	busy = append([]Span(nil), busy...)
+	sort.Slice(busy, func(i, j int) bool { return busy[i].Start.Before(busy[j].Start) })
+
+	var free []Span
+	cursor := window.Start
+	for _, b := range busy {
+		if !b.End.After(cursor) {
+			continue
+		}
+		if b.Start.After(cursor) {
+			free = append(free, Span{cursor, minTime(b.Start, window.End)})
+		}
+		cursor = b.End
+		if !cursor.Before(window.End) {
+			return free
+		}
+	}
+	if window.End.After(cursor) {
+		free = append(free, Span{cursor, window.End})
+	}
+	return free
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// fits returns the earliest start of the item in the slot that isn't before earliest
+func (s slot) fits(item Item, earliest time.Time) (time.Time, bool) {
+	if item.Day != AnyDay && item.Day != s.day {
+		return time.Time{}, false
+	}
+	// The CLI works with times in year 0, which come before the zero time
+	start := s.Start
+	if !earliest.IsZero() && earliest.After(start) {
+		start = earliest
+	}
+	if s.End.Sub(start) < item.Duration {
+		return time.Time{}, false
+	}
+	if !item.Deadline.IsZero() && start.Add(item.Duration).After(item.Deadline) {
+		return time.Time{}, false
+	}
+	return start, true
+}
+
+// Schedule places the items into the free time of the days without overlapping the busy
+// spans or each other. Items with the earliest deadline are placed first, each at the
+// earliest free time that lets it finish before its deadline. When that leaves items
+// behind, the items with the highest priority are placed first instead so that the
+// shortage hits the least important work. An item never starts before the items it
+// depends on have ended, it stays unplaced if one of them doesn't fit.
+func Schedule(days []Day, items []Item) Plan {
+	order := append([]Item(nil), items...)
+	sort.SliceStable(order, func(i, j int) bool { return before(order[i], order[j]) })
+	plan := place(days, order)
+	if len(plan.Unplaced) == 0 {
+		return plan
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return order[i].Priority > order[j].Priority })
+	return place(days, order)
+}
+
+// place puts the items into the free time in the given order, prerequisites first
+func place(days []Day, order []Item) Plan {
+	slots := freeSlots(days)
+	initial := append([]slot(nil), slots...)
+
+	var plan Plan
+	order, stuck := dependencyOrder(order)
+	for _, item := range stuck {
+		plan.Unplaced = append(plan.Unplaced, Unplaced{item, Blocked})
+	}
+
+	ends := map[int64]time.Time{}
+	failed := map[int64]bool{}
+	for _, item := range order {
+		if reason := check(days, item); reason != 0 {
+			plan.Unplaced = append(plan.Unplaced, Unplaced{item, reason})
+			failed[item.ID] = true
+			continue
+		}
+
+		earliest, blocked := item.NotBefore, false
+		for _, id := range item.After {
+			if failed[id] {
+				blocked = true
+			}
+			if end, ok := ends[id]; ok && end.After(earliest) {
+				earliest = end
+			}
+		}
+		if blocked {
+			plan.Unplaced = append(plan.Unplaced, Unplaced{item, Blocked})
+			failed[item.ID] = true
+			continue
+		}
+
+		i, start := firstFit(slots, item, earliest)
+		if i < 0 {
+			plan.Unplaced = append(plan.Unplaced, Unplaced{item, explain(initial, item, earliest)})
+			failed[item.ID] = true
+			continue
+		}
+
+		s := slots[i]
+		end := start.Add(item.Duration)
+		plan.Placed = append(plan.Placed, Placement{Item: item, Day: s.day, Start: start, End: end})
+		ends[item.ID] = end
+		// The time before a late start stays free for other items
+		slots[i].Start = end
+		if start.After(s.Start) {
+			slots = append(slots[:i], append([]slot{{Span{s.Start, start}, s.day}}, slots[i:]...)...)
+		}
+	}
+
+	sort.SliceStable(plan.Placed, func(i, j int) bool { return plan.Placed[i].Start.Before(plan.Placed[j].Start) })
+	return plan
+}
+
+// before orders items by deadline, items without one come last. Longer items go first
+// as they are the hardest to fit.
+func before(a, b Item) bool {
+	switch {
+	case a.Deadline.IsZero() != b.Deadline.IsZero():
+		return b.Deadline.IsZero()
+	case !a.Deadline.Equal(b.Deadline):
+		return a.Deadline.Before(b.Deadline)
+	}
+	return a.Duration > b.Duration
+}
+
+// firstFit returns the first slot the item fits into without starting before earliest
+// together with the start of the item in it
+func firstFit(slots []slot, item Item, earliest time.Time) (int, time.Time) {
+	for i, s := range slots {
+		if start, ok := s.fits(item, earliest); ok {
+			return i, start
+		}
+	}
+	return -1, time.Time{}
+}
+
+// dependencyOrder moves prerequisites in front of the items that depend on them and
+// otherwise keeps the order. Items caught in a cycle are returned in stuck.
+func dependencyOrder(items []Item) (ordered, stuck []Item) {
+	graph := depend.Graph{}
+	byID := map[int64]Item{}
+	var ids []int64
+	for _, item := range items {
+		graph[item.ID] = item.After
+		byID[item.ID] = item
+		ids = append(ids, item.ID)
+	}
+
+	orderedIDs, stuckIDs := graph.Order(ids)
+	for _, id := range orderedIDs {
+		ordered = append(ordered, byID[id])
+	}
+	for _, id := range stuckIDs {
+		stuck = append(stuck, byID[id])
+	}
+	return ordered, stuck
+}
+
+// check finds problems of the item itself
+func check(days []Day, item Item) Reason {
+	if item.Duration <= 0 {
+		return NoDuration
+	}
+	if item.Day != AnyDay && (item.Day < 0 || item.Day >= len(days)) {
+		return NoDay
+	}
+	return 0
+}
+
+// explain finds out why an item didn't fit by looking at the free time before anything
+// was placed. Earliest is the time the item had to wait for.
+func explain(initial []slot, item Item, earliest time.Time) Reason {
+	long, late := false, false
+	for _, s := range initial {
+		if item.Day != AnyDay && item.Day != s.day {
+			continue
+		}
+		if s.Duration() < item.Duration {
+			continue
+		}
+		long = true
+		if _, ok := s.fits(item, earliest); ok {
+			return Full
+		}
+		if _, ok := s.fits(item, time.Time{}); ok {
+			late = true
+		}
+	}
+	switch {
+	case late:
+		return Blocked
+	case long:
+		return MissesDeadline
+	}
+	return TooLong
+}
@@ -0,0 +1,589 @@
+package schedule
+
+import (
+	"github.com/Shu-AFK/TaskWeave/priority"
+	"testing"
+	"time"
+)
+
+// at returns the time h:m of the day that is d days after Oct 19 2026
+func at(d, h, m int) time.Time {
+	return time.Date(2026, 10, 19+d, h, m, 0, 0, time.UTC)
+}
+
+// workday returns a working window from 9:00 to 17:00 on day d
+func workday(d int, busy ...Span) Day {
+	return Day{Start: at(d, 9, 0), End: at(d, 17, 0), Busy: busy}
+}
+
+func flexible(id int64, duration time.Duration) Item {
+	return Item{ID: id, Name: string(rune('A' + id - 1)), Duration: duration, Day: 0}
+}
+
+func TestSchedule(t *testing.T) {
+	morning := Day{Start: at(0, 9, 0), End: at(0, 12, 0)}
+	withBusy := func(busy ...Span) Day {
+		day := morning
+		day.Busy = busy
+		return day
+	}
+	withDeadline := func(item Item, deadline time.Time) Item {
+		item.Deadline = deadline
+		return item
+	}
+	withPriority := func(item Item, priority int) Item {
+		item.Priority = priority
+		return item
+	}
+	after := func(item Item, ids ...int64) Item {
+		item.After = ids
+		return item
+	}
+
+	tests := []struct {
+		name     string
+		days     []Day
+		items    []Item
+		placed   map[int64]time.Time
+		unplaced map[int64]Reason
+	}{
+		{
+			name:   "items go to the start of the window",
+			days:   []Day{morning},
+			items:  []Item{flexible(1, time.Hour)},
+			placed: map[int64]time.Time{1: at(0, 9, 0)},
+		},
+		{
+			name:   "busy spans are left out and longer items go first",
+			days:   []Day{withBusy(Span{at(0, 9, 30), at(0, 10, 30)})},
+			items:  []Item{flexible(1, 30*time.Minute), flexible(2, time.Hour)},
+			placed: map[int64]time.Time{1: at(0, 9, 0), 2: at(0, 10, 30)},
+		},
+		{
+			name:   "busy spans outside of the window don't matter",
+			days:   []Day{withBusy(Span{at(0, 7, 0), at(0, 8, 0)}, Span{at(0, 12, 0), at(0, 13, 0)})},
+			items:  []Item{flexible(1, 3*time.Hour)},
+			placed: map[int64]time.Time{1: at(0, 9, 0)},
+		},
+		{
+			name:     "items are never split over several free spans",
+			days:     []Day{withBusy(Span{at(0, 10, 15), at(0, 10, 45)})},
+			items:    []Item{flexible(1, 90*time.Minute)},
+			unplaced: map[int64]Reason{1: TooLong},
+		},
+		{
+			name:     "items longer than the window are too long",
+			days:     []Day{morning},
+			items:    []Item{flexible(1, 4*time.Hour)},
+			unplaced: map[int64]Reason{1: TooLong},
+		},
+		{
+			name:     "items without a duration are reported",
+			days:     []Day{morning},
+			items:    []Item{flexible(1, 0)},
+			unplaced: map[int64]Reason{1: NoDuration},
+		},
+		{
+			name:     "items on a day that isn't scheduled are reported",
+			days:     []Day{morning},
+			items:    []Item{{ID: 1, Duration: time.Hour, Day: 3}},
+			unplaced: map[int64]Reason{1: NoDay},
+		},
+		{
+			name:     "items have to end before their deadline",
+			days:     []Day{morning},
+			items:    []Item{withDeadline(flexible(1, time.Hour), at(0, 9, 30))},
+			unplaced: map[int64]Reason{1: MissesDeadline},
+		},
+		{
+			name:   "the earliest deadline goes first",
+			days:   []Day{morning},
+			items:  []Item{withDeadline(flexible(1, time.Hour), at(0, 12, 0)), withDeadline(flexible(2, time.Hour), at(0, 10, 0))},
+			placed: map[int64]time.Time{1: at(0, 10, 0), 2: at(0, 9, 0)},
+		},
+		{
+			name:     "items that don't fit anymore are full",
+			days:     []Day{{Start: at(0, 9, 0), End: at(0, 10, 0)}},
+			items:    []Item{flexible(1, time.Hour), flexible(2, time.Hour)},
+			placed:   map[int64]time.Time{1: at(0, 9, 0)},
+			unplaced: map[int64]Reason{2: Full},
+		},
+		{
+			name:     "the highest priority wins when not everything fits",
+			days:     []Day{{Start: at(0, 9, 0), End: at(0, 10, 0)}},
+			items:    []Item{flexible(1, time.Hour), withPriority(flexible(2, time.Hour), 3)},
+			placed:   map[int64]time.Time{2: at(0, 9, 0)},
+			unplaced: map[int64]Reason{1: Full},
+		},
+		{
+			name:   "items stay on their day",
+			days:   []Day{morning, {Start: at(1, 9, 0), End: at(1, 12, 0)}},
+			items:  []Item{{ID: 1, Duration: time.Hour, Day: 1}},
+			placed: map[int64]time.Time{1: at(1, 9, 0)},
+		},
+		{
+			name:   "prerequisites go first",
+			days:   []Day{morning},
+			items:  []Item{after(flexible(1, 90*time.Minute), 2), flexible(2, 30*time.Minute)},
+			placed: map[int64]time.Time{1: at(0, 9, 30), 2: at(0, 9, 0)},
+		},
+		{
+			name:   "items wait for fixed prerequisites",
+			days:   []Day{morning},
+			items:  []Item{{ID: 1, Duration: time.Hour, Day: 0, NotBefore: at(0, 10, 20)}},
+			placed: map[int64]time.Time{1: at(0, 10, 20)},
+		},
+		{
+			name:     "dependents of items that don't fit are blocked",
+			days:     []Day{morning},
+			items:    []Item{flexible(1, 4*time.Hour), after(flexible(2, time.Hour), 1)},
+			unplaced: map[int64]Reason{1: TooLong, 2: Blocked},
+		},
+		{
+			name:     "items in a cycle are blocked",
+			days:     []Day{morning},
+			items:    []Item{after(flexible(1, time.Hour), 2), after(flexible(2, time.Hour), 1)},
+			unplaced: map[int64]Reason{1: Blocked, 2: Blocked},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := Schedule(tt.days, tt.items)
+
+			if len(plan.Placed) != len(tt.placed) {
+				t.Fatalf("placed %d items, want %d: %+v", len(plan.Placed), len(tt.placed), plan.Placed)
+			}
+			for i, p := range plan.Placed {
+				want, ok := tt.placed[p.Item.ID]
+				if !ok || !p.Start.Equal(want) {
+					t.Errorf("item %d starts at %s, want %s", p.Item.ID, p.Start.Format("Jan 2 15:04"), want.Format("Jan 2 15:04"))
+				}
+				if !p.End.Equal(p.Start.Add(p.Item.Duration)) {
+					t.Errorf("item %d ends at %s, want it to last %s", p.Item.ID, p.End.Format("15:04"), p.Item.Duration)
+				}
+				if i > 0 && plan.Placed[i-1].End.After(p.Start) {
+					t.Errorf("items %d and %d overlap", plan.Placed[i-1].Item.ID, p.Item.ID)
+				}
+			}
+
+			if len(plan.Unplaced) != len(tt.unplaced) {
+				t.Fatalf("got %d unplaced items, want %d: %+v", len(plan.Unplaced), len(tt.unplaced), plan.Unplaced)
+			}
+			for _, u := range plan.Unplaced {
+				if want := tt.unplaced[u.Item.ID]; u.Reason != want {
+					t.Errorf("item %d is unplaced because %q, want %q", u.Item.ID, u.Reason, want)
+				}
+			}
+		})
+	}
+}
+
+// TestScheduleOrder covers the order the items are placed in: by deadline, longer items
+// first on the same deadline, and by the quadrant of their priority once not all of them fit
+func TestScheduleOrder(t *testing.T) {
+	item := func(id int64, duration time.Duration, deadline time.Time, p priority.Priority) Item {
+		return Item{ID: id, Duration: duration, Deadline: deadline, Priority: int(p.Quadrant())}
+	}
+	morning := Day{Start: at(0, 9, 0), End: at(0, 12, 0)}
+	hour := Day{Start: at(0, 9, 0), End: at(0, 10, 0)}
+	noon := at(0, 12, 0)
+	doFirst := priority.Priority{Urgent: true, Important: true}
+	important := priority.Priority{Important: true}
+	urgent := priority.Priority{Urgent: true}
+
+	tests := []struct {
+		name     string
+		day      Day
+		items    []Item
+		placed   map[int64]time.Time
+		unplaced []int64
+	}{
+		{
+			name:   "the same deadline puts the longer item first",
+			day:    morning,
+			items:  []Item{item(1, 30*time.Minute, noon, doFirst), item(2, time.Hour, noon, priority.Priority{})},
+			placed: map[int64]time.Time{1: at(0, 10, 0), 2: at(0, 9, 0)},
+		},
+		{
+			name:   "items without a deadline come after the ones with one",
+			day:    morning,
+			items:  []Item{item(1, time.Hour, time.Time{}, doFirst), item(2, 30*time.Minute, noon, priority.Priority{})},
+			placed: map[int64]time.Time{1: at(0, 9, 30), 2: at(0, 9, 0)},
+		},
+		{
+			name:   "the deadline goes before the quadrant while everything fits",
+			day:    morning,
+			items:  []Item{item(1, time.Hour, noon, doFirst), item(2, time.Hour, at(0, 11, 0), priority.Priority{})},
+			placed: map[int64]time.Time{1: at(0, 10, 0), 2: at(0, 9, 0)},
+		},
+		{
+			name:     "do first wins the time that is short",
+			day:      hour,
+			items:    []Item{item(1, time.Hour, noon, important), item(2, time.Hour, noon, doFirst)},
+			placed:   map[int64]time.Time{2: at(0, 9, 0)},
+			unplaced: []int64{1},
+		},
+		{
+			name:     "important wins over urgent when the time is short",
+			day:      hour,
+			items:    []Item{item(1, time.Hour, at(0, 10, 0), urgent), item(2, time.Hour, noon, important)},
+			placed:   map[int64]time.Time{2: at(0, 9, 0)},
+			unplaced: []int64{1},
+		},
+		{
+			name:     "the same quadrant keeps the deadline order when the time is short",
+			day:      hour,
+			items:    []Item{item(1, time.Hour, noon, urgent), item(2, time.Hour, at(0, 11, 0), urgent)},
+			placed:   map[int64]time.Time{2: at(0, 9, 0)},
+			unplaced: []int64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := Schedule([]Day{tt.day}, tt.items)
+
+			if len(plan.Placed) != len(tt.placed) {
+				t.Fatalf("placed %d items, want %d: %+v", len(plan.Placed), len(tt.placed), plan.Placed)
+			}
+			for _, p := range plan.Placed {
+				if want, ok := tt.placed[p.Item.ID]; !ok || !p.Start.Equal(want) {
+					t.Errorf("item %d starts at %s, want %s", p.Item.ID, p.Start.Format("15:04"), want.Format("15:04"))
+				}
+			}
+			if len(plan.Unplaced) != len(tt.unplaced) {
+				t.Fatalf("got %d unplaced items, want %d: %+v", len(plan.Unplaced), len(tt.unplaced), plan.Unplaced)
+			}
+			for i, u := range plan.Unplaced {
+				if u.Item.ID != tt.unplaced[i] {
+					t.Errorf("item %d is unplaced, want item %d", u.Item.ID, tt.unplaced[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBalance(t *testing.T) {
+	tests := []struct {
+		name     string
+		days     []Day
+		items    []Item
+		maxLoad  float64
+		moves    map[int64]int
+		unplaced map[int64]Reason
+		flexible []time.Duration
+	}{
+		{
+			name:     "items stay on a day with room",
+			days:     []Day{workday(0), workday(1)},
+			items:    []Item{flexible(1, 3*time.Hour)},
+			maxLoad:  0.5,
+			flexible: []time.Duration{3 * time.Hour, 0},
+		},
+		{
+			name:     "items beyond the maximum load move",
+			days:     []Day{workday(0), workday(1)},
+			items:    []Item{flexible(1, 3*time.Hour), flexible(2, 3*time.Hour)},
+			maxLoad:  0.5,
+			moves:    map[int64]int{2: 1},
+			flexible: []time.Duration{3 * time.Hour, 3 * time.Hour},
+		},
+		{
+			name:     "fixed events take their time of the capacity",
+			days:     []Day{workday(0, Span{at(0, 9, 0), at(0, 11, 0)}), workday(1)},
+			items:    []Item{flexible(1, 90*time.Minute), flexible(2, time.Hour)},
+			maxLoad:  0.5,
+			moves:    map[int64]int{2: 1},
+			flexible: []time.Duration{90 * time.Minute, time.Hour},
+		},
+		{
+			name:     "items move to the day with the lowest load",
+			days:     []Day{workday(0), workday(1, Span{at(1, 9, 0), at(1, 12, 0)}), workday(2)},
+			items:    []Item{flexible(1, 4*time.Hour), flexible(2, time.Hour)},
+			maxLoad:  0.5,
+			moves:    map[int64]int{2: 2},
+			flexible: []time.Duration{4 * time.Hour, 0, time.Hour},
+		},
+		{
+			name:     "items that fit no capacity stay and are over capacity",
+			days:     []Day{workday(0), workday(1)},
+			items:    []Item{flexible(1, 5*time.Hour)},
+			maxLoad:  0.5,
+			unplaced: map[int64]Reason{1: OverCapacity},
+			flexible: []time.Duration{5 * time.Hour, 0},
+		},
+		{
+			name:     "a full load allows the whole window",
+			days:     []Day{workday(0)},
+			items:    []Item{flexible(1, 8*time.Hour)},
+			maxLoad:  1,
+			flexible: []time.Duration{8 * time.Hour},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balanced := Balance(tt.days, tt.items, tt.maxLoad)
+
+			if len(balanced.Moves) != len(tt.moves) {
+				t.Fatalf("got %d moves, want %d: %+v", len(balanced.Moves), len(tt.moves), balanced.Moves)
+			}
+			for _, move := range balanced.Moves {
+				if want, ok := tt.moves[move.Item.ID]; !ok || move.To != want {
+					t.Errorf("item %d moves to day %d, want %d", move.Item.ID, move.To, want)
+				}
+			}
+			if len(balanced.Unplaced) != len(tt.unplaced) {
+				t.Fatalf("got %d unplaced items, want %d: %+v", len(balanced.Unplaced), len(tt.unplaced), balanced.Unplaced)
+			}
+			for _, u := range balanced.Unplaced {
+				if want := tt.unplaced[u.Item.ID]; u.Reason != want {
+					t.Errorf("item %d is unplaced because %q, want %q", u.Item.ID, u.Reason, want)
+				}
+			}
+			for i, load := range balanced.Loads {
+				if load.Flexible != tt.flexible[i] {
+					t.Errorf("day %d has %s of flexible work, want %s", i, load.Flexible, tt.flexible[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	loads := Balance([]Day{
+		workday(0),
+		workday(1, Span{at(1, 9, 0), at(1, 11, 0)}),
+		workday(2, Span{at(2, 9, 0), at(2, 17, 0)}),
+		{Start: at(3, 17, 0), End: at(3, 9, 0)},
+	}, nil, 0.8).Loads
+
+	want := []Load{
+		{Window: 8 * time.Hour, Capacity: 384 * time.Minute},
+		{Window: 8 * time.Hour, Fixed: 2 * time.Hour, Capacity: 264 * time.Minute},
+		{Window: 8 * time.Hour, Fixed: 8 * time.Hour},
+		{},
+	}
+	for i, load := range loads {
+		if load != want[i] {
+			t.Errorf("day %d has the load %+v, want %+v", i, load, want[i])
+		}
+	}
+}
+
+func TestRollover(t *testing.T) {
+	over := func(id int64, duration time.Duration) Item {
+		return flexible(id, duration)
+	}
+	on := func(item Item, day int) Item {
+		item.Day = day
+		return item
+	}
+
+	tests := []struct {
+		name     string
+		days     []Day
+		items    []Item
+		open     int
+		moves    map[int64]int
+		unplaced map[int64]Reason
+	}{
+		{
+			name:  "unfinished items go to the next day",
+			days:  []Day{workday(0), workday(1)},
+			items: []Item{over(1, time.Hour)},
+			open:  1,
+			moves: map[int64]int{1: 1},
+		},
+		{
+			name:  "items on open days stay",
+			days:  []Day{workday(0), workday(1)},
+			items: []Item{on(flexible(1, time.Hour), 1)},
+			open:  1,
+		},
+		{
+			name:  "days beyond the maximum load are skipped",
+			days:  []Day{workday(0), workday(1), workday(2)},
+			items: []Item{on(flexible(1, 3*time.Hour), 1), over(2, 2*time.Hour)},
+			open:  1,
+			moves: map[int64]int{2: 2},
+		},
+		{
+			name:  "a deadline doesn't keep an item from rolling over",
+			days:  []Day{workday(0), workday(1)},
+			items: []Item{{ID: 1, Duration: time.Hour, Deadline: at(0, 12, 0), Day: 0}},
+			open:  1,
+			moves: map[int64]int{1: 1},
+		},
+		{
+			name:  "dependents don't land before their prerequisites",
+			days:  []Day{workday(0), workday(1), workday(2)},
+			items: []Item{on(flexible(1, 3*time.Hour), 2), {ID: 2, Duration: time.Hour, Day: 0, After: []int64{1}}},
+			open:  1,
+			moves: map[int64]int{2: 2},
+		},
+		{
+			name:     "items without a later day stay",
+			days:     []Day{workday(0)},
+			items:    []Item{over(1, time.Hour)},
+			open:     1,
+			unplaced: map[int64]Reason{1: NoLaterDay},
+		},
+		{
+			name:     "items beyond every capacity stay",
+			days:     []Day{workday(0), workday(1)},
+			items:    []Item{over(1, 5*time.Hour)},
+			open:     1,
+			unplaced: map[int64]Reason{1: OverCapacity},
+		},
+		{
+			name:     "items longer than every free span stay",
+			days:     []Day{workday(0), workday(1, Span{at(1, 12, 0), at(1, 14, 0)})},
+			items:    []Item{over(1, 4*time.Hour)},
+			open:     1,
+			unplaced: map[int64]Reason{1: TooLong},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balanced := Rollover(tt.days, tt.items, 0.5, tt.open)
+
+			if len(balanced.Moves) != len(tt.moves) {
+				t.Fatalf("got %d moves, want %d: %+v", len(balanced.Moves), len(tt.moves), balanced.Moves)
+			}
+			for _, move := range balanced.Moves {
+				if want, ok := tt.moves[move.Item.ID]; !ok || move.To != want {
+					t.Errorf("item %d moves to day %d, want %d", move.Item.ID, move.To, want)
+				}
+			}
+			if len(balanced.Unplaced) != len(tt.unplaced) {
+				t.Fatalf("got %d unplaced items, want %d: %+v", len(balanced.Unplaced), len(tt.unplaced), balanced.Unplaced)
+			}
+			for _, u := range balanced.Unplaced {
+				if want := tt.unplaced[u.Item.ID]; u.Reason != want {
+					t.Errorf("item %d is unplaced because %q, want %q", u.Item.ID, u.Reason, want)
+				}
+			}
+		})
+	}
+}
+
+func TestForecast(t *testing.T) {
+	due := func(item Item, deadline time.Time) Item {
+		item.Deadline = deadline
+		return item
+	}
+
+	tests := []struct {
+		name    string
+		days    []Day
+		items   []Item
+		maxLoad float64
+		// finish is zero for items with work left
+		finish []time.Time
+		left   []time.Duration
+	}{
+		{
+			name:    "work is split over the days up to the maximum load",
+			days:    []Day{workday(0), workday(1)},
+			items:   []Item{flexible(1, 6*time.Hour)},
+			maxLoad: 0.5,
+			finish:  []time.Time{at(1, 11, 0)},
+			left:    []time.Duration{0},
+		},
+		{
+			name:    "work is split around busy spans",
+			days:    []Day{workday(0, Span{at(0, 10, 0), at(0, 11, 0)})},
+			items:   []Item{flexible(1, 2*time.Hour)},
+			maxLoad: 1,
+			finish:  []time.Time{at(0, 12, 0)},
+			left:    []time.Duration{0},
+		},
+		{
+			name:    "work is left when the days run out",
+			days:    []Day{workday(0)},
+			items:   []Item{flexible(1, 10*time.Hour)},
+			maxLoad: 0.5,
+			finish:  []time.Time{{}},
+			left:    []time.Duration{6 * time.Hour},
+		},
+		{
+			name:    "the earliest deadline is worked on first",
+			days:    []Day{workday(0)},
+			items:   []Item{due(flexible(1, time.Hour), at(0, 17, 0)), due(flexible(2, time.Hour), at(0, 10, 0))},
+			maxLoad: 1,
+			finish:  []time.Time{at(0, 11, 0), at(0, 10, 0)},
+			left:    []time.Duration{0, 0},
+		},
+		{
+			name:  "dependents wait for their prerequisites",
+			days:  []Day{workday(0)},
+			items: []Item{flexible(1, time.Hour), {ID: 2, Duration: time.Hour, Deadline: at(0, 10, 0), After: []int64{1}}},
+			// Item 2 starts once item 1 has finished although its deadline is earlier
+			maxLoad: 1,
+			finish:  []time.Time{at(0, 10, 0), at(0, 11, 0)},
+			left:    []time.Duration{0, 0},
+		},
+		{
+			name:    "the time before a late start stays free",
+			days:    []Day{workday(0)},
+			items:   []Item{{ID: 1, Duration: time.Hour, NotBefore: at(0, 12, 0)}, flexible(2, time.Hour)},
+			maxLoad: 1,
+			finish:  []time.Time{at(0, 13, 0), at(0, 10, 0)},
+			left:    []time.Duration{0, 0},
+		},
+		{
+			name:    "dependents of unfinished work aren't worked on",
+			days:    []Day{workday(0)},
+			items:   []Item{flexible(1, 9*time.Hour), {ID: 2, Duration: time.Hour, After: []int64{1}}},
+			maxLoad: 1,
+			finish:  []time.Time{{}, {}},
+			left:    []time.Duration{time.Hour, time.Hour},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forecast := Forecast(tt.days, tt.items, tt.maxLoad)
+
+			if len(forecast) != len(tt.items) {
+				t.Fatalf("got the progress of %d items, want %d", len(forecast), len(tt.items))
+			}
+			for i, p := range forecast {
+				if p.Item.ID != tt.items[i].ID {
+					t.Errorf("progress %d is of item %d, want %d", i, p.Item.ID, tt.items[i].ID)
+				}
+				if finish := p.Finish(); !finish.Equal(tt.finish[i]) {
+					t.Errorf("item %d finishes at %s, want %s", p.Item.ID, finish.Format("Jan 2 15:04"), tt.finish[i].Format("Jan 2 15:04"))
+				}
+				if p.Left != tt.left[i] {
+					t.Errorf("item %d has %s left, want %s", p.Item.ID, p.Left, tt.left[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProgressLeftAt(t *testing.T) {
+	p := Progress{
+		Work: []Span{{at(0, 9, 0), at(0, 11, 0)}, {at(1, 9, 0), at(1, 10, 0)}},
+		Left: 30 * time.Minute,
+	}
+
+	tests := []struct {
+		at   time.Time
+		want time.Duration
+	}{
+		{at(0, 8, 0), 210 * time.Minute},
+		{at(0, 10, 0), 150 * time.Minute},
+		{at(0, 12, 0), 90 * time.Minute},
+		{at(1, 10, 0), 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := p.LeftAt(tt.at); got != tt.want {
+			t.Errorf("LeftAt(%s) = %s, want %s", tt.at.Format("Jan 2 15:04"), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Sends forms marked with data-target in the background and swaps the returned HTML
+// fragment into the page. Without JavaScript the forms are posted normally.
+document.addEventListener('submit', async (e) => {
+    const form = e.target.closest('form[data-target]');
+    if (!form) {
+        return;
+    }
+    e.preventDefault();
+
+    let res;
+    try {
+        res = await fetch(form.action, {
+            method: form.dataset.method || 'POST',
+            headers: {'HX-Request': 'true'},
+            body: new URLSearchParams(new FormData(form)),
+        });
+    } catch {
+        // The request may have been made, posting the form again could repeat it
+        location.reload();
+        return;
+    }
+
+    // Errors come as plain text. Forms without a place for them and expired sessions load
+    // the page again, which shows the current state or the login.
+    if (!res.ok) {
+        const error = form.querySelector('.error');
+        if (!error || res.status === 401) {
+            location.reload();
+            return;
+        }
+        error.textContent = await res.text();
+        return;
+    }
+
+    const target = document.querySelector(form.dataset.target);
+    if (target) {
+        target.outerHTML = await res.text();
+    }
+});
+
+// Follows the changes made in other tabs or by other clients and refreshes the affected
+// cards. EventSource reconnects on its own and sends the id of the last change it got.
+const page = document.querySelector('[data-changes-since]');
+if (page && window.EventSource) {
+    const changes = new EventSource('/tasks/changes?since=' + encodeURIComponent(page.dataset.changesSince));
+
+    const refresh = async (selector, url) => {
+        const card = document.querySelector(selector);
+        if (!card) {
+            return;
+        }
+        const res = await fetch(url, {headers: {'HX-Request': 'true'}});
+        if (res.ok) {
+            card.outerHTML = await res.text();
+        } else if (res.status === 404) {
+            card.remove();
+        }
+    };
+
+    const apply = (kind, change) => {
+        switch (change.entity) {
+        case 'todo':
+            return refresh('#event-' + change.event, '/tasks/events/' + change.event);
+        case 'event':
+            if (kind === 'deleted') {
+                document.querySelector('#event-' + change.id)?.remove();
+                return;
+            }
+            if (kind === 'created') {
+                return refresh('#day-' + change.day, '/tasks/days/' + change.day);
+            }
+            return refresh('#event-' + change.id, '/tasks/events/' + change.id);
+        case 'day':
+            if (kind === 'deleted') {
+                document.querySelector('#day-' + change.id)?.remove();
+                return;
+            }
+            if (kind === 'created') {
+                // New days have to be placed by date, that is left to the server
+                location.reload();
+                return;
+            }
+            return refresh('#day-' + change.id, '/tasks/days/' + change.id);
+        }
+    };
+
+    // Any change can move the forecast of every deadline. A burst of changes, e.g. from a
+    // rollover, only loads it once.
+    let risksTimeout;
+    const refreshRisks = () => {
+        clearTimeout(risksTimeout);
+        risksTimeout = setTimeout(() => refresh('#risks', '/tasks/risks'), 300);
+    };
+
+    for (const kind of ['created', 'updated', 'deleted']) {
+        changes.addEventListener(kind, (e) => {
+            apply(kind, JSON.parse(e.data));
+            refreshRisks();
+        });
+    }
+    changes.addEventListener('reset', () => location.reload());
+}
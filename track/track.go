@@ -0,0 +1,94 @@
+// Package track measures the time actually spent on work and compares it with the time
+// that was planned for it. It is shared by the CLI and the web server and knows nothing
+// about how entries are stored.
+package track
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrNotRunning is returned by Stop when no timer runs
+var ErrNotRunning = errors.New("no timer is running")
+
+// Entry is a stretch of tracked time. Entries without a stop are still running.
+type Entry struct {
+	Start time.Time
+	Stop  time.Time
+	Note  string
+}
+
+// Running reports whether the timer of the entry hasn't been stopped yet
+func (e Entry) Running() bool {
+	return e.Stop.IsZero()
+}
+
+// Runs reports whether the timer of the entries runs, only the last entry can be running
+func Runs(entries []Entry) bool {
+	return len(entries) > 0 && entries[len(entries)-1].Running()
+}
+
+// Stop stops the running timer of the entries at now and returns the stopped entry. A
+// note replaces the one given at the start.
+func Stop(entries []Entry, note string, now time.Time) (Entry, error) {
+	if !Runs(entries) {
+		return Entry{}, ErrNotRunning
+	}
+	entry := &entries[len(entries)-1]
+	entry.Stop = now
+	if note != "" {
+		entry.Note = note
+	}
+	return *entry, nil
+}
+
+// Duration is the tracked time, running entries count up to now
+func (e Entry) Duration(now time.Time) time.Duration {
+	stop := e.Stop
+	if e.Running() {
+		stop = now
+	}
+	if stop.Before(e.Start) {
+		return 0
+	}
+	return stop.Sub(e.Start)
+}
+
+// Total adds up the tracked time of the entries
+func Total(entries []Entry, now time.Time) time.Duration {
+	var total time.Duration
+	for _, e := range entries {
+		total += e.Duration(now)
+	}
+	return total
+}
+
+// Comparison holds the planned and the tracked time of a piece of work
+type Comparison struct {
+	Estimated time.Duration
+	Actual    time.Duration
+}
+
+// Difference is positive when the work took longer than planned
+func (c Comparison) Difference() time.Duration {
+	return c.Actual - c.Estimated
+}
+
+// Percent is the tracked time in percent of the planned time, 0 if nothing was planned
+func (c Comparison) Percent() int {
+	if c.Estimated <= 0 {
+		return 0
+	}
+	return int(math.Round(float64(c.Actual) / float64(c.Estimated) * 100))
+}
+
+// Over reports whether the work took longer than planned
+func (c Comparison) Over() bool {
+	return c.Actual > c.Estimated
+}
+
+// Hours returns d in hours rounded to two decimals, as it is put on an invoice
+func Hours(d time.Duration) float64 {
+	return math.Round(d.Hours()*100) / 100
+}
@@ -0,0 +1,115 @@
+package track
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// at returns the time h:m of Oct 19 2026
+func at(h, m int) time.Time {
+	return time.Date(2026, 10, 19, h, m, 0, 0, time.UTC)
+}
+
+func TestTotal(t *testing.T) {
+	now := at(12, 0)
+
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    time.Duration
+	}{
+		{
+			name: "no entries add up to nothing",
+		},
+		{
+			name:    "stopped entries add up",
+			entries: []Entry{{Start: at(9, 0), Stop: at(9, 45)}, {Start: at(10, 0), Stop: at(10, 30)}},
+			want:    75 * time.Minute,
+		},
+		{
+			name:    "a running entry counts up to now",
+			entries: []Entry{{Start: at(9, 0), Stop: at(10, 0)}, {Start: at(11, 30)}},
+			want:    90 * time.Minute,
+		},
+		{
+			name:    "entries that stop before they start count nothing",
+			entries: []Entry{{Start: at(10, 0), Stop: at(9, 0)}, {Start: at(9, 0), Stop: at(9, 15)}},
+			want:    15 * time.Minute,
+		},
+		{
+			name:    "a running entry that starts after now counts nothing",
+			entries: []Entry{{Start: at(13, 0)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Total(tt.entries, now); got != tt.want {
+				t.Errorf("Total() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStop(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		note    string
+		want    Entry
+		err     error
+	}{
+		{
+			name: "without entries no timer runs",
+			err:  ErrNotRunning,
+		},
+		{
+			name:    "a stopped timer can't be stopped again",
+			entries: []Entry{{Start: at(9, 0), Stop: at(10, 0)}},
+			err:     ErrNotRunning,
+		},
+		{
+			name:    "the running entry is stopped and keeps its note",
+			entries: []Entry{{Start: at(9, 0), Stop: at(10, 0)}, {Start: at(11, 0), Note: "draft"}},
+			want:    Entry{Start: at(11, 0), Stop: at(12, 0), Note: "draft"},
+		},
+		{
+			name:    "a note replaces the one given at the start",
+			entries: []Entry{{Start: at(11, 0), Note: "draft"}},
+			note:    "review",
+			want:    Entry{Start: at(11, 0), Stop: at(12, 0), Note: "review"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stop(tt.entries, tt.note, at(12, 0))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Stop() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Stop() = %+v, want %+v", got, tt.want)
+			}
+			if err == nil && tt.entries[len(tt.entries)-1] != tt.want {
+				t.Errorf("the last entry is %+v, want it stopped in place", tt.entries[len(tt.entries)-1])
+			}
+			if Runs(tt.entries) {
+				t.Error("the timer still runs")
+			}
+		})
+	}
+}
+
+func TestComparison(t *testing.T) {
+	c := Comparison{Estimated: time.Hour, Actual: 90 * time.Minute}
+	if c.Difference() != 30*time.Minute || c.Percent() != 150 || !c.Over() {
+		t.Errorf("%+v: difference %s, %d%%, over %t, want 30m, 150%% and over", c, c.Difference(), c.Percent(), c.Over())
+	}
+	if p := (Comparison{Actual: time.Hour}).Percent(); p != 0 {
+		t.Errorf("Percent() without a plan = %d, want 0", p)
+	}
+	if h := Hours(100 * time.Minute); h != 1.67 {
+		t.Errorf("Hours(100m) = %v, want 1.67", h)
+	}
+}